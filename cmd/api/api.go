@@ -2,33 +2,49 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/csye7125/team01/internal/handlers"
 	"github.com/csye7125/team01/internal/middlewares"
+	"github.com/csye7125/team01/internal/openapi"
+	"github.com/csye7125/team01/internal/parsers"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/retrieval"
+	"github.com/csye7125/team01/internal/rpc"
+	"github.com/csye7125/team01/internal/sentiment"
 	"github.com/csye7125/team01/internal/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	validator "github.com/pb33f/libopenapi-validator"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-func NewApplication(storage *store.Storage) *application {
+func NewApplication(storage *store.Storage, analyzer *sentiment.Analyzer, specValidator validator.Validator) *application {
+	retriever := retrieval.NewBM25Retriever(storage.Texts)
 	return &application{
-		config: config{addr: ":8080"},
-		store:  storage,
+		config:        config{addr: ":8080", grpcAddr: grpcAddr()},
+		store:         storage,
+		retriever:     retriever,
+		pipeline:      parsers.NewPipeline(storage, parsers.DefaultRegistry(), retriever, analyzer),
+		specValidator: specValidator,
 	}
 }
 
 type application struct {
-	config config
-	store  *store.Storage
+	config        config
+	store         *store.Storage
+	retriever     retrieval.Retriever
+	pipeline      *parsers.Pipeline
+	specValidator validator.Validator
 }
 
 type config struct {
-	addr string
-	db   dbConfig
+	addr     string
+	grpcAddr string
+	db       dbConfig
 }
 
 type dbConfig struct {
@@ -45,27 +61,55 @@ func wrapHandler(handler http.HandlerFunc, operation string) http.HandlerFunc {
 	}
 }
 
-func (a *application) mount() http.Handler {
+func (a *application) mount() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(requestTimeout(60*time.Second, exportTimeout()))
+	r.Use(openapi.Middleware(a.specValidator, os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true"))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problems.Write(w, r, problems.NotFound("No route matches "+r.URL.Path))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problems.Write(w, r, problems.New(http.StatusMethodNotAllowed, r.Method+" is not supported on "+r.URL.Path))
+	})
 
 	healthHandler := handlers.NewHealthHandler()
 	userHandler := handlers.NewUserHandler(a.store)
 	courseHandler := handlers.NewCourseHandler(a.store)
 	instructorHandler := handlers.NewInstructorHandler(a.store)
-	traceHandler := handlers.NewTraceHandler(a.store, os.Getenv("GCS_BUCKET_NAME"))
+	traceHandler := handlers.NewTraceHandler(a.store, os.Getenv("GCS_BUCKET_NAME"), a.pipeline)
+	searchHandler := handlers.NewSearchHandler(a.store)
+	askHandler := handlers.NewAskHandler(a.store, a.retriever)
+	graphqlHandler := handlers.NewGraphQLHandler(a.store)
+	catalogHandler := handlers.NewCatalogHandler(a.store, traceHandler)
+	batchHandler := handlers.NewBatchHandler(a.store, r)
 	authMiddleware := middlewares.NewAuthMiddleware(a.store.Users)
+	idempotency := middlewares.NewIdempotencyMiddleware(a.store.Idempotency, idempotencyTTL())
 
 	// Public endpoints with OpenTelemetry instrumentation
 	r.Get("/healthz", wrapHandler(healthHandler.HealthCheckHandler, "HealthCheck"))
+	r.Get("/openapi.json", wrapHandler(openapi.SpecHandler, "GetOpenAPI"))
+	r.Get("/docs", wrapHandler(openapi.DocsHandler, "GetDocs"))
 	r.Post("/v1/user", wrapHandler(userHandler.CreateUserHandler, "CreateUser"))
 	r.Get("/v1/course/{courseId}", wrapHandler(courseHandler.GetCourseHandler, "GetCourse"))
+	r.Get("/v1/courses", wrapHandler(courseHandler.ListCoursesHandler, "ListCourses"))
+	r.Get("/v1/courses/compare", wrapHandler(courseHandler.CompareCoursesHandler, "CompareCourses"))
+	r.Get("/v1/instructors", wrapHandler(instructorHandler.ListInstructorsHandler, "ListInstructors"))
 	r.Get("/v1/instructor/{instructorId}", wrapHandler(instructorHandler.GetInstructorHandler, "GetInstructor"))
+	r.Get("/v1/instructor/{instructorId}/analytics", wrapHandler(instructorHandler.GetInstructorAnalyticsHandler, "GetInstructorAnalytics"))
+	r.Get("/v1/catalog/{code}", wrapHandler(catalogHandler.GetCatalogCourseHandler, "GetCatalogCourse"))
+	r.Get("/v1/catalog/{code}/offerings", wrapHandler(catalogHandler.ListOfferingsHandler, "ListOfferings"))
+	r.Get("/v1/catalog/{code}/offerings/{offeringId}", wrapHandler(catalogHandler.GetOfferingHandler, "GetOffering"))
+
+	// GraphQL checks optional Basic Auth itself, as anonymous callers may read public fields
+	r.Post("/graphql", wrapHandler(graphqlHandler.GraphQLHandler, "GraphQL"))
+
+	// Batches authenticate each sub-request as it runs through the router
+	r.Post("/v1/batch", wrapHandler(batchHandler.BatchHandler, "Batch"))
 
 	// Protected endpoints with OpenTelemetry instrumentation
 	r.Group(func(r chi.Router) {
@@ -73,26 +117,136 @@ func (a *application) mount() http.Handler {
 
 		r.Get("/v1/user/{userId}", wrapHandler(userHandler.GetUserHandler, "GetUser"))
 		r.Put("/v1/user/{userId}", wrapHandler(userHandler.UpdateUserHandler, "UpdateUser"))
+		r.Patch("/v1/user/{userId}", wrapHandler(userHandler.PatchUserHandler, "PatchUser"))
 		r.Delete("/v1/user/{userId}", wrapHandler(userHandler.DeleteUserHandler, "DeleteUser"))
 
-		r.Post("/v1/course", wrapHandler(courseHandler.CreateCourseHandler, "CreateCourse"))
+		r.With(idempotency.Handler).Post("/v1/course", wrapHandler(courseHandler.CreateCourseHandler, "CreateCourse"))
+		r.With(idempotency.Handler).Post("/v1/courses:batch", wrapHandler(courseHandler.BatchCoursesHandler, "BatchCourses"))
 		r.Put("/v1/course/{courseId}", wrapHandler(courseHandler.UpdateCourseHandler, "UpdateCourse"))
 		r.Patch("/v1/course/{courseId}", wrapHandler(courseHandler.PatchCourseHandler, "PatchCourse"))
 		r.Delete("/v1/course/{courseId}", wrapHandler(courseHandler.DeleteCourseHandler, "DeleteCourse"))
+		r.Get("/v1/course/{courseId}/sentiment", wrapHandler(courseHandler.GetCourseSentimentHandler, "GetCourseSentiment"))
 
-		r.Post("/v1/instructor", wrapHandler(instructorHandler.CreateInstructorHandler, "CreateInstructor"))
+		r.With(idempotency.Handler).Post("/v1/instructor", wrapHandler(instructorHandler.CreateInstructorHandler, "CreateInstructor"))
 		r.Put("/v1/instructor/{instructorId}", wrapHandler(instructorHandler.UpdateInstructorHandler, "UpdateInstructor"))
 		r.Patch("/v1/instructor/{instructorId}", wrapHandler(instructorHandler.PatchInstructorHandler, "PatchInstructor"))
 		r.Delete("/v1/instructor/{instructorId}", wrapHandler(instructorHandler.DeleteInstructorHandler, "DeleteInstructor"))
+		r.Get("/v1/instructor/{instructorId}/sentiment", wrapHandler(instructorHandler.GetInstructorSentimentHandler, "GetInstructorSentiment"))
 
-		r.Post("/v1/course/{course_id}/trace", wrapHandler(traceHandler.UploadTraceHandler, "UploadTrace"))
+		r.With(idempotency.Handler).Post("/v1/course/{course_id}/trace", wrapHandler(traceHandler.UploadTraceHandler, "UploadTrace"))
 		r.Get("/v1/course/{course_id}/trace/{trace_id}", wrapHandler(traceHandler.GetTraceHandler, "GetTrace"))
 		r.Get("/v1/course/{course_id}/trace", wrapHandler(traceHandler.GetAllTracesHandler, "GetAllTraces"))
 		r.Delete("/v1/course/{course_id}/trace/{trace_id}", wrapHandler(traceHandler.DeleteTraceHandler, "DeleteTrace"))
+		r.Post("/v1/course/{course_id}/ask", wrapHandler(askHandler.AskCourseHandler, "AskCourse"))
+
+		r.Patch("/v1/catalog/{code}", wrapHandler(catalogHandler.PatchCatalogCourseHandler, "PatchCatalogCourse"))
+		r.With(idempotency.Handler).Post("/v1/catalog/{code}/offerings", wrapHandler(catalogHandler.CreateOfferingHandler, "CreateOffering"))
+		r.Get("/v1/catalog/{code}/offerings/{offeringId}/traces", wrapHandler(catalogHandler.ListOfferingTracesHandler, "ListOfferingTraces"))
+		r.With(idempotency.Handler).Post("/v1/catalog/{code}/offerings/{offeringId}/traces", wrapHandler(catalogHandler.UploadOfferingTraceHandler, "UploadOfferingTrace"))
+
+		r.Get("/v1/search", wrapHandler(searchHandler.SearchHandler, "Search"))
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.AdminMiddleware)
+
+			r.Get("/v1/admin/parsers", wrapHandler(traceHandler.ListParsersHandler, "ListParsers"))
+			r.Post("/v1/admin/traces/reparse", wrapHandler(traceHandler.ReparseTracesHandler, "ReparseTraces"))
+			r.Get("/v1/admin/traces/reparse/{jobId}", wrapHandler(traceHandler.GetReparseJobHandler, "GetReparseJob"))
+		})
+	})
+
+	// v2 endpoints exchange the request and response types of internal/dto
+	r.Route("/v2", func(r chi.Router) {
+		r.Post("/users", wrapHandler(userHandler.CreateUserV2Handler, "CreateUserV2"))
+		r.Get("/courses", wrapHandler(courseHandler.ListCoursesV2Handler, "ListCoursesV2"))
+		r.Get("/courses/{courseId}", wrapHandler(courseHandler.GetCourseV2Handler, "GetCourseV2"))
+		r.Get("/instructors", wrapHandler(instructorHandler.ListInstructorsV2Handler, "ListInstructorsV2"))
+		r.Get("/instructors/{instructorId}", wrapHandler(instructorHandler.GetInstructorV2Handler, "GetInstructorV2"))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.BasicAuthMiddleware)
+
+			r.Get("/users/{userId}", wrapHandler(userHandler.GetUserV2Handler, "GetUserV2"))
+			r.Put("/users/{userId}", wrapHandler(userHandler.UpdateUserV2Handler, "UpdateUserV2"))
+			r.Patch("/users/{userId}", wrapHandler(userHandler.PatchUserV2Handler, "PatchUserV2"))
+			r.Delete("/users/{userId}", wrapHandler(userHandler.DeleteUserV2Handler, "DeleteUserV2"))
+
+			r.With(idempotency.Handler).Post("/courses", wrapHandler(courseHandler.CreateCourseV2Handler, "CreateCourseV2"))
+			r.Put("/courses/{courseId}", wrapHandler(courseHandler.UpdateCourseV2Handler, "UpdateCourseV2"))
+			r.Patch("/courses/{courseId}", wrapHandler(courseHandler.PatchCourseV2Handler, "PatchCourseV2"))
+			r.Delete("/courses/{courseId}", wrapHandler(courseHandler.DeleteCourseV2Handler, "DeleteCourseV2"))
+
+			r.With(idempotency.Handler).Post("/instructors", wrapHandler(instructorHandler.CreateInstructorV2Handler, "CreateInstructorV2"))
+			r.Put("/instructors/{instructorId}", wrapHandler(instructorHandler.UpdateInstructorV2Handler, "UpdateInstructorV2"))
+			r.Patch("/instructors/{instructorId}", wrapHandler(instructorHandler.PatchInstructorV2Handler, "PatchInstructorV2"))
+			r.Delete("/instructors/{instructorId}", wrapHandler(instructorHandler.DeleteInstructorV2Handler, "DeleteInstructorV2"))
+		})
 	})
 	return r
 }
 
+// idempotencyTTL is how long responses are kept for Idempotency-Key replays,
+// from IDEMPOTENCY_TTL (default 24h)
+func idempotencyTTL() time.Duration {
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("⚠️ Ignoring invalid IDEMPOTENCY_TTL %q", value)
+	}
+	return 24 * time.Hour
+}
+
+// exportTimeout bounds CSV and NDJSON exports, from EXPORT_TIMEOUT (default 10m)
+func exportTimeout() time.Duration {
+	if value := os.Getenv("EXPORT_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+		log.Printf("⚠️ Ignoring invalid EXPORT_TIMEOUT %q", value)
+	}
+	return 10 * time.Minute
+}
+
+// requestTimeout cancels requests after timeout. Exports get exportTimeout
+// instead, and their write deadline is moved past the server's WriteTimeout.
+func requestTimeout(timeout, exportTimeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		regular := middleware.Timeout(timeout)(next)
+		export := middleware.Timeout(exportTimeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !handlers.IsExport(r) {
+				regular.ServeHTTP(w, r)
+				return
+			}
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout)); err != nil {
+				log.Printf("⚠️ Could not extend the write deadline of an export: %v", err)
+			}
+			export.ServeHTTP(w, r)
+		})
+	}
+}
+
+// grpcAddr is where the gRPC API listens, from GRPC_ADDR (default :9090)
+func grpcAddr() string {
+	if value := os.Getenv("GRPC_ADDR"); value != "" {
+		return value
+	}
+	return ":9090"
+}
+
+// runGRPC serves the gRPC API on its own port, next to the REST server
+func (a *application) runGRPC() error {
+	listener, err := net.Listen("tcp", a.config.grpcAddr)
+	if err != nil {
+		return err
+	}
+	log.Println("Starting gRPC server on", a.config.grpcAddr)
+	return rpc.NewServer(a.store, os.Getenv("GCS_BUCKET_NAME"), a.pipeline).Serve(listener)
+}
+
 func (a *application) run(mux http.Handler) error {
 	srv := &http.Server{
 		Addr:         a.config.addr,
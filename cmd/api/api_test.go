@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/csye7125/team01/internal/openapi"
+	"github.com/csye7125/team01/internal/sentiment"
+	"github.com/csye7125/team01/internal/store"
+)
+
+func TestRoutesMatchSpecification(t *testing.T) {
+	analyzer, err := sentiment.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	specValidator, err := openapi.NewValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 🔹 Mounting only builds the router; no request reaches the store
+	app := NewApplication(store.NewStorage(nil), analyzer, specValidator)
+	if err := openapi.CheckRoutes(app.mount()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"log"
 	"time"
 
+	"github.com/csye7125/team01/internal/cache"
 	"github.com/csye7125/team01/internal/db"
+	"github.com/csye7125/team01/internal/events"
+	"github.com/csye7125/team01/internal/openapi"
+	"github.com/csye7125/team01/internal/sentiment"
 	"github.com/csye7125/team01/internal/store"
 )
 
@@ -26,6 +30,18 @@ func main() {
 		}
 	}()
 
+	shutdownMeter, err := InitMeter()
+	if err != nil {
+		log.Fatalf("Failed to initialize OpenTelemetry metrics: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownMeter(ctx); err != nil {
+			log.Printf("Error shutting down OpenTelemetry metrics: %v", err)
+		}
+	}()
+
 	// ✅ Connect to DB using GORM
 	database, err := db.ConnectDB()
 	if err != nil {
@@ -33,14 +49,108 @@ func main() {
 	}
 
 	// ✅ Run automatic migrations
-	database.AutoMigrate(&store.User{})
+	database.AutoMigrate(&store.User{}, &store.OutboxEvent{}, &store.TraceResult{}, &store.TraceComment{},
+		&store.CommentTheme{}, &store.TraceSentiment{}, &store.TracePage{}, &store.TraceChunk{}, &store.IdempotencyRecord{}, &store.CatalogCourse{})
+	if err := store.EnsureTraceColumns(database); err != nil {
+		log.Fatalf("❌ Could not migrate trace columns: %v", err)
+	}
+	if err := store.EnsureSearchIndexes(database, store.SearchLanguage()); err != nil {
+		log.Fatalf("❌ Could not create search indexes: %v", err)
+	}
+	if err := store.EnsureCourseIndexes(database); err != nil {
+		log.Fatalf("❌ Could not create course indexes: %v", err)
+	}
+	if err := store.EnsureInstructorIndexes(database); err != nil {
+		log.Fatalf("❌ Could not create instructor indexes: %v", err)
+	}
+	if err := store.EnsureCatalogColumns(database); err != nil {
+		log.Fatalf("❌ Could not create catalog columns: %v", err)
+	}
 
 	fmt.Println("✅ Database migrations completed!")
 
 	// ✅ Fix: Use `NewStorage(database)` correctly
 	storage := store.NewStorage(database)
-	app := NewApplication(storage) // ✅ Fix: app.store is now correctly initialized
+
+	// ✅ Cache public reads when a cache backend is configured, dropping
+	// entries that any replica's writes announce over NOTIFY
+	readCache, err := cache.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Could not configure cache: %v", err)
+	}
+	if readCache != nil {
+		dsn, err := db.DSN()
+		if err != nil {
+			log.Fatalf("❌ Could not configure cache: %v", err)
+		}
+		storage.UseCache(readCache)
+		listenCtx, stopListening := context.WithCancel(context.Background())
+		defer stopListening()
+		go cache.Listen(listenCtx, dsn, readCache)
+		fmt.Println("✅ Read cache enabled!")
+	}
+
+	// ✅ Fail fast on a broken sentiment lexicon
+	analyzer, err := sentiment.Default()
+	if err != nil {
+		log.Fatalf("❌ Could not load sentiment lexicon: %v", err)
+	}
+
+	// ✅ Fail fast on an invalid API specification
+	specValidator, err := openapi.NewValidator()
+	if err != nil {
+		log.Fatalf("❌ Could not load API specification: %v", err)
+	}
+
+	app := NewApplication(storage, analyzer, specValidator) // ✅ Fix: app.store is now correctly initialized
+
+	// ✅ Start the outbox relay when an event broker is configured
+	broker, err := events.NewBrokerFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Could not configure event broker: %v", err)
+	}
+	if broker != nil {
+		defer broker.Close()
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go events.NewRelay(storage.Outbox, broker).Run(relayCtx)
+		fmt.Println("✅ Outbox relay started!")
+	}
+
+	// ✅ Purge expired idempotency keys in the background
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go purgeIdempotencyKeys(purgeCtx, storage.Idempotency, time.Hour)
 
 	mux := app.mount()
+
+	// ✅ Warn about routes the API specification does not describe; the route test enforces it
+	if err := openapi.CheckRoutes(mux); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
+	// ✅ Serve gRPC next to REST
+	go func() {
+		log.Fatal(app.runGRPC())
+	}()
+
 	log.Fatal(app.run(mux))
-}
\ No newline at end of file
+}
+
+// purgeIdempotencyKeys deletes expired Idempotency-Key records every interval
+func purgeIdempotencyKeys(ctx context.Context, idempotency *store.IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if purged, err := idempotency.PurgeExpired(ctx); err != nil {
+				log.Printf("❌ Could not purge idempotency keys: %v", err)
+			} else if purged > 0 {
+				log.Printf("🧹 Purged %d expired idempotency keys", purged)
+			}
+		}
+	}
+}
@@ -7,9 +7,11 @@ import (
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp" // Added this
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
@@ -41,6 +43,28 @@ func InitTracer() (func(context.Context) error, error) {
 		return nil, err
 	}
 
+	res, err := serviceResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up the trace provider
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	)
+	otel.SetTracerProvider(tp)
+
+	// Set up the propagator for distributed tracing
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// Return a function to shut down the exporter
+	return tp.Shutdown, nil
+}
+
+// serviceResource describes this service to the collector
+func serviceResource(ctx context.Context) (*resource.Resource, error) {
 	// Get service info from environment
 	serviceName := os.Getenv("SERVICE_NAME")
 	if serviceName == "" {
@@ -53,29 +77,45 @@ func InitTracer() (func(context.Context) error, error) {
 	}
 
 	// Create a resource with service information
-	res, err := resource.New(ctx,
+	return resource.New(ctx,
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String(serviceName),
 			semconv.ServiceVersionKey.String(serviceVersion),
 		),
 	)
+}
+
+// InitMeter sets up the OpenTelemetry meter provider, exporting to the same
+// collector as traces
+func InitMeter() (func(context.Context) error, error) {
+	ctx := context.Background()
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "opentelemetry-collector.monitoring.svc.cluster.local:4317"
+	}
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(endpoint),
+	}
+	if os.Getenv("OTEL_INSECURE") == "true" {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := serviceResource(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set up the trace provider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
 	)
-	otel.SetTracerProvider(tp)
-
-	// Set up the propagator for distributed tracing
-	otel.SetTextMapPropagator(propagation.TraceContext{})
-
-	// Return a function to shut down the exporter
-	return tp.Shutdown, nil
+	otel.SetMeterProvider(mp)
+	return mp.Shutdown, nil
 }
 
 // InstrumentedHTTPClient returns an HTTP client instrumented with OpenTelemetry
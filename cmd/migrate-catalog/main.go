@@ -0,0 +1,51 @@
+// Command migrate-catalog files existing courses under catalog courses, once,
+// when upgrading to the catalog. It prints what it would do and changes
+// nothing unless run with -apply. Courses whose name, description or credit
+// hours differ from the others of their code are listed and left unfiled, to
+// be settled by hand and picked up by running it again.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/csye7125/team01/internal/db"
+	"github.com/csye7125/team01/internal/store"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "commit the migration instead of only reporting it")
+	flag.Parse()
+
+	database, err := db.ConnectDB()
+	if err != nil {
+		log.Fatalf("❌ Could not connect to the database: %v", err)
+	}
+	if err := database.AutoMigrate(&store.CatalogCourse{}); err != nil {
+		log.Fatalf("❌ Could not migrate catalog courses: %v", err)
+	}
+	if err := store.EnsureCatalogColumns(database); err != nil {
+		log.Fatalf("❌ Could not create catalog columns: %v", err)
+	}
+
+	migration, err := store.MigrateCatalog(database, *apply)
+	if err != nil {
+		log.Fatalf("❌ Could not migrate courses to the catalog: %v", err)
+	}
+
+	if *apply {
+		fmt.Printf("Created %d catalog courses and filed %d courses under them\n", migration.Created, migration.Linked)
+	} else {
+		fmt.Printf("Would create %d catalog courses and file %d courses under them\n", migration.Created, migration.Linked)
+	}
+	if len(migration.Conflicts) > 0 {
+		fmt.Printf("⚠️ %d courses differ from the catalog course of their code and were left unfiled:\n", len(migration.Conflicts))
+		for _, course := range migration.Conflicts {
+			fmt.Printf("  course %d  %s %d  %s: %q, %d credit hours\n", course.ID, course.SemesterTerm, course.SemesterYear, course.Code, course.Name, course.CreditHours)
+		}
+	}
+	if !*apply {
+		fmt.Println("Nothing was changed; run with -apply to commit")
+	}
+}
@@ -0,0 +1,245 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// SignificanceLevel is the p-value below which a difference counts as significant
+const SignificanceLevel = 0.05
+
+// similarQuestionThreshold is the word overlap above which two wordings are the same question
+const similarQuestionThreshold = 0.75
+
+type CourseSummary struct {
+	CourseID     uint     `json:"course_id"`
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	SemesterTerm string   `json:"semester_term"`
+	SemesterYear int      `json:"semester_year"`
+	CreditHours  int      `json:"credit_hours"`
+	InstructorID uint     `json:"instructor_id"`
+	Traces       int      `json:"traces"`
+	Respondents  int      `json:"respondents"`
+	Invited      int      `json:"invited"`
+	ResponseRate *float64 `json:"response_rate"`
+}
+
+type CourseScore struct {
+	CourseID     uint     `json:"course_id"`
+	Mean         float64  `json:"mean"`
+	StdDev       float64  `json:"std_dev"`
+	Respondents  int      `json:"respondents"`
+	ResponseRate *float64 `json:"response_rate"`
+}
+
+// Difference compares two courses on one question.
+// PValue is nil when the response counts are too small to test.
+type Difference struct {
+	CourseA     uint     `json:"course_a"`
+	CourseB     uint     `json:"course_b"`
+	Difference  float64  `json:"difference"`
+	PValue      *float64 `json:"p_value"`
+	Significant bool     `json:"significant"`
+}
+
+// QuestionComparison lines up one question across courses.
+// Scores follow the order of Courses in the comparison and are nil where a course lacks the question.
+type QuestionComparison struct {
+	Key         string         `json:"key"`
+	Question    string         `json:"question"`
+	Wordings    []string       `json:"wordings"`
+	Scores      []*CourseScore `json:"scores"`
+	Differences []Difference   `json:"differences"`
+}
+
+type CourseComparison struct {
+	Courses           []CourseSummary      `json:"courses"`
+	Questions         []QuestionComparison `json:"questions"`
+	SignificanceLevel float64              `json:"significance_level"`
+}
+
+// BuildCourseComparison aligns the parsed results of the given courses by normalized question
+func BuildCourseComparison(courses []store.Course, results []store.TraceResult) CourseComparison {
+	comparison := CourseComparison{
+		Courses:           make([]CourseSummary, len(courses)),
+		Questions:         []QuestionComparison{},
+		SignificanceLevel: SignificanceLevel,
+	}
+	position := map[uint]int{}
+	for i, course := range courses {
+		position[course.ID] = i
+		comparison.Courses[i] = CourseSummary{
+			CourseID:     course.ID,
+			Code:         course.Code,
+			Name:         course.Name,
+			SemesterTerm: course.SemesterTerm,
+			SemesterYear: course.SemesterYear,
+			CreditHours:  course.CreditHours,
+			InstructorID: course.InstructorID,
+		}
+	}
+
+	keys := mergeSimilarKeys(results)
+
+	type cell struct {
+		samples   []Sample
+		responded int
+		invited   int
+	}
+	type questionGroup struct {
+		wording  string
+		latest   uint
+		wordings map[string]bool
+		cells    map[int]*cell
+	}
+	groups := map[string]*questionGroup{}
+
+	// 🔹 Course totals take each trace's largest response and invitation counts
+	type traceTotals struct{ course, responded, invited int }
+	traces := map[uint]*traceTotals{}
+
+	for _, result := range results {
+		index, ok := position[result.CourseID]
+		if !ok {
+			continue
+		}
+
+		totals, ok := traces[result.TraceID]
+		if !ok {
+			totals = &traceTotals{course: index}
+			traces[result.TraceID] = totals
+		}
+		totals.responded = max(totals.responded, result.ResponseCount)
+		totals.invited = max(totals.invited, result.InvitedCount)
+
+		key := keys[result.Question]
+		group, ok := groups[key]
+		if !ok {
+			group = &questionGroup{wordings: map[string]bool{}, cells: map[int]*cell{}}
+			groups[key] = group
+		}
+		group.wordings[result.Question] = true
+		// Label each question with the wording of the newest trace
+		if result.TraceID >= group.latest {
+			group.latest = result.TraceID
+			group.wording = result.Question
+		}
+
+		c, ok := group.cells[index]
+		if !ok {
+			c = &cell{}
+			group.cells[index] = c
+		}
+		c.samples = append(c.samples, Sample{Mean: result.Mean, StdDev: result.StdDev, N: result.ResponseCount})
+		c.responded += result.ResponseCount
+		c.invited += result.InvitedCount
+	}
+
+	for _, totals := range traces {
+		summary := &comparison.Courses[totals.course]
+		summary.Traces++
+		summary.Respondents += totals.responded
+		summary.Invited += totals.invited
+	}
+	for i := range comparison.Courses {
+		comparison.Courses[i].ResponseRate = rate(comparison.Courses[i].Respondents, comparison.Courses[i].Invited)
+	}
+
+	for key, group := range groups {
+		question := QuestionComparison{
+			Key:         key,
+			Question:    group.wording,
+			Scores:      make([]*CourseScore, len(courses)),
+			Differences: []Difference{},
+		}
+		for wording := range group.wordings {
+			question.Wordings = append(question.Wordings, wording)
+		}
+		sort.Strings(question.Wordings)
+
+		pooled := make([]Sample, len(courses))
+		for index, c := range group.cells {
+			pooled[index] = Combine(c.samples)
+			question.Scores[index] = &CourseScore{
+				CourseID:     courses[index].ID,
+				Mean:         pooled[index].Mean,
+				StdDev:       pooled[index].StdDev,
+				Respondents:  pooled[index].N,
+				ResponseRate: rate(c.responded, c.invited),
+			}
+		}
+
+		// 🔹 Pairwise Welch tests between courses that both have the question
+		for i := 0; i < len(courses); i++ {
+			for j := i + 1; j < len(courses); j++ {
+				if question.Scores[i] == nil || question.Scores[j] == nil {
+					continue
+				}
+				difference := Difference{
+					CourseA:    courses[i].ID,
+					CourseB:    courses[j].ID,
+					Difference: pooled[i].Mean - pooled[j].Mean,
+				}
+				if p, ok := WelchTest(pooled[i], pooled[j]); ok {
+					difference.PValue = &p
+					difference.Significant = p < SignificanceLevel
+				}
+				question.Differences = append(question.Differences, difference)
+			}
+		}
+		comparison.Questions = append(comparison.Questions, question)
+	}
+	sort.Slice(comparison.Questions, func(i, j int) bool { return comparison.Questions[i].Key < comparison.Questions[j].Key })
+
+	return comparison
+}
+
+// mergeSimilarKeys maps every wording to a question key, folding near-identical keys together
+func mergeSimilarKeys(results []store.TraceResult) map[string]string {
+	wordingKeys := map[string]string{}
+	uniqueKeys := map[string]bool{}
+	for _, result := range results {
+		if _, ok := wordingKeys[result.Question]; ok {
+			continue
+		}
+		key := QuestionKey(result.Question)
+		wordingKeys[result.Question] = key
+		uniqueKeys[key] = true
+	}
+
+	sorted := make([]string, 0, len(uniqueKeys))
+	for key := range uniqueKeys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var representatives []string
+	canonical := map[string]string{}
+	for _, key := range sorted {
+		canonical[key] = key
+		for _, representative := range representatives {
+			if questionSimilarity(key, representative) >= similarQuestionThreshold {
+				canonical[key] = representative
+				break
+			}
+		}
+		if canonical[key] == key {
+			representatives = append(representatives, key)
+		}
+	}
+
+	for wording, key := range wordingKeys {
+		wordingKeys[wording] = canonical[key]
+	}
+	return wordingKeys
+}
+
+func rate(part, whole int) *float64 {
+	if whole <= 0 {
+		return nil
+	}
+	value := float64(part) / float64(whole)
+	return &value
+}
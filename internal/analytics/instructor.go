@@ -0,0 +1,229 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// termOrder ranks semester terms within an academic year
+var termOrder = map[string]int{
+	"spring": 1,
+	"summer": 2,
+	"fall":   3,
+}
+
+// Semester identifies a term, e.g. Fall 2024
+type Semester struct {
+	Term string `json:"semester_term"`
+	Year int    `json:"semester_year"`
+}
+
+// Before reports whether s comes earlier than other. Terms outside termOrder
+// come after the known terms of their year, alphabetically.
+func (s Semester) Before(other Semester) bool {
+	if s.Year != other.Year {
+		return s.Year < other.Year
+	}
+	term, otherTerm := strings.ToLower(s.Term), strings.ToLower(other.Term)
+	order, known := termOrder[term]
+	otherOrder, otherKnown := termOrder[otherTerm]
+	switch {
+	case known && otherKnown:
+		return order < otherOrder
+	case known != otherKnown:
+		return known
+	}
+	if term != otherTerm {
+		return term < otherTerm
+	}
+	return s.Term < other.Term
+}
+
+type QuestionScore struct {
+	Question     string  `json:"question"`
+	WeightedMean float64 `json:"weighted_mean"`
+	Respondents  int     `json:"respondents"`
+	Courses      int     `json:"courses"`
+}
+
+type SemesterScores struct {
+	Semester
+	Questions []QuestionScore `json:"questions"`
+}
+
+type TrendPoint struct {
+	Semester
+	WeightedMean float64 `json:"weighted_mean"`
+	Respondents  int     `json:"respondents"`
+}
+
+// QuestionTrend follows one question across semesters.
+// Change is last minus first; Slope is the least-squares change per semester taught.
+type QuestionTrend struct {
+	Question string       `json:"question"`
+	Points   []TrendPoint `json:"points"`
+	Change   float64      `json:"change"`
+	Slope    float64      `json:"slope"`
+}
+
+type Distribution struct {
+	Instructors int     `json:"instructors"`
+	Min         float64 `json:"min"`
+	P25         float64 `json:"p25"`
+	Median      float64 `json:"median"`
+	P75         float64 `json:"p75"`
+	Max         float64 `json:"max"`
+}
+
+// DepartmentComparison places the instructor's overall score among all instructors of the department
+type DepartmentComparison struct {
+	Question       string       `json:"question"`
+	InstructorMean float64      `json:"instructor_mean"`
+	Respondents    int          `json:"respondents"`
+	Department     Distribution `json:"department"`
+	PercentileRank float64      `json:"percentile_rank"`
+}
+
+type InstructorReport struct {
+	InstructorID uint                   `json:"instructor_id"`
+	Name         string                 `json:"name"`
+	Departments  []string               `json:"departments"`
+	Semesters    []SemesterScores       `json:"semesters"`
+	Trends       []QuestionTrend        `json:"trends"`
+	Comparison   []DepartmentComparison `json:"department_comparison"`
+}
+
+// BuildInstructorReport assembles per-semester scores, trends and department comparison
+// from the weighted means computed by the store
+func BuildInstructorReport(instructor *store.Instructor, departments []string, scores []store.InstructorSemesterScore, departmentMeans []store.InstructorQuestionMean) InstructorReport {
+	report := InstructorReport{
+		InstructorID: instructor.InstructorID,
+		Name:         instructor.Name,
+		Departments:  departments,
+		Semesters:    []SemesterScores{},
+		Trends:       []QuestionTrend{},
+		Comparison:   []DepartmentComparison{},
+	}
+
+	// 🔹 Group scores by semester, oldest first
+	bySemester := map[Semester]*SemesterScores{}
+	byQuestion := map[string][]TrendPoint{}
+	for _, score := range scores {
+		semester := Semester{Term: score.SemesterTerm, Year: score.SemesterYear}
+		group, ok := bySemester[semester]
+		if !ok {
+			group = &SemesterScores{Semester: semester}
+			bySemester[semester] = group
+		}
+		group.Questions = append(group.Questions, QuestionScore{
+			Question:     score.Question,
+			WeightedMean: score.WeightedMean,
+			Respondents:  score.Respondents,
+			Courses:      score.Courses,
+		})
+		byQuestion[score.Question] = append(byQuestion[score.Question], TrendPoint{
+			Semester:     semester,
+			WeightedMean: score.WeightedMean,
+			Respondents:  score.Respondents,
+		})
+	}
+	for _, group := range bySemester {
+		sort.Slice(group.Questions, func(i, j int) bool { return group.Questions[i].Question < group.Questions[j].Question })
+		report.Semesters = append(report.Semesters, *group)
+	}
+	sort.Slice(report.Semesters, func(i, j int) bool { return report.Semesters[i].Before(report.Semesters[j].Semester) })
+
+	// 🔹 Trends per question
+	for question, points := range byQuestion {
+		sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j].Semester) })
+		trend := QuestionTrend{Question: question, Points: points}
+		if len(points) > 1 {
+			trend.Change = points[len(points)-1].WeightedMean - points[0].WeightedMean
+			trend.Slope = slope(points)
+		}
+		report.Trends = append(report.Trends, trend)
+	}
+	sort.Slice(report.Trends, func(i, j int) bool { return report.Trends[i].Question < report.Trends[j].Question })
+
+	// 🔹 Compare the instructor's overall mean with the department per question
+	peers := map[string][]float64{}
+	own := map[string]store.InstructorQuestionMean{}
+	for _, mean := range departmentMeans {
+		peers[mean.Question] = append(peers[mean.Question], mean.WeightedMean)
+		if mean.InstructorID == instructor.InstructorID {
+			own[mean.Question] = mean
+		}
+	}
+	for question, mine := range own {
+		values := peers[question]
+		sort.Float64s(values)
+		report.Comparison = append(report.Comparison, DepartmentComparison{
+			Question:       question,
+			InstructorMean: mine.WeightedMean,
+			Respondents:    mine.Respondents,
+			Department: Distribution{
+				Instructors: len(values),
+				Min:         values[0],
+				P25:         Percentile(values, 25),
+				Median:      Percentile(values, 50),
+				P75:         Percentile(values, 75),
+				Max:         values[len(values)-1],
+			},
+			PercentileRank: PercentileRank(values, mine.WeightedMean),
+		})
+	}
+	sort.Slice(report.Comparison, func(i, j int) bool { return report.Comparison[i].Question < report.Comparison[j].Question })
+
+	return report
+}
+
+// Percentile interpolates the p-th percentile (0-100) of sorted values
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
+}
+
+// PercentileRank is the share of values below v, counting ties as half, in percent
+func PercentileRank(values []float64, v float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	below, equal := 0, 0
+	for _, value := range values {
+		switch {
+		case value < v:
+			below++
+		case value == v:
+			equal++
+		}
+	}
+	return (float64(below) + float64(equal)/2) / float64(len(values)) * 100
+}
+
+// slope fits a least-squares line through the points, using their position as x
+func slope(points []TrendPoint) float64 {
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, point := range points {
+		x := float64(i)
+		sumX += x
+		sumY += point.WeightedMean
+		sumXY += x * point.WeightedMean
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+func TestSemesterBefore(t *testing.T) {
+	ordered := []Semester{
+		{"Fall", 2023},
+		{"spring", 2024},
+		{"Summer", 2024},
+		{"FALL", 2024},
+		{"Intersession", 2024},
+		{"winter", 2024},
+		{"Spring", 2025},
+	}
+	for i, earlier := range ordered {
+		for _, later := range ordered[i+1:] {
+			if !earlier.Before(later) || later.Before(earlier) {
+				t.Errorf("want %v before %v", earlier, later)
+			}
+		}
+	}
+
+	shuffled := []Semester{ordered[5], ordered[2], ordered[6], ordered[0], ordered[4], ordered[3], ordered[1]}
+	sort.Slice(shuffled, func(i, j int) bool { return shuffled[i].Before(shuffled[j]) })
+	if !reflect.DeepEqual(shuffled, ordered) {
+		t.Errorf("sorted = %v, want %v", shuffled, ordered)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{nil, 50, 0},
+		{[]float64{5}, 50, 5},
+		{[]float64{1, 2, 3, 4}, 0, 1},
+		{[]float64{1, 2, 3, 4}, 25, 1.75},
+		{[]float64{1, 2, 3, 4}, 50, 2.5},
+		{[]float64{1, 2, 3, 4}, 100, 4},
+		{[]float64{3, 4, 5}, 75, 4.5},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.sorted, tt.p); !near(got, tt.want, 1e-9) {
+			t.Errorf("Percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPercentileRank(t *testing.T) {
+	tests := []struct {
+		values []float64
+		v      float64
+		want   float64
+	}{
+		{nil, 3, 0},
+		{[]float64{1, 2, 2, 3}, 2, 50},         // one below, two ties counted as half
+		{[]float64{1, 2, 2, 3}, 0, 0},          // below everyone
+		{[]float64{1, 2, 2, 3}, 5, 100},        // above everyone
+		{[]float64{3, 1, 2}, 3, 100 * 2.5 / 3}, // values need not be sorted
+	}
+	for _, tt := range tests {
+		if got := PercentileRank(tt.values, tt.v); !near(got, tt.want, 1e-9) {
+			t.Errorf("PercentileRank(%v, %v) = %v, want %v", tt.values, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestSlope(t *testing.T) {
+	tests := []struct {
+		means []float64
+		want  float64
+	}{
+		{[]float64{4}, 0},
+		{[]float64{1, 2, 3}, 1},
+		{[]float64{4, 4, 4}, 0},
+		{[]float64{1, 3, 2}, 0.5},
+		{[]float64{3, 4, 3.6, 5}, 0.56},
+	}
+	for _, tt := range tests {
+		points := make([]TrendPoint, len(tt.means))
+		for i, mean := range tt.means {
+			points[i].WeightedMean = mean
+		}
+		if got := slope(points); !near(got, tt.want, 1e-9) {
+			t.Errorf("slope(%v) = %v, want %v", tt.means, got, tt.want)
+		}
+	}
+}
+
+func TestBuildInstructorReport(t *testing.T) {
+	instructor := &store.Instructor{InstructorID: 1, Name: "Ada Lovelace"}
+	scores := []store.InstructorSemesterScore{
+		{SemesterTerm: "Fall", SemesterYear: 2024, Question: "Clarity", WeightedMean: 4, Respondents: 20, Courses: 1},
+		{SemesterTerm: "Spring", SemesterYear: 2025, Question: "Clarity", WeightedMean: 5, Respondents: 10, Courses: 1},
+		{SemesterTerm: "Winter", SemesterYear: 2024, Question: "Clarity", WeightedMean: 3.6, Respondents: 5, Courses: 1},
+		{SemesterTerm: "Spring", SemesterYear: 2024, Question: "Pacing", WeightedMean: 3.5, Respondents: 30, Courses: 2},
+		{SemesterTerm: "Spring", SemesterYear: 2024, Question: "Clarity", WeightedMean: 3, Respondents: 30, Courses: 2},
+	}
+	departmentMeans := []store.InstructorQuestionMean{
+		{InstructorID: 2, Question: "Clarity", WeightedMean: 3, Respondents: 40},
+		{InstructorID: 1, Question: "Clarity", WeightedMean: 4, Respondents: 65},
+		{InstructorID: 3, Question: "Clarity", WeightedMean: 5, Respondents: 12},
+		{InstructorID: 2, Question: "Pacing", WeightedMean: 4, Respondents: 40},
+	}
+
+	report := BuildInstructorReport(instructor, []string{"CSYE"}, scores, departmentMeans)
+
+	var semesters []Semester
+	for _, group := range report.Semesters {
+		semesters = append(semesters, group.Semester)
+	}
+	wantSemesters := []Semester{{"Spring", 2024}, {"Fall", 2024}, {"Winter", 2024}, {"Spring", 2025}}
+	if !reflect.DeepEqual(semesters, wantSemesters) {
+		t.Errorf("semesters = %v, want %v", semesters, wantSemesters)
+	}
+	wantSpring := []QuestionScore{
+		{Question: "Clarity", WeightedMean: 3, Respondents: 30, Courses: 2},
+		{Question: "Pacing", WeightedMean: 3.5, Respondents: 30, Courses: 2},
+	}
+	if !reflect.DeepEqual(report.Semesters[0].Questions, wantSpring) {
+		t.Errorf("Spring 2024 questions = %+v, want %+v", report.Semesters[0].Questions, wantSpring)
+	}
+
+	if len(report.Trends) != 2 {
+		t.Fatalf("trends = %+v, want Clarity and Pacing", report.Trends)
+	}
+	clarity, pacing := report.Trends[0], report.Trends[1]
+	if clarity.Question != "Clarity" || len(clarity.Points) != 4 || !near(clarity.Change, 2, 1e-9) || !near(clarity.Slope, 0.56, 1e-9) {
+		t.Errorf("Clarity trend = %+v, want 4 points, change 2 and slope 0.56", clarity)
+	}
+	if clarity.Points[2].Semester != (Semester{"Winter", 2024}) {
+		t.Errorf("Clarity points = %+v, want Winter 2024 third", clarity.Points)
+	}
+	// 🔹 One semester is no trend
+	if pacing.Question != "Pacing" || len(pacing.Points) != 1 || pacing.Change != 0 || pacing.Slope != 0 {
+		t.Errorf("Pacing trend = %+v, want a single point without change", pacing)
+	}
+
+	// 🔹 Only questions the instructor has a mean for are compared
+	wantComparison := []DepartmentComparison{{
+		Question:       "Clarity",
+		InstructorMean: 4,
+		Respondents:    65,
+		Department:     Distribution{Instructors: 3, Min: 3, P25: 3.5, Median: 4, P75: 4.5, Max: 5},
+		PercentileRank: 50,
+	}}
+	if !reflect.DeepEqual(report.Comparison, wantComparison) {
+		t.Errorf("comparison = %+v, want %+v", report.Comparison, wantComparison)
+	}
+}
+
+func TestBuildInstructorReportWithoutScores(t *testing.T) {
+	report := BuildInstructorReport(&store.Instructor{InstructorID: 1}, nil, nil, nil)
+	if report.Semesters == nil || report.Trends == nil || report.Comparison == nil {
+		t.Errorf("report = %+v, want empty lists rather than null", report)
+	}
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	questionNumbering = regexp.MustCompile(`^\s*(q(uestion)?\s*)?\d+[\.\):]?\s*`)
+	nonWord           = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// questionStopwords carry no meaning for matching reworded questions
+var questionStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "to": true, "in": true, "for": true,
+	"this": true, "that": true, "was": true, "were": true, "is": true, "are": true,
+	"and": true, "my": true, "me": true, "i": true, "on": true, "with": true,
+}
+
+var (
+	aliasesOnce    sync.Once
+	questionAlias  map[string]string
+	aliasLoadError error
+)
+
+// LoadQuestionAliases reads QUESTION_ALIASES_FILE, a JSON object mapping a canonical question
+// to the wordings used for it in other survey versions. It is read once per process.
+func LoadQuestionAliases() error {
+	aliasesOnce.Do(func() {
+		questionAlias = map[string]string{}
+		path := os.Getenv("QUESTION_ALIASES_FILE")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			aliasLoadError = fmt.Errorf("failed to read question aliases: %w", err)
+			return
+		}
+		var aliases map[string][]string
+		if err := json.Unmarshal(data, &aliases); err != nil {
+			aliasLoadError = fmt.Errorf("failed to parse question aliases: %w", err)
+			return
+		}
+		for canonical, variants := range aliases {
+			key := normalizeQuestion(canonical)
+			questionAlias[key] = key
+			for _, variant := range variants {
+				questionAlias[normalizeQuestion(variant)] = key
+			}
+		}
+	})
+	return aliasLoadError
+}
+
+// QuestionKey maps a question's wording to a stable key shared by its rewordings
+func QuestionKey(question string) string {
+	_ = LoadQuestionAliases()
+	key := normalizeQuestion(question)
+	if canonical, ok := questionAlias[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// normalizeQuestion drops numbering, case, punctuation and stopwords
+func normalizeQuestion(question string) string {
+	text := strings.ToLower(questionNumbering.ReplaceAllString(question, ""))
+	var words []string
+	for _, word := range strings.Fields(nonWord.ReplaceAllString(text, " ")) {
+		if !questionStopwords[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// questionSimilarity is the Jaccard similarity of two question keys' words
+func questionSimilarity(a, b string) float64 {
+	wordsA := strings.Fields(a)
+	wordsB := map[string]bool{}
+	for _, word := range strings.Fields(b) {
+		wordsB[word] = true
+	}
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	shared, union := 0, len(wordsB)
+	seen := map[string]bool{}
+	for _, word := range wordsA {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if wordsB[word] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,124 @@
+package analytics
+
+import "math"
+
+// Sample summarises a group of responses
+type Sample struct {
+	Mean   float64
+	StdDev float64
+	N      int
+}
+
+// Combine pools several samples of the same question into one
+func Combine(samples []Sample) Sample {
+	total := 0
+	weighted := 0.0
+	for _, s := range samples {
+		total += s.N
+		weighted += s.Mean * float64(s.N)
+	}
+	if total == 0 {
+		return Sample{}
+	}
+	mean := weighted / float64(total)
+	if total < 2 {
+		return Sample{Mean: mean, N: total}
+	}
+
+	var squares float64
+	for _, s := range samples {
+		if s.N > 1 {
+			squares += float64(s.N-1) * s.StdDev * s.StdDev
+		}
+		squares += float64(s.N) * (s.Mean - mean) * (s.Mean - mean)
+	}
+	return Sample{Mean: mean, StdDev: math.Sqrt(squares / float64(total-1)), N: total}
+}
+
+// WelchTest returns the two-sided p-value for a difference in means.
+// It returns ok=false when the samples are too small or have no spread to test.
+func WelchTest(a, b Sample) (pValue float64, ok bool) {
+	if a.N < 2 || b.N < 2 {
+		return 0, false
+	}
+	va := a.StdDev * a.StdDev / float64(a.N)
+	vb := b.StdDev * b.StdDev / float64(b.N)
+	if va+vb == 0 {
+		return 0, false
+	}
+	t := (a.Mean - b.Mean) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	return studentTwoSided(t, df), true
+}
+
+// studentTwoSided is P(|T| >= |t|) for Student's t with df degrees of freedom
+func studentTwoSided(t, df float64) float64 {
+	x := df / (df + t*t)
+	return regularizedIncompleteBeta(df/2, 0.5, x)
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with Lentz's continued fraction
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgA, _ := math.Lgamma(a)
+	lgB, _ := math.Lgamma(b)
+	lgAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgAB - lgA - lgB + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only below the mean of the distribution
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		numerator := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		result *= d * c
+		// Odd step
+		numerator = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestStudentTwoSided(t *testing.T) {
+	tests := []struct {
+		t, df, want float64
+	}{
+		{1, 1, 0.5},                         // Cauchy: P(|T| >= 1) = 1/2
+		{2, 2, 1 - 2/math.Sqrt(6)},          // closed form for two degrees of freedom
+		{0, 5, 1},                           // no difference at all
+		{1.959964, 1e7, 0.05},               // approaches the normal distribution
+		{-2, 2, 1 - 2/math.Sqrt(6)},         // two-sided, so the sign does not matter
+		{math.Sqrt(2), 225.0 / 17, 0.18039}, // Welch's df need not be whole
+	}
+	for _, tt := range tests {
+		if got := studentTwoSided(tt.t, tt.df); !near(got, tt.want, 1e-5) {
+			t.Errorf("studentTwoSided(%v, %v) = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestWelchTest(t *testing.T) {
+	// 🔹 t = 1/sqrt(0.4 + 0.1) and Welch–Satterthwaite df = 0.25 / (0.17 / 9)
+	p, ok := WelchTest(Sample{Mean: 5, StdDev: 2, N: 10}, Sample{Mean: 4, StdDev: 1, N: 10})
+	if !ok {
+		t.Fatal("WelchTest refused two testable samples")
+	}
+	if !near(p, 0.18039, 1e-5) {
+		t.Fatalf("p = %v, want 0.18039", p)
+	}
+
+	// 🔹 Swapping the samples gives the same two-sided p-value
+	if swapped, _ := WelchTest(Sample{Mean: 4, StdDev: 1, N: 10}, Sample{Mean: 5, StdDev: 2, N: 10}); !near(swapped, p, 1e-12) {
+		t.Fatalf("swapped p = %v, want %v", swapped, p)
+	}
+
+	// 🔹 A large difference with little spread is significant
+	if p, _ := WelchTest(Sample{Mean: 4.5, StdDev: 0.5, N: 40}, Sample{Mean: 3.5, StdDev: 0.6, N: 35}); p >= 0.001 {
+		t.Fatalf("p = %v for a clear difference", p)
+	}
+}
+
+func TestWelchTestUntestable(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Sample
+	}{
+		{"single response", Sample{Mean: 4, StdDev: 0, N: 1}, Sample{Mean: 3, StdDev: 1, N: 10}},
+		{"no spread", Sample{Mean: 4, StdDev: 0, N: 10}, Sample{Mean: 3, StdDev: 0, N: 10}},
+		{"empty", Sample{}, Sample{}},
+	}
+	for _, tt := range tests {
+		if _, ok := WelchTest(tt.a, tt.b); ok {
+			t.Errorf("%s: WelchTest reported a p-value", tt.name)
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	// 🔹 {1, 2, 3} and {4, 5} pool to {1, 2, 3, 4, 5}
+	pooled := Combine([]Sample{
+		{Mean: 2, StdDev: 1, N: 3},
+		{Mean: 4.5, StdDev: math.Sqrt(0.5), N: 2},
+	})
+	if pooled.N != 5 || !near(pooled.Mean, 3, 1e-12) || !near(pooled.StdDev, math.Sqrt(2.5), 1e-12) {
+		t.Fatalf("Combine = %+v, want mean 3, sd %v, n 5", pooled, math.Sqrt(2.5))
+	}
+
+	if empty := Combine(nil); empty != (Sample{}) {
+		t.Fatalf("Combine(nil) = %+v", empty)
+	}
+}
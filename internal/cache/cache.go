@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// InvalidationChannel is the Postgres NOTIFY channel writes announce changed
+// keys on, so every replica drops them from its cache
+const InvalidationChannel = "cache_invalidation"
+
+// Entry is a cached value and when it was read from the database
+type Entry struct {
+	Value    []byte
+	StoredAt time.Time
+}
+
+// Ticket marks when a read missed the cache. Set refuses a value whose ticket
+// predates a Delete of its key, as the value may have been read before the
+// write the Delete announced.
+type Ticket uint64
+
+// Cache holds encoded reads for TTL. Backends treat their own failures as
+// misses, so a broken cache only makes reads slower.
+type Cache interface {
+	// Get returns the entry for key, or on a miss the ticket to store the value read instead
+	Get(ctx context.Context, key string) (Entry, Ticket, bool)
+	// Set stores value unless key was deleted since ticket was issued
+	Set(ctx context.Context, key string, value []byte, ticket Ticket)
+	Delete(ctx context.Context, keys ...string)
+	// Purge drops whatever invalidations may have been missed
+	Purge(ctx context.Context)
+	TTL() time.Duration
+}
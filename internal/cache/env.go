@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/env"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewFromEnv builds the cache selected by CACHE_BACKEND, counting its hits and
+// misses. It returns a nil cache when caching is disabled.
+func NewFromEnv() (Cache, error) {
+	ttl, err := time.ParseDuration(env.GetString("CACHE_TTL", "30s"))
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_TTL %q", env.GetString("CACHE_TTL", ""))
+	}
+
+	var c Cache
+	switch strings.ToLower(env.GetString("CACHE_BACKEND", "")) {
+	case "":
+		return nil, nil
+	case "memory":
+		c = NewLRU(env.GetInt("CACHE_SIZE", 10000), ttl)
+	case "redis":
+		options, err := redis.ParseURL(env.GetString("REDIS_URL", "redis://localhost:6379/0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		c = NewRedis(redis.NewClient(options), env.GetString("CACHE_KEY_PREFIX", "api-server:"), ttl)
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q", env.GetString("CACHE_BACKEND", ""))
+	}
+	return NewInstrumented(c)
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listen deletes the keys announced on InvalidationChannel from c until ctx
+// is done. Notifications sent while it is disconnected are lost, so it purges
+// c each time it starts listening.
+func Listen(ctx context.Context, dsn string, c Cache) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := listen(ctx, dsn, c)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("cache listener: %v; reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func listen(ctx context.Context, dsn string, c Cache) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{InvalidationChannel}.Sanitize()); err != nil {
+		return err
+	}
+	c.Purge(ctx)
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		c.Delete(ctx, notification.Payload)
+	}
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// LRU is an in-process cache of up to size entries, each kept for ttl
+type LRU struct {
+	entries *expirable.LRU[string, Entry]
+	ttl     time.Duration
+
+	// mu orders deletes against sets. Tickets count deletes: deleted holds the
+	// count at each key's last delete, and floor the highest count it forgot.
+	mu      sync.Mutex
+	deletes uint64
+	deleted *lru.Cache[string, uint64]
+	floor   uint64
+}
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	c := &LRU{entries: expirable.NewLRU[string, Entry](size, nil, ttl), ttl: ttl}
+	c.deleted, _ = lru.NewWithEvict[string, uint64](size, func(key string, deletes uint64) {
+		c.floor = max(c.floor, deletes)
+	})
+	return c
+}
+
+func (c *LRU) Get(ctx context.Context, key string) (Entry, Ticket, bool) {
+	if entry, ok := c.entries.Get(key); ok {
+		return entry, 0, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Entry{}, Ticket(c.deletes), false
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ticket Ticket) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lastDelete, ok := c.deleted.Peek(key)
+	if !ok {
+		lastDelete = c.floor
+	}
+	if lastDelete > uint64(ticket) {
+		return
+	}
+	c.entries.Add(key, Entry{Value: value, StoredAt: time.Now()})
+}
+
+func (c *LRU) Delete(ctx context.Context, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deletes++
+	for _, key := range keys {
+		c.entries.Remove(key)
+		c.deleted.Add(key, c.deletes)
+	}
+}
+
+func (c *LRU) Purge(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deletes++
+	c.floor = c.deletes
+	c.deleted.Purge()
+	c.entries.Purge()
+}
+
+func (c *LRU) TTL() time.Duration {
+	return c.ttl
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRURefusesSetsThatPredateADelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10, time.Minute)
+
+	// 🔹 A read misses, a write deletes the key, then the read stores what it loaded
+	_, stale, _ := c.Get(ctx, "course:1")
+	c.Delete(ctx, "course:1")
+	c.Set(ctx, "course:1", []byte("old"), stale)
+	if _, _, ok := c.Get(ctx, "course:1"); ok {
+		t.Fatal("a value read before the delete was stored")
+	}
+
+	// 🔹 A read that misses after the delete may store its value
+	_, fresh, _ := c.Get(ctx, "course:1")
+	c.Set(ctx, "course:1", []byte("new"), fresh)
+	if entry, _, ok := c.Get(ctx, "course:1"); !ok || string(entry.Value) != "new" {
+		t.Fatalf("got %q, %v; want the value read after the delete", entry.Value, ok)
+	}
+
+	// 🔹 Deletes of other keys do not hold a key back
+	_, ticket, _ := c.Get(ctx, "course:2")
+	c.Delete(ctx, "course:3")
+	c.Set(ctx, "course:2", []byte("two"), ticket)
+	if _, _, ok := c.Get(ctx, "course:2"); !ok {
+		t.Fatal("a delete of another key refused the value")
+	}
+}
+
+func TestLRURefusesSetsAfterForgettingDeletes(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2, time.Minute)
+
+	// 🔹 Once the delete of a key is evicted, sets from before it are refused
+	_, stale, _ := c.Get(ctx, "course:1")
+	c.Delete(ctx, "course:1")
+	c.Delete(ctx, "course:2", "course:3")
+	c.Set(ctx, "course:1", []byte("old"), stale)
+	if _, _, ok := c.Get(ctx, "course:1"); ok {
+		t.Fatal("a value read before a forgotten delete was stored")
+	}
+
+	// 🔹 A purge may have missed deletes of any key
+	_, stale, _ = c.Get(ctx, "course:4")
+	c.Purge(ctx)
+	c.Set(ctx, "course:4", []byte("old"), stale)
+	if _, _, ok := c.Get(ctx, "course:4"); ok {
+		t.Fatal("a value read before the purge was stored")
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Instrumented counts the hits and misses of a cache, by resource, which is
+// the part of the key before the first colon
+type Instrumented struct {
+	Cache
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+func NewInstrumented(c Cache) (*Instrumented, error) {
+	meter := otel.Meter("github.com/csye7125/team01/internal/cache")
+	hits, err := meter.Int64Counter("api.cache.hits", metric.WithDescription("Reads served from the cache"))
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.Int64Counter("api.cache.misses", metric.WithDescription("Reads that went to the database"))
+	if err != nil {
+		return nil, err
+	}
+	return &Instrumented{Cache: c, hits: hits, misses: misses}, nil
+}
+
+func (c *Instrumented) Get(ctx context.Context, key string) (Entry, Ticket, bool) {
+	entry, ticket, ok := c.Cache.Get(ctx, key)
+	resource, _, _ := strings.Cut(key, ":")
+	attributes := metric.WithAttributes(attribute.String("resource", resource))
+	if ok {
+		c.hits.Add(ctx, 1, attributes)
+	} else {
+		c.misses.Add(ctx, 1, attributes)
+	}
+	return entry, ticket, ok
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// generationTTL keeps each key's generation far longer than any read of it takes
+const generationTTL = time.Hour
+
+// storeUnlessDeleted sets KEYS[1] to ARGV[2] for ARGV[3] milliseconds, unless
+// generation KEYS[2] moved on from ARGV[1] since the value was read
+var storeUnlessDeleted = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '0') ~= ARGV[1] then
+  return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// Redis is a cache shared by every replica. Values are stored behind the time
+// they were read, in Unix milliseconds, so Age stays right across replicas.
+// Each Delete gives its key a new random generation, which is the ticket of
+// later misses, so a value read before the Delete is not stored after it.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedis(client *redis.Client, prefix string, ttl time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (c *Redis) generationKey(key string) string {
+	return c.prefix + "generation:" + key
+}
+
+func (c *Redis) Get(ctx context.Context, key string) (Entry, Ticket, bool) {
+	values, err := c.client.MGet(ctx, c.prefix+key, c.generationKey(key)).Result()
+	if err != nil {
+		log.Printf("⚠️ Cache read of %s failed: %v", key, err)
+		return Entry{}, 0, false
+	}
+	if data, ok := values[0].(string); ok && len(data) >= 8 {
+		storedAt := time.UnixMilli(int64(binary.BigEndian.Uint64([]byte(data))))
+		return Entry{Value: []byte(data[8:]), StoredAt: storedAt}, 0, true
+	}
+	var ticket Ticket
+	if generation, ok := values[1].(string); ok {
+		parsed, _ := strconv.ParseUint(generation, 10, 64)
+		ticket = Ticket(parsed)
+	}
+	return Entry{}, ticket, false
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ticket Ticket) {
+	data := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(value)), uint64(time.Now().UnixMilli()))
+	err := storeUnlessDeleted.Run(ctx, c.client, []string{c.prefix + key, c.generationKey(key)},
+		strconv.FormatUint(uint64(ticket), 10), append(data, value...), c.ttl.Milliseconds()).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("⚠️ Cache write of %s failed: %v", key, err)
+	}
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, c.prefix+key)
+			pipe.Set(ctx, c.generationKey(key), strconv.FormatUint(rand.Uint64()|1, 10), generationTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️ Cache invalidation of %v failed: %v", keys, err)
+	}
+}
+
+// Purge does nothing: writers delete shared entries themselves once they
+// commit, and generations keep reads that raced them from storing old values
+func (c *Redis) Purge(ctx context.Context) {}
+
+func (c *Redis) TTL() time.Duration {
+	return c.ttl
+}
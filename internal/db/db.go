@@ -17,8 +17,8 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// ConnectDB initializes the database using GORM
-func ConnectDB() (*gorm.DB, error) {
+// DSN is the connection string for the database named by the DB_* variables
+func DSN() (string, error) {
 	// Get individual environment variables with default values
 	host := os.Getenv("DB_HOST")
 	if host == "" {
@@ -48,13 +48,21 @@ func ConnectDB() (*gorm.DB, error) {
 	// Convert DB_PORT to integer and handle potential errors
 	port, err := strconv.Atoi(portStr)
 	if err != nil {
-		log.Fatalf("Invalid port value: %v", err)
-		return nil, err
+		return "", fmt.Errorf("invalid port value: %w", err)
 	}
 
 	// Construct the DSN using individual environment variables
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
-		host, port, user, password, dbName)
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
+		host, port, user, password, dbName), nil
+}
+
+// ConnectDB initializes the database using GORM
+func ConnectDB() (*gorm.DB, error) {
+	dsn, err := DSN()
+	if err != nil {
+		log.Fatalf("Invalid database settings: %v", err)
+		return nil, err
+	}
 
 	// Create a custom logger that creates spans for database operations
 	dbLogger := logger.New(
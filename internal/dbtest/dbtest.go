@@ -0,0 +1,135 @@
+// Package dbtest opens GORM databases answered from a script, for tests of
+// code that queries Postgres without one to run against.
+package dbtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Result answers the statements containing Match with Rows, or with Affected
+// when they are executed
+type Result struct {
+	Match    string
+	Columns  []string
+	Rows     [][]driver.Value
+	Affected int64
+}
+
+// Driver is a database/sql driver that answers statements from a script, in
+// order of the first matching entry, and records what ran. Statements nothing
+// matches return no rows; transactions are recorded as BEGIN, COMMIT and
+// ROLLBACK.
+type Driver struct {
+	mu         sync.Mutex
+	script     []Result
+	statements []string
+}
+
+// Open returns a database answered by script
+func Open(t *testing.T, script ...Result) (*gorm.DB, *Driver) {
+	t.Helper()
+	scripted := &Driver{script: script}
+	name := "dbtest-" + t.Name()
+	sql.Register(name, scripted)
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, scripted
+}
+
+// Ran tells whether a statement containing text ran
+func (d *Driver) Ran(text string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, statement := range d.statements {
+		if strings.Contains(statement, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Statements lists what ran, in order
+func (d *Driver) Statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.statements...)
+}
+
+func (d *Driver) answer(query string) Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, query)
+	for _, result := range d.script {
+		if strings.Contains(query, result.Match) {
+			return result
+		}
+	}
+	return Result{}
+}
+
+func (d *Driver) Open(string) (driver.Conn, error) { return &conn{d}, nil }
+
+type conn struct{ d *Driver }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c.d, query}, nil
+}
+func (c *conn) Close() error { return nil }
+func (c *conn) Begin() (driver.Tx, error) {
+	c.d.answer("BEGIN")
+	return &tx{c.d}, nil
+}
+
+type tx struct{ d *Driver }
+
+func (tx *tx) Commit() error   { tx.d.answer("COMMIT"); return nil }
+func (tx *tx) Rollback() error { tx.d.answer("ROLLBACK"); return nil }
+
+type stmt struct {
+	d     *Driver
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(s.d.answer(s.query).Affected), nil
+}
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	result := s.d.answer(s.query)
+	return &rows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
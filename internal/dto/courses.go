@@ -0,0 +1,97 @@
+package dto
+
+import "github.com/csye7125/team01/internal/store"
+
+// Course is a course as the API returns it
+type Course struct {
+	ID           uint   `json:"id"`
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	SemesterTerm string `json:"semester_term"`
+	SemesterYear int    `json:"semester_year"`
+	CreditHours  int    `json:"credit_hours"`
+	InstructorID uint   `json:"instructor_id"`
+	OwnerUserID  uint   `json:"owner_user_id"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+	Version      int64  `json:"version"`
+}
+
+// NewCourse maps a stored course to its response
+func NewCourse(course *store.Course) Course {
+	return Course{
+		ID:           course.ID,
+		Code:         course.Code,
+		Name:         course.Name,
+		Description:  course.Description,
+		SemesterTerm: course.SemesterTerm,
+		SemesterYear: course.SemesterYear,
+		CreditHours:  course.CreditHours,
+		InstructorID: course.InstructorID,
+		OwnerUserID:  course.OwnerUserID,
+		CreatedAt:    timestamp(course.DateAdded),
+		UpdatedAt:    timestamp(course.DateLastUpdated),
+		Version:      course.Version,
+	}
+}
+
+// NewCourses maps a list of stored courses, never returning nil
+func NewCourses(courses []store.Course) []Course {
+	list := make([]Course, len(courses))
+	for i := range courses {
+		list[i] = NewCourse(&courses[i])
+	}
+	return list
+}
+
+// CourseRequest holds the fields owners may set on a course; PUT replaces them all
+type CourseRequest struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	SemesterTerm string `json:"semester_term"`
+	SemesterYear int    `json:"semester_year"`
+	CreditHours  int    `json:"credit_hours"`
+	InstructorID uint   `json:"instructor_id"`
+}
+
+// CourseRequestFields are the members of CourseRequest, which PATCH may change
+var CourseRequestFields = []string{"code", "name", "description", "semester_term", "semester_year", "credit_hours", "instructor_id"}
+
+// NewCourseRequest is the request that would leave course as it is
+func NewCourseRequest(course *store.Course) CourseRequest {
+	return CourseRequest{
+		Code:         course.Code,
+		Name:         course.Name,
+		Description:  course.Description,
+		SemesterTerm: course.SemesterTerm,
+		SemesterYear: course.SemesterYear,
+		CreditHours:  course.CreditHours,
+		InstructorID: course.InstructorID,
+	}
+}
+
+// Apply copies the request onto course
+func (req CourseRequest) Apply(course *store.Course) {
+	course.Code = req.Code
+	course.Name = req.Name
+	course.Description = req.Description
+	course.SemesterTerm = req.SemesterTerm
+	course.SemesterYear = req.SemesterYear
+	course.CreditHours = req.CreditHours
+	course.InstructorID = req.InstructorID
+}
+
+// Columns are the course columns a PUT writes, including cleared ones
+func (req CourseRequest) Columns() map[string]interface{} {
+	return map[string]interface{}{
+		"code":          req.Code,
+		"name":          req.Name,
+		"description":   req.Description,
+		"semester_term": req.SemesterTerm,
+		"semester_year": req.SemesterYear,
+		"credit_hours":  req.CreditHours,
+		"instructor_id": req.InstructorID,
+	}
+}
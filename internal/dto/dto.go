@@ -0,0 +1,14 @@
+// Package dto holds the request and response bodies of the v2 API and their
+// mapping to the store models, so database columns never leak into the API.
+// All members are snake_case and all timestamps are RFC 3339 in UTC.
+package dto
+
+import "time"
+
+// timestamp formats a time for the API, or "" when it is not set
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,67 @@
+package dto
+
+import "github.com/csye7125/team01/internal/store"
+
+// Instructor is an instructor as the API returns it
+type Instructor struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	UserID    uint   `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Version   int64  `json:"version"`
+	// Courses is only set when requested with include=courses
+	Courses []Course `json:"courses,omitempty"`
+	// Relevance is the name similarity to the search query, only set by searches
+	Relevance *float64 `json:"relevance,omitempty"`
+}
+
+// NewInstructor maps a stored instructor to its response. Courses are
+// included when withCourses is set, as an empty list if there are none.
+func NewInstructor(instructor *store.Instructor, withCourses bool) Instructor {
+	response := Instructor{
+		ID:        instructor.InstructorID,
+		Name:      instructor.Name,
+		UserID:    instructor.UserID,
+		CreatedAt: timestamp(instructor.DateCreated),
+		UpdatedAt: timestamp(instructor.DateUpdated),
+		Version:   instructor.Version,
+		Relevance: instructor.Relevance,
+	}
+	if withCourses {
+		response.Courses = NewCourses(instructor.Courses)
+	}
+	return response
+}
+
+// NewInstructors maps a list of stored instructors, never returning nil
+func NewInstructors(instructors []store.Instructor, withCourses bool) []Instructor {
+	list := make([]Instructor, len(instructors))
+	for i := range instructors {
+		list[i] = NewInstructor(&instructors[i], withCourses)
+	}
+	return list
+}
+
+// InstructorRequest holds the fields callers may set on an instructor
+type InstructorRequest struct {
+	Name string `json:"name"`
+}
+
+// InstructorRequestFields are the members of InstructorRequest, which PATCH may change
+var InstructorRequestFields = []string{"name"}
+
+// NewInstructorRequest is the request that would leave instructor as it is
+func NewInstructorRequest(instructor *store.Instructor) InstructorRequest {
+	return InstructorRequest{Name: instructor.Name}
+}
+
+// Apply copies the request onto instructor
+func (req InstructorRequest) Apply(instructor *store.Instructor) {
+	instructor.Name = req.Name
+}
+
+// Columns are the instructor columns a PUT writes
+func (req InstructorRequest) Columns() map[string]interface{} {
+	return map[string]interface{}{"name": req.Name}
+}
@@ -0,0 +1,81 @@
+package dto
+
+import "github.com/csye7125/team01/internal/store"
+
+// User is a user as the API returns it; the password is never included
+type User struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Version   int64  `json:"version"`
+}
+
+// NewUser maps a stored user to its response
+func NewUser(user *store.User) User {
+	return User{
+		ID:        user.ID,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		CreatedAt: timestamp(user.AccountCreated),
+		UpdatedAt: timestamp(user.AccountUpdated),
+		Version:   user.Version,
+	}
+}
+
+// CreateUserRequest registers a user
+type CreateUserRequest struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// User builds the user to store
+func (req CreateUserRequest) User() *store.User {
+	return &store.User{
+		Username:  req.Username,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}
+}
+
+// UserRequest holds the fields users may change on themselves. PUT replaces
+// them all except an empty password, which keeps the current one.
+type UserRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password,omitempty"`
+}
+
+// UserRequestFields are the members of UserRequest, which PATCH may change
+var UserRequestFields = []string{"first_name", "last_name", "password"}
+
+// NewUserRequest is the request that would leave user as it is
+func NewUserRequest(user *store.User) UserRequest {
+	return UserRequest{FirstName: user.FirstName, LastName: user.LastName}
+}
+
+// Apply copies the request onto user
+func (req UserRequest) Apply(user *store.User) {
+	user.FirstName, user.LastName = req.FirstName, req.LastName
+	if req.Password != "" {
+		user.Password = req.Password
+	}
+}
+
+// Columns are the user columns a PUT writes
+func (req UserRequest) Columns() map[string]interface{} {
+	columns := map[string]interface{}{
+		"first_name": req.FirstName,
+		"last_name":  req.LastName,
+	}
+	if req.Password != "" {
+		columns["password"] = req.Password
+	}
+	return columns
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// ValidateCourse checks a course before it is saved
+func ValidateCourse(course *store.Course) []problems.FieldError {
+	var errs []problems.FieldError
+	if strings.TrimSpace(course.Code) == "" {
+		errs = append(errs, problems.FieldError{Field: "code", Message: "is required"})
+	}
+	if strings.TrimSpace(course.Name) == "" {
+		errs = append(errs, problems.FieldError{Field: "name", Message: "is required"})
+	}
+	if course.CreditHours < 0 {
+		errs = append(errs, problems.FieldError{Field: "credit_hours", Message: "must not be negative"})
+	}
+	if course.SemesterYear < 0 {
+		errs = append(errs, problems.FieldError{Field: "semester_year", Message: "must not be negative"})
+	}
+	return errs
+}
+
+// ValidateCatalogCourse checks a catalog course before it is saved
+func ValidateCatalogCourse(catalog *store.CatalogCourse) []problems.FieldError {
+	var errs []problems.FieldError
+	if strings.TrimSpace(catalog.Name) == "" {
+		errs = append(errs, problems.FieldError{Field: "name", Message: "is required"})
+	}
+	if catalog.CreditHours < 0 {
+		errs = append(errs, problems.FieldError{Field: "credit_hours", Message: "must not be negative"})
+	}
+	return errs
+}
+
+// ValidateUser checks a patched user; changes lists the columns being written
+func ValidateUser(user *store.User, changes map[string]interface{}) []problems.FieldError {
+	var errs []problems.FieldError
+	if strings.TrimSpace(user.FirstName) == "" {
+		errs = append(errs, problems.FieldError{Field: "first_name", Message: "is required"})
+	}
+	if strings.TrimSpace(user.LastName) == "" {
+		errs = append(errs, problems.FieldError{Field: "last_name", Message: "is required"})
+	}
+	if _, changed := changes["password"]; changed && user.Password == "" {
+		errs = append(errs, problems.FieldError{Field: "password", Message: "cannot be empty"})
+	}
+	return errs
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/env"
+)
+
+// Message is a domain event as handed to a broker
+type Message struct {
+	ID         string
+	Key        string // per-aggregate ordering key, e.g. "course:42"
+	Subject    string // e.g. "course.updated"
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+// Broker publishes messages to a message bus.
+// Publish must only return nil once the broker has durably accepted the message.
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// NewBrokerFromEnv builds the broker selected by EVENT_BROKER.
+// It returns a nil broker when event publishing is disabled.
+func NewBrokerFromEnv() (Broker, error) {
+	switch strings.ToLower(env.GetString("EVENT_BROKER", "")) {
+	case "":
+		return nil, nil
+	case "memory":
+		return NewMemoryBroker(), nil
+	case "kafka":
+		brokers := strings.Split(env.GetString("KAFKA_BROKERS", "localhost:9092"), ",")
+		return NewKafkaBroker(brokers, env.GetString("KAFKA_TOPIC", "api-server.events")), nil
+	case "nats":
+		return NewNATSBroker(env.GetString("NATS_URL", "nats://localhost:4222"), env.GetString("NATS_SUBJECT_PREFIX", "api-server"))
+	default:
+		return nil, fmt.Errorf("unknown EVENT_BROKER %q", env.GetString("EVENT_BROKER", ""))
+	}
+}
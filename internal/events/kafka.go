@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBroker publishes events to a single topic, keyed by aggregate so that
+// all events of one aggregate land on the same partition in order
+type KafkaBroker struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaBroker(brokers []string, topic string) *KafkaBroker {
+	return &KafkaBroker{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, msg Message) error {
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(msg.Key),
+		Value: msg.Payload,
+		Time:  msg.OccurredAt,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(msg.ID)},
+			{Key: "event-type", Value: []byte(msg.Subject)},
+		},
+	})
+}
+
+func (b *KafkaBroker) Close() error {
+	return b.writer.Close()
+}
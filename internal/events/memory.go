@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker keeps published messages in memory, for tests and local development
+type MemoryBroker struct {
+	mu       sync.Mutex
+	messages []Message
+	failKeys map[string]error
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{failKeys: map[string]error{}}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.failKeys[msg.Key]; err != nil {
+		return err
+	}
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+// FailKey makes every publish for the ordering key return err until cleared with a nil err
+func (b *MemoryBroker) FailKey(key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.failKeys, key)
+		return
+	}
+	b.failKeys[key] = err
+}
+
+// Messages returns a copy of everything published so far, in publish order
+func (b *MemoryBroker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]Message, len(b.messages))
+	copy(out, b.messages)
+	return out
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSBroker publishes events to JetStream on "<prefix>.<aggregate>.<event>".
+// The event id is sent as Nats-Msg-Id so JetStream drops redelivered duplicates.
+type NATSBroker struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+func NewNATSBroker(url, prefix string) (*NATSBroker, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream context: %w", err)
+	}
+	return &NATSBroker{conn: conn, js: js, prefix: prefix}, nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, msg Message) error {
+	m := nats.NewMsg(b.prefix + "." + msg.Subject)
+	m.Data = msg.Payload
+	m.Header.Set("Ordering-Key", msg.Key)
+	_, err := b.js.PublishMsg(m, nats.MsgId(msg.ID), nats.Context(ctx))
+	return err
+}
+
+func (b *NATSBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// Outbox holds the events a relay publishes; *store.OutboxStore is the one
+// backed by the outbox_events table
+type Outbox interface {
+	ProcessPending(ctx context.Context, limit int, lease time.Duration, maxAttempts int, fn func([]store.OutboxEvent) map[uint64]error) (int, error)
+	DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Relay moves events from the outbox table to a broker.
+// Delivery is at-least-once: an event is marked published only after the broker
+// accepts it, so a crash in between republishes it once its lease runs out.
+// Lease must comfortably exceed the time a batch takes to publish.
+type Relay struct {
+	Outbox      Outbox
+	Broker      Broker
+	BatchSize   int
+	Interval    time.Duration
+	Retention   time.Duration
+	Lease       time.Duration
+	MaxAttempts int
+}
+
+func NewRelay(outbox Outbox, broker Broker) *Relay {
+	return &Relay{
+		Outbox:      outbox,
+		Broker:      broker,
+		BatchSize:   100,
+		Interval:    time.Second,
+		Retention:   7 * 24 * time.Hour,
+		Lease:       5 * time.Minute,
+		MaxAttempts: 10,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		for {
+			published, err := r.PublishPending(ctx)
+			if err != nil {
+				log.Printf("outbox relay: %v", err)
+				break
+			}
+			// Keep draining while batches come back full
+			if published < r.BatchSize {
+				break
+			}
+		}
+
+		if r.Retention > 0 && time.Since(lastCleanup) > time.Hour {
+			if _, err := r.Outbox.DeletePublishedBefore(ctx, time.Now().Add(-r.Retention)); err != nil {
+				log.Printf("outbox relay: cleanup failed: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes one batch of pending events and returns how many were published.
+// Once an event of an aggregate fails, later events of that aggregate are held back
+// so consumers never see them out of order, until it is dead lettered after
+// MaxAttempts failures.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	return r.Outbox.ProcessPending(ctx, r.BatchSize, r.Lease, r.MaxAttempts, func(pending []store.OutboxEvent) map[uint64]error {
+		results := make(map[uint64]error, len(pending))
+		blocked := map[string]bool{}
+
+		for _, event := range pending {
+			key := event.OrderingKey()
+			if blocked[key] {
+				continue
+			}
+
+			err := r.Broker.Publish(ctx, Message{
+				ID:         strconv.FormatUint(event.ID, 10),
+				Key:        key,
+				Subject:    event.Subject(),
+				Payload:    event.Payload,
+				OccurredAt: event.DateCreated,
+			})
+			if err != nil {
+				err = fmt.Errorf("publish %s: %w", event.Subject(), err)
+				blocked[key] = true
+			}
+			results[event.ID] = err
+		}
+		return results
+	})
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// memoryOutbox follows the contract of store.OutboxStore.ProcessPending
+// without a database
+type memoryOutbox struct {
+	events []store.OutboxEvent
+}
+
+func (o *memoryOutbox) add(aggregateType, aggregateID, eventType string) {
+	o.events = append(o.events, store.OutboxEvent{
+		ID:            uint64(len(o.events) + 1),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       []byte(`{}`),
+	})
+}
+
+func (o *memoryOutbox) ProcessPending(ctx context.Context, limit int, lease time.Duration, maxAttempts int, fn func([]store.OutboxEvent) map[uint64]error) (int, error) {
+	var pending []store.OutboxEvent
+	for _, event := range o.events {
+		if event.PublishedAt == nil && event.DeadAt == nil && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	results := fn(pending)
+	published := 0
+	now := time.Now()
+	for i := range o.events {
+		event := &o.events[i]
+		publishErr, attempted := results[event.ID]
+		if !attempted {
+			continue
+		}
+		event.Attempts++
+		if publishErr == nil {
+			event.PublishedAt = &now
+			published++
+			continue
+		}
+		event.LastError = publishErr.Error()
+		if maxAttempts > 0 && event.Attempts >= maxAttempts {
+			event.DeadAt = &now
+		}
+	}
+	return published, nil
+}
+
+func (o *memoryOutbox) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func messageIDs(messages []Message) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	return ids
+}
+
+func TestPublishPendingKeepsAggregateOrder(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add("course", "1", "created")
+	outbox.add("course", "2", "created")
+	outbox.add("course", "1", "updated")
+	outbox.add("course", "2", "updated")
+
+	broker := NewMemoryBroker()
+	relay := NewRelay(outbox, broker)
+	published, err := relay.PublishPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 4 {
+		t.Fatalf("published %d events, want 4", published)
+	}
+	if got := messageIDs(broker.Messages()); !slices.Equal(got, []string{"1", "2", "3", "4"}) {
+		t.Fatalf("published %v, want events 1 to 4 in order", got)
+	}
+	if msg := broker.Messages()[2]; msg.Key != "course:1" || msg.Subject != "course.updated" {
+		t.Fatalf("event 3 published as %s %s", msg.Key, msg.Subject)
+	}
+}
+
+func TestPublishPendingHoldsBackAfterFailure(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add("course", "1", "created")
+	outbox.add("course", "2", "created")
+	outbox.add("course", "1", "updated")
+	outbox.add("course", "2", "updated")
+
+	broker := NewMemoryBroker()
+	broker.FailKey("course:1", errors.New("broker down"))
+	relay := NewRelay(outbox, broker)
+
+	// 🔹 Course 1's events wait behind its failed event; course 2 is unaffected
+	if _, err := relay.PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(broker.Messages()); !slices.Equal(got, []string{"2", "4"}) {
+		t.Fatalf("published %v, want only course 2's events", got)
+	}
+	if outbox.events[0].Attempts != 1 || outbox.events[0].LastError == "" {
+		t.Fatalf("failed event has attempts %d and error %q", outbox.events[0].Attempts, outbox.events[0].LastError)
+	}
+	if outbox.events[2].Attempts != 0 {
+		t.Fatal("event behind a failure was attempted")
+	}
+
+	// 🔹 Once the broker recovers the held events follow in order
+	broker.FailKey("course:1", nil)
+	published, err := relay.PublishPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 {
+		t.Fatalf("retry published %d events, want 2", published)
+	}
+	if got := messageIDs(broker.Messages()); !slices.Equal(got, []string{"2", "4", "1", "3"}) {
+		t.Fatalf("published %v, want course 1's events after the retry", got)
+	}
+}
+
+func TestPublishPendingDeadLettersAfterMaxAttempts(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add("course", "1", "created")
+	outbox.add("course", "1", "updated")
+
+	broker := NewMemoryBroker()
+	broker.FailKey("course:1", errors.New("rejected"))
+	relay := NewRelay(outbox, broker)
+	relay.MaxAttempts = 3
+
+	for i := 0; i < relay.MaxAttempts; i++ {
+		if _, err := relay.PublishPending(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if outbox.events[0].DeadAt == nil {
+		t.Fatalf("event failed %d times and is not dead lettered", outbox.events[0].Attempts)
+	}
+	if outbox.events[1].Attempts != 0 {
+		t.Fatal("event behind a failure was attempted")
+	}
+
+	// 🔹 The dead event no longer blocks the one behind it
+	broker.FailKey("course:1", nil)
+	if _, err := relay.PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(broker.Messages()); !slices.Equal(got, []string{"2"}) {
+		t.Fatalf("published %v, want only the event behind the dead one", got)
+	}
+}
+
+func TestPublishPendingHonorsBatchSize(t *testing.T) {
+	outbox := &memoryOutbox{}
+	for i := 0; i < 5; i++ {
+		outbox.add("trace", strconv.Itoa(i), "created")
+	}
+
+	broker := NewMemoryBroker()
+	relay := NewRelay(outbox, broker)
+	relay.BatchSize = 2
+
+	published, err := relay.PublishPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 || len(broker.Messages()) != 2 {
+		t.Fatalf("published %d events in one batch of 2", len(broker.Messages()))
+	}
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"log"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+type contextKey string
+
+const sessionKey contextKey = "graph.session"
+
+// session is the state of one query: who runs it and the loaders whose rows
+// are shared by the whole query, and only by it
+type session struct {
+	viewer *store.User
+	store  *store.Storage
+	*loaders
+}
+
+// NewContext prepares ctx for one query run on behalf of viewer, who is nil for
+// anonymous requests
+func NewContext(ctx context.Context, storage *store.Storage, viewer *store.User) context.Context {
+	return context.WithValue(ctx, sessionKey, &session{viewer: viewer, store: storage, loaders: newLoaders(storage)})
+}
+
+func sessionFrom(ctx context.Context) *session {
+	return ctx.Value(sessionKey).(*session)
+}
+
+// queryError is a resolver error with a machine-readable code in its extensions
+type queryError struct {
+	message string
+	code    string
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func (e *queryError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+var errUnauthenticated = &queryError{message: "authentication required", code: "UNAUTHENTICATED"}
+
+func forbidden(message string) error {
+	return &queryError{message: message, code: "FORBIDDEN"}
+}
+
+func badInput(message string) error {
+	return &queryError{message: message, code: "BAD_USER_INPUT"}
+}
+
+// internalError logs a store failure and hides its details from the client
+func internalError(what string, err error) error {
+	log.Printf("❌ GraphQL could not load %s: %v", what, err)
+	return &queryError{message: "could not load " + what, code: "INTERNAL_SERVER_ERROR"}
+}
+
+// requireViewer returns the caller, or an error for anonymous requests
+func requireViewer(ctx context.Context) (*store.User, error) {
+	viewer := sessionFrom(ctx).viewer
+	if viewer == nil {
+		return nil, errUnauthenticated
+	}
+	return viewer, nil
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// batchWait is how long a loader collects keys before fetching them. Resolvers of
+// sibling list items run concurrently, so they all join the same batch.
+const batchWait = 2 * time.Millisecond
+
+// loader batches the keys requested by concurrently running resolvers into a
+// single fetch and remembers the results for the rest of the request
+type loader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	mu      sync.Mutex
+	pending *batch[K, V]
+	loaded  map[K]*batch[K, V]
+}
+
+// batch is one fetch of a loader; done closes once values and err are set
+type batch[K comparable, V any] struct {
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, loaded: map[K]*batch[K, V]{}}
+}
+
+// Load returns the value for key, or the zero value if the fetch did not find it
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.loaded[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[K, V]{done: make(chan struct{})}
+			pending := l.pending
+			time.AfterFunc(batchWait, func() { l.run(ctx, pending) })
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.loaded[key] = b
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.values[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	l.pending = nil
+	l.mu.Unlock()
+
+	b.values, b.err = l.fetch(ctx, b.keys)
+	close(b.done)
+}
+
+// pageKey asks for the first page of a parent's children
+type pageKey struct {
+	ID    uint
+	Limit int
+}
+
+// loaders are the per-request loaders behind every resolver that reads the store
+type loaders struct {
+	users             *loader[uint, *store.User]
+	courses           *loader[uint, *store.Course]
+	instructors       *loader[uint, *store.Instructor]
+	traces            *loader[uint, *store.Trace]
+	instructorCourses *loader[pageKey, *store.CourseList]
+	ownedCourses      *loader[pageKey, *store.CourseList]
+	courseTraces      *loader[pageKey, *store.TraceList]
+}
+
+func newLoaders(storage *store.Storage) *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, ids []uint) (map[uint]*store.User, error) {
+			users, err := storage.Users.GetUsersByIDs(ctx, ids)
+			return byID(users, err, func(user *store.User) uint { return user.ID })
+		}),
+		courses: newLoader(func(ctx context.Context, ids []uint) (map[uint]*store.Course, error) {
+			courses, err := storage.Courses.GetCoursesByIDs(ctx, ids)
+			return byID(courses, err, func(course *store.Course) uint { return course.ID })
+		}),
+		instructors: newLoader(func(ctx context.Context, ids []uint) (map[uint]*store.Instructor, error) {
+			instructors, err := storage.Instructors.GetInstructorsByIDs(ctx, ids)
+			return byID(instructors, err, func(instructor *store.Instructor) uint { return instructor.InstructorID })
+		}),
+		traces: newLoader(func(ctx context.Context, ids []uint) (map[uint]*store.Trace, error) {
+			traces, err := storage.Traces.GetTracesByIDs(ctx, ids)
+			return byID(traces, err, func(trace *store.Trace) uint { return trace.TraceID })
+		}),
+		instructorCourses: newLoader(func(ctx context.Context, keys []pageKey) (map[pageKey]*store.CourseList, error) {
+			return pagesByLimit(ctx, keys, storage.Courses.FirstCoursePagesByInstructor)
+		}),
+		ownedCourses: newLoader(func(ctx context.Context, keys []pageKey) (map[pageKey]*store.CourseList, error) {
+			return pagesByLimit(ctx, keys, storage.Courses.FirstCoursePagesByOwner)
+		}),
+		courseTraces: newLoader(func(ctx context.Context, keys []pageKey) (map[pageKey]*store.TraceList, error) {
+			return pagesByLimit(ctx, keys, storage.Traces.FirstTracePages)
+		}),
+	}
+}
+
+// byID indexes the rows of a fetch by their ID
+func byID[V any](rows []V, err error, id func(*V) uint) (map[uint]*V, error) {
+	if err != nil {
+		return nil, err
+	}
+	indexed := make(map[uint]*V, len(rows))
+	for i := range rows {
+		indexed[id(&rows[i])] = &rows[i]
+	}
+	return indexed, nil
+}
+
+// pagesByLimit fetches first pages with one store call per distinct page size,
+// which is a single call unless a query asks for different sizes
+func pagesByLimit[V any](ctx context.Context, keys []pageKey, fetch func(context.Context, []uint, int) (map[uint]V, error)) (map[pageKey]V, error) {
+	idsByLimit := map[int][]uint{}
+	for _, key := range keys {
+		idsByLimit[key.Limit] = append(idsByLimit[key.Limit], key.ID)
+	}
+	pages := make(map[pageKey]V, len(keys))
+	for limit, ids := range idsByLimit {
+		fetched, err := fetch(ctx, ids, limit)
+		if err != nil {
+			return nil, err
+		}
+		for id, page := range fetched {
+			pages[pageKey{ID: id, Limit: limit}] = page
+		}
+	}
+	return pages, nil
+}
@@ -0,0 +1,234 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/csye7125/team01/internal/store"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const maxPageSize = 100
+
+// courseSorts maps the CourseSort enum to the store's sort keys
+var courseSorts = map[string]string{
+	"CODE":          store.CourseSortCode,
+	"NAME":          store.CourseSortName,
+	"SEMESTER_YEAR": store.CourseSortSemesterYear,
+	"CREDIT_HOURS":  store.CourseSortCreditHours,
+	"CREATED_AT":    store.CourseSortDateAdded,
+}
+
+// Resolver answers the root Query type; everything it needs comes with the context from NewContext
+type Resolver struct{}
+
+// pageArgs are the arguments of every connection field
+type pageArgs struct {
+	First int32
+	After *string
+}
+
+// limit is the page size asked for, which must be between 1 and maxPageSize
+func (args pageArgs) limit() (int, error) {
+	if args.First < 1 || args.First > maxPageSize {
+		return 0, badInput(fmt.Sprintf("first must be between 1 and %d", maxPageSize))
+	}
+	return int(args.First), nil
+}
+
+// cursor decodes after, which must come from a page of the same sort
+func (args pageArgs) cursor(sort string, desc bool) (*store.Cursor, error) {
+	if args.After == nil || *args.After == "" {
+		return nil, nil
+	}
+	cursor, err := store.DecodeCursor(*args.After)
+	if err != nil || cursor.Sort != sort || cursor.Desc != desc {
+		return nil, badInput("invalid after cursor")
+	}
+	return cursor, nil
+}
+
+// parseID reads a numeric ID argument
+func parseID(id graphql.ID) (uint, error) {
+	value, err := strconv.ParseUint(string(id), 10, 32)
+	if err != nil || value == 0 {
+		return 0, badInput(fmt.Sprintf("invalid ID %q", id))
+	}
+	return uint(value), nil
+}
+
+func formatID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
+
+func (r *Resolver) Viewer(ctx context.Context) *userResolver {
+	if viewer := sessionFrom(ctx).viewer; viewer != nil {
+		return &userResolver{viewer}
+	}
+	return nil
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	viewer, err := requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if id != viewer.ID {
+		return nil, forbidden("you can only read your own user")
+	}
+	return &userResolver{viewer}, nil
+}
+
+func (r *Resolver) Course(ctx context.Context, args struct{ ID graphql.ID }) (*courseResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadCourse(ctx, id)
+}
+
+func (r *Resolver) Courses(ctx context.Context, args struct {
+	pageArgs
+	Code         *string
+	SemesterTerm *string
+	SemesterYear *int32
+	InstructorID *graphql.ID
+	OwnerUserID  *graphql.ID
+	Q            *string
+	Sort         string
+	Desc         bool
+}) (*courseConnection, error) {
+	filter := store.CourseListFilter{Sort: courseSorts[args.Sort], Desc: args.Desc}
+	var err error
+	if filter.Limit, err = args.limit(); err != nil {
+		return nil, err
+	}
+	if filter.After, err = args.cursor(filter.Sort, filter.Desc); err != nil {
+		return nil, err
+	}
+
+	if args.Code != nil {
+		filter.Code = strings.TrimSpace(*args.Code)
+	}
+	if args.SemesterTerm != nil {
+		filter.SemesterTerm = *args.SemesterTerm
+	}
+	if args.SemesterYear != nil {
+		filter.SemesterYear = int(*args.SemesterYear)
+	}
+	if args.InstructorID != nil {
+		if filter.InstructorID, err = parseID(*args.InstructorID); err != nil {
+			return nil, err
+		}
+	}
+	if args.OwnerUserID != nil {
+		if filter.OwnerUserID, err = parseID(*args.OwnerUserID); err != nil {
+			return nil, err
+		}
+	}
+	if args.Q != nil {
+		filter.Name = strings.TrimSpace(*args.Q)
+	}
+
+	return listCourses(ctx, filter)
+}
+
+func (r *Resolver) Instructor(ctx context.Context, args struct{ ID graphql.ID }) (*instructorResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadInstructor(ctx, id)
+}
+
+func (r *Resolver) Instructors(ctx context.Context, args struct {
+	pageArgs
+	Q *string
+}) (*instructorConnection, error) {
+	filter := store.InstructorListFilter{Sort: store.InstructorSortName}
+	var err error
+	if filter.Limit, err = args.limit(); err != nil {
+		return nil, err
+	}
+	if args.Q != nil {
+		filter.Name = strings.TrimSpace(*args.Q)
+	}
+	if filter.Name != "" {
+		filter.Sort, filter.Desc = store.InstructorSortRelevance, true
+	}
+	if filter.After, err = args.cursor(filter.Sort, filter.Desc); err != nil {
+		return nil, err
+	}
+
+	list, err := sessionFrom(ctx).store.Instructors.ListInstructors(ctx, filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return nil, badInput("invalid after cursor")
+	}
+	if err != nil {
+		return nil, internalError("instructors", err)
+	}
+	connection := &instructorConnection{total: list.Total, next: list.Next, nodes: []*instructorResolver{}}
+	for i := range list.Instructors {
+		connection.nodes = append(connection.nodes, &instructorResolver{&list.Instructors[i]})
+	}
+	return connection, nil
+}
+
+func (r *Resolver) Trace(ctx context.Context, args struct{ ID graphql.ID }) (*traceResolver, error) {
+	if _, err := requireViewer(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := sessionFrom(ctx).traces.Load(ctx, id)
+	if err != nil {
+		return nil, internalError("trace", err)
+	}
+	if trace == nil {
+		return nil, nil
+	}
+	return &traceResolver{trace}, nil
+}
+
+// listCourses runs a course listing that cannot be batched, such as a page after a cursor
+func listCourses(ctx context.Context, filter store.CourseListFilter) (*courseConnection, error) {
+	list, err := sessionFrom(ctx).store.Courses.ListCourses(ctx, filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return nil, badInput("invalid after cursor")
+	}
+	if err != nil {
+		return nil, internalError("courses", err)
+	}
+	return newCourseConnection(list), nil
+}
+
+func loadCourse(ctx context.Context, id uint) (*courseResolver, error) {
+	course, err := sessionFrom(ctx).courses.Load(ctx, id)
+	if err != nil {
+		return nil, internalError("course", err)
+	}
+	if course == nil {
+		return nil, nil
+	}
+	return &courseResolver{course}, nil
+}
+
+func loadInstructor(ctx context.Context, id uint) (*instructorResolver, error) {
+	instructor, err := sessionFrom(ctx).instructors.Load(ctx, id)
+	if err != nil {
+		return nil, internalError("instructor", err)
+	}
+	if instructor == nil {
+		return nil, nil
+	}
+	return &instructorResolver{instructor}, nil
+}
@@ -0,0 +1,104 @@
+// Package graph serves the GraphQL view of users, courses, instructors and traces.
+// Resolvers read the store through per-request loaders, so sibling fields of a
+// list are fetched with one query instead of one query per item.
+package graph
+
+import (
+	_ "embed"
+	"fmt"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+const (
+	// MaxDepth is the deepest selection a query may nest
+	MaxDepth = 8
+	// MaxCost caps the number of fields a query may resolve, counting every node of every page
+	MaxCost = 5000
+	// parallelism lets every item of a full page resolve at once, so their loads share one batch
+	parallelism = maxPageSize
+)
+
+// connectionFirst is the default page size of each connection field, as declared in schema.graphql
+var connectionFirst = map[string]int{
+	"courses":     25,
+	"instructors": 25,
+	"traces":      10,
+}
+
+// NewSchema parses the schema and binds it to the resolvers
+func NewSchema() *graphql.Schema {
+	return graphql.MustParseSchema(schemaSource, &Resolver{},
+		graphql.MaxDepth(MaxDepth),
+		graphql.MaxParallelism(parallelism),
+	)
+}
+
+// QueryCost estimates how many fields the operation will resolve: each field
+// costs one plus its selections, and the selections of a connection count once
+// per node it may return. The query must already be valid.
+func QueryCost(query, operationName string, variables map[string]interface{}) (int, error) {
+	document, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, err
+	}
+	operation := document.Operations.ForName(operationName)
+	if operation == nil {
+		if operationName != "" || len(document.Operations) != 1 {
+			return 0, fmt.Errorf("operation %q not found", operationName)
+		}
+		operation = document.Operations[0]
+	}
+	return selectionCost(document, operation.SelectionSet, variables, map[string]bool{}), nil
+}
+
+func selectionCost(document *ast.QueryDocument, selections ast.SelectionSet, variables map[string]interface{}, visiting map[string]bool) int {
+	cost := 0
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost += 1 + pageSize(selection, variables)*selectionCost(document, selection.SelectionSet, variables, visiting)
+		case *ast.InlineFragment:
+			cost += selectionCost(document, selection.SelectionSet, variables, visiting)
+		case *ast.FragmentSpread:
+			// validation rejects fragment cycles; visiting only guards the walk
+			fragment := document.Fragments.ForName(selection.Name)
+			if fragment == nil || visiting[selection.Name] {
+				continue
+			}
+			visiting[selection.Name] = true
+			cost += selectionCost(document, fragment.SelectionSet, variables, visiting)
+			delete(visiting, selection.Name)
+		}
+	}
+	return cost
+}
+
+// pageSize is how many nodes a connection field may return, or 1 for other fields
+func pageSize(field *ast.Field, variables map[string]interface{}) int {
+	size, ok := connectionFirst[field.Name]
+	if !ok {
+		return 1
+	}
+	if argument := field.Arguments.ForName("first"); argument != nil {
+		value, err := argument.Value.Value(variables)
+		if err != nil {
+			return size
+		}
+		switch value := value.(type) {
+		case int64:
+			size = int(value)
+		case float64:
+			size = int(value)
+		}
+	}
+	if size < 1 {
+		return 1
+	}
+	return size
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]interface{}
+		want      int
+	}{
+		{
+			name:  "plain fields",
+			query: `{ viewer { username firstName } }`,
+			want:  3,
+		},
+		{
+			name:  "explicit page size",
+			query: `{ courses(first: 2) { nodes { code name } } }`,
+			want:  1 + 2*(1+2),
+		},
+		{
+			name:  "default page size from the schema",
+			query: `{ courses { totalCount nodes { code } } }`,
+			want:  1 + 25*(1+1+1),
+		},
+		{
+			name:      "page size from a variable",
+			query:     `query Page($n: Int) { instructors(first: $n) { nodes { name } } }`,
+			variables: map[string]interface{}{"n": float64(4)},
+			want:      1 + 4*(1+1),
+		},
+		{
+			name:  "nested pages multiply",
+			query: `{ courses(first: 3) { nodes { traces(first: 5) { nodes { fileName } } } } }`,
+			want:  1 + 3*(1+(1+5*(1+1))),
+		},
+		{
+			name:  "fragments count where they are spread",
+			query: `{ course(id: 1) { ...Details instructor { ... on Instructor { name } } } } fragment Details on Course { code name }`,
+			want:  1 + (2 + 1 + 1),
+		},
+		{
+			name:      "named operation",
+			query:     `query A { viewer { id } } query B { courses(first: 1) { nodes { id } } }`,
+			operation: "B",
+			want:      1 + 1*(1+1),
+		},
+	}
+	for _, tt := range tests {
+		got, err := QueryCost(tt.query, tt.operation, tt.variables)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: cost %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueryCostLimit(t *testing.T) {
+	// 🔹 Two full pages of 50 nested in each other resolve thousands of fields
+	cost, err := QueryCost(`{ courses(first: 50) { nodes { traces(first: 50) { nodes { fileName } } } } }`, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost <= MaxCost {
+		t.Fatalf("cost %d is within the limit of %d", cost, MaxCost)
+	}
+}
+
+func TestQueryCostOperationNotFound(t *testing.T) {
+	for _, operation := range []string{"", "C"} {
+		if _, err := QueryCost(`query A { viewer { id } } query B { viewer { id } }`, operation, nil); err == nil {
+			t.Errorf("operation %q was found", operation)
+		}
+	}
+}
+
+// nestedQuery selects fields depth levels deep, going back and forth between
+// courses and their instructors
+func nestedQuery(depth int) string {
+	fields := []string{"course(id: 1)"}
+	for len(fields) < depth-1 {
+		fields = append(fields, []string{"instructor", "courses", "nodes"}[(len(fields)-1)%3])
+	}
+	return "{ " + strings.Join(fields, " { ") + " { id" + strings.Repeat(" }", len(fields)) + " }"
+}
+
+func TestSchemaMaxDepth(t *testing.T) {
+	schema := NewSchema()
+	if errs := schema.Validate(nestedQuery(MaxDepth)); len(errs) > 0 {
+		t.Fatalf("query of depth %d rejected: %v", MaxDepth, errs)
+	}
+	errs := schema.Validate(nestedQuery(MaxDepth + 1))
+	if len(errs) == 0 {
+		t.Fatalf("query of depth %d accepted", MaxDepth+1)
+	}
+	if !strings.Contains(errs[0].Message, "depth") {
+		t.Fatalf("query of depth %d rejected with %q", MaxDepth+1, errs[0].Message)
+	}
+}
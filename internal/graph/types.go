@@ -0,0 +1,232 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/csye7125/team01/internal/store"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type userResolver struct {
+	user *store.User
+}
+
+func (u *userResolver) ID() graphql.ID          { return formatID(u.user.ID) }
+func (u *userResolver) Username() string        { return u.user.Username }
+func (u *userResolver) FirstName() string       { return u.user.FirstName }
+func (u *userResolver) LastName() string        { return u.user.LastName }
+func (u *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: u.user.AccountCreated} }
+func (u *userResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: u.user.AccountUpdated} }
+func (u *userResolver) Version() int32          { return int32(u.user.Version) }
+
+func (u *userResolver) Courses(ctx context.Context, args pageArgs) (*courseConnection, error) {
+	return childCourses(ctx, args, u.user.ID, store.CourseListFilter{OwnerUserID: u.user.ID}, sessionFrom(ctx).ownedCourses)
+}
+
+type courseResolver struct {
+	course *store.Course
+}
+
+func (c *courseResolver) ID() graphql.ID          { return formatID(c.course.ID) }
+func (c *courseResolver) Code() string            { return c.course.Code }
+func (c *courseResolver) Name() string            { return c.course.Name }
+func (c *courseResolver) Description() string     { return c.course.Description }
+func (c *courseResolver) SemesterTerm() string    { return c.course.SemesterTerm }
+func (c *courseResolver) SemesterYear() int32     { return int32(c.course.SemesterYear) }
+func (c *courseResolver) CreditHours() int32      { return int32(c.course.CreditHours) }
+func (c *courseResolver) CreatedAt() graphql.Time { return graphql.Time{Time: c.course.DateAdded} }
+func (c *courseResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: c.course.DateLastUpdated}
+}
+func (c *courseResolver) Version() int32          { return int32(c.course.Version) }
+func (c *courseResolver) OwnerUserID() graphql.ID { return formatID(c.course.OwnerUserID) }
+
+func (c *courseResolver) Instructor(ctx context.Context) (*instructorResolver, error) {
+	if c.course.InstructorID == 0 {
+		return nil, nil
+	}
+	return loadInstructor(ctx, c.course.InstructorID)
+}
+
+// Owner follows GET /v1/user/{userId}: users may only read themselves
+func (c *courseResolver) Owner(ctx context.Context) (*userResolver, error) {
+	viewer, err := requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.course.OwnerUserID != viewer.ID {
+		return nil, forbidden("you can only read the owner of your own courses")
+	}
+	return &userResolver{viewer}, nil
+}
+
+// Traces follows GET /v1/course/{course_id}/trace, which any authenticated user may call
+func (c *courseResolver) Traces(ctx context.Context, args pageArgs) (*traceConnection, error) {
+	if _, err := requireViewer(ctx); err != nil {
+		return nil, err
+	}
+	limit, err := args.limit()
+	if err != nil {
+		return nil, err
+	}
+	after, err := args.cursor(store.TraceSortDateCreated, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var list *store.TraceList
+	if after == nil {
+		list, err = sessionFrom(ctx).courseTraces.Load(ctx, pageKey{ID: c.course.ID, Limit: limit})
+	} else {
+		list, err = sessionFrom(ctx).store.Traces.ListTraces(ctx, store.TraceListFilter{
+			CourseID: c.course.ID,
+			Sort:     store.TraceSortDateCreated,
+			Desc:     true,
+			After:    after,
+			Limit:    limit,
+		})
+	}
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return nil, badInput("invalid after cursor")
+	}
+	if err != nil {
+		return nil, internalError("traces", err)
+	}
+	return newTraceConnection(list), nil
+}
+
+type instructorResolver struct {
+	instructor *store.Instructor
+}
+
+func (i *instructorResolver) ID() graphql.ID     { return formatID(i.instructor.InstructorID) }
+func (i *instructorResolver) Name() string       { return i.instructor.Name }
+func (i *instructorResolver) UserID() graphql.ID { return formatID(i.instructor.UserID) }
+func (i *instructorResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: i.instructor.DateCreated}
+}
+func (i *instructorResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: i.instructor.DateUpdated}
+}
+func (i *instructorResolver) Version() int32 { return int32(i.instructor.Version) }
+
+func (i *instructorResolver) Courses(ctx context.Context, args pageArgs) (*courseConnection, error) {
+	return childCourses(ctx, args, i.instructor.InstructorID, store.CourseListFilter{InstructorID: i.instructor.InstructorID}, sessionFrom(ctx).instructorCourses)
+}
+
+type traceResolver struct {
+	trace *store.Trace
+}
+
+func (t *traceResolver) ID() graphql.ID          { return formatID(t.trace.TraceID) }
+func (t *traceResolver) FileName() string        { return t.trace.FileName }
+func (t *traceResolver) UserID() graphql.ID      { return formatID(t.trace.UserID) }
+func (t *traceResolver) CreatedAt() graphql.Time { return graphql.Time{Time: t.trace.DateCreated} }
+func (t *traceResolver) ParseStatus() string     { return t.trace.ParseStatus }
+func (t *traceResolver) ParserName() string      { return t.trace.ParserName }
+func (t *traceResolver) ParserVersion() int32    { return int32(t.trace.ParserVersion) }
+
+func (t *traceResolver) ParsedAt() *graphql.Time {
+	if t.trace.DateParsed == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t.trace.DateParsed}
+}
+
+func (t *traceResolver) Course(ctx context.Context) (*courseResolver, error) {
+	return loadCourse(ctx, t.trace.CourseID)
+}
+
+// childCourses pages through the courses of the parent id, which filter selects, in
+// code order. First pages of sibling parents are batched by pages; later pages are
+// listed one by one.
+func childCourses(ctx context.Context, args pageArgs, id uint, filter store.CourseListFilter, pages *loader[pageKey, *store.CourseList]) (*courseConnection, error) {
+	var err error
+	if filter.Limit, err = args.limit(); err != nil {
+		return nil, err
+	}
+	filter.Sort = store.CourseSortCode
+	if filter.After, err = args.cursor(filter.Sort, false); err != nil {
+		return nil, err
+	}
+	if filter.After != nil {
+		return listCourses(ctx, filter)
+	}
+
+	list, err := pages.Load(ctx, pageKey{ID: id, Limit: filter.Limit})
+	if err != nil {
+		return nil, internalError("courses", err)
+	}
+	return newCourseConnection(list), nil
+}
+
+// pageInfo tells whether a connection has more nodes and where they start
+type pageInfo struct {
+	next *store.Cursor
+}
+
+func (p pageInfo) HasNextPage() bool { return p.next != nil }
+
+func (p pageInfo) EndCursor() *string {
+	if p.next == nil {
+		return nil
+	}
+	cursor := p.next.Encode()
+	return &cursor
+}
+
+type courseConnection struct {
+	total int64
+	next  *store.Cursor
+	nodes []*courseResolver
+}
+
+// newCourseConnection wraps a page of courses; a parent without courses has no page
+func newCourseConnection(list *store.CourseList) *courseConnection {
+	connection := &courseConnection{nodes: []*courseResolver{}}
+	if list == nil {
+		return connection
+	}
+	connection.total, connection.next = list.Total, list.Next
+	for i := range list.Courses {
+		connection.nodes = append(connection.nodes, &courseResolver{&list.Courses[i]})
+	}
+	return connection
+}
+
+func (c *courseConnection) TotalCount() int32        { return int32(c.total) }
+func (c *courseConnection) Nodes() []*courseResolver { return c.nodes }
+func (c *courseConnection) PageInfo() pageInfo       { return pageInfo{c.next} }
+
+type instructorConnection struct {
+	total int64
+	next  *store.Cursor
+	nodes []*instructorResolver
+}
+
+func (c *instructorConnection) TotalCount() int32            { return int32(c.total) }
+func (c *instructorConnection) Nodes() []*instructorResolver { return c.nodes }
+func (c *instructorConnection) PageInfo() pageInfo           { return pageInfo{c.next} }
+
+type traceConnection struct {
+	total int64
+	next  *store.Cursor
+	nodes []*traceResolver
+}
+
+func newTraceConnection(list *store.TraceList) *traceConnection {
+	connection := &traceConnection{nodes: []*traceResolver{}}
+	if list == nil {
+		return connection
+	}
+	connection.total, connection.next = list.Total, list.Next
+	for i := range list.Traces {
+		connection.nodes = append(connection.nodes, &traceResolver{&list.Traces[i]})
+	}
+	return connection
+}
+
+func (c *traceConnection) TotalCount() int32       { return int32(c.total) }
+func (c *traceConnection) Nodes() []*traceResolver { return c.nodes }
+func (c *traceConnection) PageInfo() pageInfo      { return pageInfo{c.next} }
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/retrieval"
+	"github.com/csye7125/team01/internal/store"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultAskPassages = 5
+	maxAskPassages     = 20
+)
+
+type AskHandler struct {
+	Store     *store.Storage
+	Retriever retrieval.Retriever
+}
+
+func NewAskHandler(store *store.Storage, retriever retrieval.Retriever) *AskHandler {
+	return &AskHandler{Store: store, Retriever: retriever}
+}
+
+type askPassage struct {
+	retrieval.Passage
+	FileName string `json:"file_name"`
+}
+
+func (h *AskHandler) AskCourseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	courseID, err := strconv.ParseUint(chi.URLParam(r, "course_id"), 10, 32)
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
+		return
+	}
+
+	// 🔹 Parse the question
+	var request struct {
+		Question string `json:"question"`
+		TopK     int    `json:"top_k"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	request.Question = strings.TrimSpace(request.Question)
+	if request.Question == "" {
+		problems.Write(w, r, problems.BadRequest("Question is required"))
+		return
+	}
+	if request.TopK == 0 {
+		request.TopK = defaultAskPassages
+	}
+	if request.TopK < 1 || request.TopK > maxAskPassages {
+		problems.Write(w, r, problems.BadRequest(fmt.Sprintf("top_k must be between 1 and %d", maxAskPassages)))
+		return
+	}
+
+	if _, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID)); err != nil {
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
+		return
+	}
+
+	// 🔹 Retrieve the best passages
+	passages, err := h.Retriever.Search(r.Context(), uint(courseID), request.Question, request.TopK)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not search course traces").Wrap(err))
+		return
+	}
+
+	// 🔹 Label passages with their trace file names
+	traces, err := h.Store.Traces.GetTracesByCourseID(r.Context(), strconv.FormatUint(courseID, 10))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch traces").Wrap(err))
+		return
+	}
+	fileNames := map[uint]string{}
+	for _, trace := range traces {
+		fileNames[trace.TraceID] = trace.FileName
+	}
+	results := make([]askPassage, 0, len(passages))
+	for _, passage := range passages {
+		results = append(results, askPassage{Passage: passage, FileName: fileNames[passage.TraceID]})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"course_id": courseID,
+		"question":  request.Question,
+		"passages":  results,
+	})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBatchRequests caps how many sub-requests one batch may carry
+const maxBatchRequests = 50
+
+// batchPath is where batches are served; a batch cannot contain another
+const batchPath = "/v1/batch"
+
+// outsideDatabase are the routes whose effects reach beyond the database, such
+// as trace files in the bucket, so an atomic batch could not roll them back
+var outsideDatabase = []string{
+	"POST /v1/course/*/trace",
+	"POST /v1/catalog/*/offerings/*/traces",
+	"POST /v1/admin/traces/reparse",
+}
+
+// errBatchFailed rolls an atomic batch back after a sub-request failed
+var errBatchFailed = errors.New("batch sub-request failed")
+
+type batchRequest struct {
+	Mode     string            `json:"mode"`
+	Requests []batchSubRequest `json:"requests"`
+}
+
+type batchSubRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// batchResult is the response to one sub-request
+type batchResult struct {
+	Index   int               `json:"index"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type batchResponse struct {
+	Mode    string        `json:"mode"`
+	Results []batchResult `json:"results"`
+}
+
+// BatchHandler runs sub-requests through the API's own router, so each one
+// passes the same authentication, validation and limits as if sent alone
+type BatchHandler struct {
+	Store  *store.Storage
+	Router http.Handler
+}
+
+func NewBatchHandler(store *store.Storage, router http.Handler) *BatchHandler {
+	return &BatchHandler{Store: store, Router: router}
+}
+
+func (h *BatchHandler) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Step 1: Decode and check the batch before running any of it
+	var batch batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	if batch.Mode == "" {
+		batch.Mode = batchModeBestEffort
+	}
+	if batch.Mode != batchModeAtomic && batch.Mode != batchModeBestEffort {
+		problems.Write(w, r, problems.Invalid("Invalid batch", problems.FieldError{Field: "mode", Message: "must be atomic or best_effort"}))
+		return
+	}
+	if len(batch.Requests) == 0 || len(batch.Requests) > maxBatchRequests {
+		problems.Write(w, r, problems.Invalid("Invalid batch", problems.FieldError{Field: "requests", Message: fmt.Sprintf("must hold between 1 and %d requests", maxBatchRequests)}))
+		return
+	}
+	var fieldErrors []problems.FieldError
+	for i, sub := range batch.Requests {
+		fieldErrors = append(fieldErrors, sub.validate(i)...)
+		if batch.Mode == batchModeAtomic && sub.outsideDatabase() {
+			fieldErrors = append(fieldErrors, problems.FieldError{Field: fmt.Sprintf("requests[%d]", i), Message: "has effects outside the database and cannot run in an atomic batch"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid batch", fieldErrors...))
+		return
+	}
+
+	// 🔹 Step 2: Run the sub-requests in order
+	resp := batchResponse{Mode: batch.Mode, Results: make([]batchResult, len(batch.Requests))}
+	if batch.Mode == batchModeBestEffort {
+		for i, sub := range batch.Requests {
+			resp.Results[i] = h.serve(r.Context(), r, i, sub)
+		}
+	} else {
+		// 🔹 Atomic batches share one transaction and stop at the first failure.
+		// Like atomic course batches, every other request then gets 424.
+		err := h.Store.WithTransaction(r.Context(), func(ctx context.Context) error {
+			for i, sub := range batch.Requests {
+				resp.Results[i] = h.serve(ctx, r, i, sub)
+				if resp.Results[i].Status >= http.StatusBadRequest {
+					for j := range batch.Requests {
+						if j != i {
+							resp.Results[j] = notAppliedResult(r, j, i)
+						}
+					}
+					return errBatchFailed
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errBatchFailed) {
+			problems.Write(w, r, problems.Internal("Could not commit batch").Wrap(err))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validate checks that a sub-request names a method and a path on the REST API
+func (sub batchSubRequest) validate(i int) []problems.FieldError {
+	var fieldErrors []problems.FieldError
+	switch sub.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: fmt.Sprintf("requests[%d].method", i), Message: "must be GET, POST, PUT, PATCH or DELETE"})
+	}
+	target, err := url.Parse(sub.Path)
+	switch {
+	case err != nil || target.Scheme != "" || target.Host != "":
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: fmt.Sprintf("requests[%d].path", i), Message: "must be a path such as /v1/courses"})
+	case !strings.HasPrefix(target.Path, "/v1/") && !strings.HasPrefix(target.Path, "/v2/"):
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: fmt.Sprintf("requests[%d].path", i), Message: "must be under /v1/ or /v2/"})
+	case target.Path == batchPath:
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: fmt.Sprintf("requests[%d].path", i), Message: "batches cannot be nested"})
+	}
+	return fieldErrors
+}
+
+// outsideDatabase tells whether a sub-request has effects a rollback cannot undo
+func (sub batchSubRequest) outsideDatabase() bool {
+	target, err := url.Parse(sub.Path)
+	if err != nil {
+		return false
+	}
+	for _, route := range outsideDatabase {
+		if matched, _ := path.Match(route, sub.Method+" "+strings.TrimSuffix(target.Path, "/")); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// serve runs one sub-request through the router. It inherits the batch's
+// credentials unless it sends its own Authorization header.
+func (h *BatchHandler) serve(ctx context.Context, r *http.Request, i int, sub batchSubRequest) batchResult {
+	// 🔹 Drop the batch's route so the router matches the sub-request afresh
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, nil)
+	req, err := http.NewRequestWithContext(ctx, sub.Method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return batchResult{Index: i, Status: http.StatusBadRequest}
+	}
+	req.Host, req.RemoteAddr, req.RequestURI = r.Host, r.RemoteAddr, sub.Path
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if len(sub.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range sub.Headers {
+		req.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	h.Router.ServeHTTP(recorder, req)
+	return recordedResult(i, recorder)
+}
+
+// notAppliedResult answers a sub-request of an atomic batch that was rolled
+// back or never run because another one failed
+func notAppliedResult(r *http.Request, i, failed int) batchResult {
+	recorder := httptest.NewRecorder()
+	problems.Write(recorder, r, problems.New(http.StatusFailedDependency, fmt.Sprintf("Not applied because request %d failed", failed)))
+	return recordedResult(i, recorder)
+}
+
+// recordedResult turns a recorded response into a result. JSON bodies are
+// embedded as they are and any other body as a JSON string.
+func recordedResult(i int, recorder *httptest.ResponseRecorder) batchResult {
+	result := batchResult{Index: i, Status: recorder.Code, Headers: map[string]string{}}
+	for name, values := range recorder.Header() {
+		result.Headers[name] = strings.Join(values, ", ")
+	}
+
+	body := recorder.Body.Bytes()
+	if len(body) == 0 {
+		return result
+	}
+	mediaType, _, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if (mediaType == "application/json" || mediaType == "application/problem+json") && json.Valid(body) {
+		result.Body = json.RawMessage(bytes.TrimSpace(body))
+	} else {
+		result.Body, _ = json.Marshal(string(body))
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/csye7125/team01/internal/store"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordingDriver is a database/sql driver that runs nothing and records
+// transactions and the first word of each statement
+type recordingDriver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (d *recordingDriver) record(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+func (d *recordingDriver) recorded() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return strings.Join(d.events, ", ")
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{c.d, query}, nil
+}
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.record("begin")
+	return &recordingTx{c.d}, nil
+}
+
+type recordingTx struct{ d *recordingDriver }
+
+func (tx *recordingTx) Commit() error   { tx.d.record("commit"); return nil }
+func (tx *recordingTx) Rollback() error { tx.d.record("rollback"); return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(strings.ToLower(strings.Fields(s.query)[0]))
+	return driver.RowsAffected(0), nil
+}
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("recordingDriver does not answer queries")
+}
+
+// testStorage is a store whose transactions are recorded by the returned driver
+func testStorage(t *testing.T) (*store.Storage, *recordingDriver) {
+	t.Helper()
+	recorder := &recordingDriver{}
+	name := "recording-" + t.Name()
+	sql.Register(name, recorder)
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.NewStorage(db), recorder
+}
+
+// testRouter answers sub-requests by path. Each one it serves registers an
+// effect for after commit and saves in a savepoint of the batch transaction.
+type testRouter struct {
+	storage *store.Storage
+	served  []string
+	effects []string
+}
+
+func (router *testRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router.served = append(router.served, r.URL.Path)
+	path := r.URL.Path
+	store.AfterCommit(r.Context(), func() { router.effects = append(router.effects, path) })
+	router.storage.WithTransaction(r.Context(), func(ctx context.Context) error { return nil })
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(path, "/fail"):
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"detail": "invalid"}`)
+	default:
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"path": "`+path+`"}`)
+	}
+}
+
+func runBatch(t *testing.T, handler *BatchHandler, body string) (int, batchResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.BatchHandler(w, httptest.NewRequest(http.MethodPost, batchPath, bytes.NewBufferString(body)))
+	var resp batchResponse
+	if w.Code == http.StatusMultiStatus {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, resp
+}
+
+func statuses(resp batchResponse) []int {
+	codes := make([]int, len(resp.Results))
+	for i, result := range resp.Results {
+		codes[i] = result.Status
+	}
+	return codes
+}
+
+func TestAtomicBatchRollsBack(t *testing.T) {
+	storage, driver := testStorage(t)
+	router := &testRouter{storage: storage}
+	handler := NewBatchHandler(storage, router)
+
+	code, resp := runBatch(t, handler, `{"mode": "atomic", "requests": [
+		{"method": "POST", "path": "/v1/course/one"},
+		{"method": "POST", "path": "/v1/course/fail"},
+		{"method": "POST", "path": "/v1/course/three"}
+	]}`)
+	if code != http.StatusMultiStatus {
+		t.Fatalf("batch got %d", code)
+	}
+	if got := statuses(resp); !slices.Equal(got, []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusFailedDependency}) {
+		t.Fatalf("statuses %v, want 424, 400, 424", got)
+	}
+	if len(router.served) != 2 {
+		t.Fatalf("served %v, want to stop at the failure", router.served)
+	}
+	if got := driver.recorded(); got != "begin, savepoint, savepoint, rollback" {
+		t.Fatalf("transaction was %q, want one that rolls back", got)
+	}
+	if len(router.effects) != 0 {
+		t.Fatalf("effects %v ran for a rolled back batch", router.effects)
+	}
+}
+
+func TestAtomicBatchCommits(t *testing.T) {
+	storage, driver := testStorage(t)
+	router := &testRouter{storage: storage}
+	handler := NewBatchHandler(storage, router)
+
+	_, resp := runBatch(t, handler, `{"mode": "atomic", "requests": [
+		{"method": "POST", "path": "/v1/course/one"},
+		{"method": "POST", "path": "/v1/course/two"}
+	]}`)
+	if got := statuses(resp); !slices.Equal(got, []int{http.StatusCreated, http.StatusCreated}) {
+		t.Fatalf("statuses %v, want 201, 201", got)
+	}
+	if got := driver.recorded(); got != "begin, savepoint, savepoint, commit" {
+		t.Fatalf("transaction was %q, want one that commits", got)
+	}
+	if strings.Join(router.effects, " ") != "/v1/course/one /v1/course/two" {
+		t.Fatalf("effects %v, want both in order after the commit", router.effects)
+	}
+}
+
+func TestBestEffortBatchRunsEveryRequest(t *testing.T) {
+	storage, _ := testStorage(t)
+	router := &testRouter{storage: storage}
+	handler := NewBatchHandler(storage, router)
+
+	_, resp := runBatch(t, handler, `{"requests": [
+		{"method": "POST", "path": "/v1/course/fail"},
+		{"method": "POST", "path": "/v1/course/two"}
+	]}`)
+	if got := statuses(resp); !slices.Equal(got, []int{http.StatusBadRequest, http.StatusCreated}) {
+		t.Fatalf("statuses %v, want 400, 201", got)
+	}
+	if len(router.effects) != 2 {
+		t.Fatalf("effects %v, want one per request", router.effects)
+	}
+}
+
+func TestAtomicBatchRejectsEffectsOutsideDatabase(t *testing.T) {
+	storage, driver := testStorage(t)
+	router := &testRouter{storage: storage}
+	handler := NewBatchHandler(storage, router)
+
+	for _, sub := range []string{
+		`{"method": "POST", "path": "/v1/course/12/trace"}`,
+		`{"method": "POST", "path": "/v1/catalog/CSYE7125/offerings/3/traces/"}`,
+		`{"method": "POST", "path": "/v1/admin/traces/reparse?dry=1"}`,
+	} {
+		code, _ := runBatch(t, handler, `{"mode": "atomic", "requests": [{"method": "GET", "path": "/v1/courses"}, `+sub+`]}`)
+		if code != http.StatusBadRequest {
+			t.Errorf("%s got %d, want 400", sub, code)
+		}
+	}
+	if len(router.served) != 0 || driver.recorded() != "" {
+		t.Fatalf("rejected batches ran: served %v, database %q", router.served, driver.recorded())
+	}
+
+	// 🔹 The same requests may run on their own
+	if code, _ := runBatch(t, handler, `{"requests": [{"method": "POST", "path": "/v1/course/12/trace"}]}`); code != http.StatusMultiStatus {
+		t.Fatalf("best effort upload got %d, want 207", code)
+	}
+	// 🔹 Deleting a trace defers its bucket delete, so it may run atomically
+	if code, _ := runBatch(t, handler, `{"mode": "atomic", "requests": [{"method": "DELETE", "path": "/v1/course/12/trace/4"}]}`); code != http.StatusMultiStatus {
+		t.Fatalf("atomic trace delete got %d, want 207", code)
+	}
+}
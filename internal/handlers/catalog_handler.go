@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// offeringSorts are the orders a catalog course's offerings list in; the
+// first is the default
+var offeringSorts = sortOptions{
+	{store.CourseSortSemesterYear, store.CourseSortSemesterYear},
+	{store.CourseSortDateAdded, store.CourseSortDateAdded},
+}
+
+// offeringRequest is the body of a new offering; the catalog course in the
+// URL supplies the rest
+type offeringRequest struct {
+	SemesterTerm string `json:"semester_term"`
+	SemesterYear int    `json:"semester_year"`
+	InstructorID uint   `json:"instructor_id"`
+	Manufacturer string `json:"manufacturer"`
+}
+
+// CatalogHandler serves catalog courses and their offerings. Offerings are
+// course rows, so their traces are served by the trace handlers.
+type CatalogHandler struct {
+	Store  *store.Storage
+	Traces *TraceHandler
+}
+
+func NewCatalogHandler(store *store.Storage, traces *TraceHandler) *CatalogHandler {
+	return &CatalogHandler{Store: store, Traces: traces}
+}
+
+func (h *CatalogHandler) GetCatalogCourseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	catalog, err := h.catalogCourse(r)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	writeValidators(w, catalog.Version, catalog.DateLastUpdated)
+	if notModified(w, r, catalog.Version, catalog.DateLastUpdated) {
+		return
+	}
+	json.NewEncoder(w).Encode(catalog)
+}
+
+func (h *CatalogHandler) PatchCatalogCourseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Authenticate the caller and find the catalog course
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	catalog, err := h.catalogCourse(r)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	// 🔹 Ensure the caller owns the catalog course or one of its offerings
+	allowed, err := h.Store.Catalog.CanEdit(r.Context(), catalog, user.ID)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not check catalog course owners").Wrap(err))
+		return
+	}
+	if !allowed {
+		problems.Write(w, r, problems.Forbidden("Unauthorized. Only owners of the catalog course or its offerings can patch it"))
+		return
+	}
+
+	// 🔹 Make sure the caller saw the current version
+	if !checkIfMatch(w, r, catalog.Version) {
+		return
+	}
+
+	// 🔹 Merge the patch into the catalog course and validate the result
+	members, err := readPatch(r, catalog)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	version := catalog.Version
+	updateData, fieldErrors := applyPatch(catalog, members, store.CatalogMutableColumns)
+	if len(fieldErrors) == 0 {
+		fieldErrors = dto.ValidateCatalogCourse(catalog)
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid catalog course patch", fieldErrors...))
+		return
+	}
+
+	// 🔹 Perform update; every offering takes on the new details
+	err = h.Store.Catalog.PatchCatalogCourse(r.Context(), catalog.ID, version, updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not patch catalog course").Wrap(err))
+		return
+	}
+
+	catalog.Version = updateData["version"].(int64)
+	catalog.DateLastUpdated = updateData["date_last_updated"].(time.Time)
+	writeValidators(w, catalog.Version, catalog.DateLastUpdated)
+	json.NewEncoder(w).Encode(catalog)
+}
+
+func (h *CatalogHandler) ListOfferingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	catalog, err := h.catalogCourse(r)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	filter, err := courseListFilter(r.URL.Query(), offeringSorts)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	filter.CatalogCourseID = catalog.ID
+
+	list, err := h.Store.Courses.ListCourses(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not list offerings").Wrap(err))
+		return
+	}
+
+	writePageHeaders(w, r, list.Total, list.Next)
+	json.NewEncoder(w).Encode(store.Offerings(list.Courses))
+}
+
+func (h *CatalogHandler) CreateOfferingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Authenticate the future owner and find the catalog course
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	catalog, err := h.catalogCourse(r)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	// 🔹 Build the offering's course row from the catalog and the request
+	var req offeringRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	course := &store.Course{
+		Code:         catalog.Code,
+		Name:         catalog.Name,
+		Description:  catalog.Description,
+		CreditHours:  catalog.CreditHours,
+		SemesterTerm: req.SemesterTerm,
+		SemesterYear: req.SemesterYear,
+		InstructorID: req.InstructorID,
+		Manufacturer: req.Manufacturer,
+		OwnerUserID:  user.ID,
+	}
+	if fieldErrors := dto.ValidateCourse(course); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid offering", fieldErrors...))
+		return
+	}
+	if err := h.Store.Instructors.CheckInstructorExists(r.Context(), course.InstructorID); err != nil {
+		problems.Write(w, r, problems.Invalid("Invalid offering", problems.FieldError{Field: "instructor_id", Message: "does not exist"}))
+		return
+	}
+
+	err = h.Store.Courses.CreateCourse(r.Context(), course)
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not create offering").Wrap(err))
+		return
+	}
+	writeCreated(w, fmt.Sprintf("/v1/catalog/%s/offerings/%d", url.PathEscape(catalog.Code), course.ID), course.Version, course.Offering())
+}
+
+func (h *CatalogHandler) GetOfferingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	offering, err := h.offering(r)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	writeValidators(w, offering.Version, offering.DateLastUpdated)
+	if notModified(w, r, offering.Version, offering.DateLastUpdated) {
+		return
+	}
+	json.NewEncoder(w).Encode(offering)
+}
+
+// ListOfferingTracesHandler lists an offering's traces as GET /v1/course/{course_id}/trace does
+func (h *CatalogHandler) ListOfferingTracesHandler(w http.ResponseWriter, r *http.Request) {
+	h.offeringTraces(h.Traces.GetAllTracesHandler)(w, r)
+}
+
+// UploadOfferingTraceHandler attaches a trace to an offering as POST /v1/course/{course_id}/trace does
+func (h *CatalogHandler) UploadOfferingTraceHandler(w http.ResponseWriter, r *http.Request) {
+	h.offeringTraces(h.Traces.UploadTraceHandler)(w, r)
+}
+
+// offeringTraces runs a trace handler for the offering in the URL, which the
+// trace handlers know by its course ID
+func (h *CatalogHandler) offeringTraces(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offering, err := h.offering(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			problems.Write(w, r, err)
+			return
+		}
+		chi.RouteContext(r.Context()).URLParams.Add("course_id", strconv.FormatUint(uint64(offering.ID), 10))
+		next(w, r)
+	}
+}
+
+// catalogCourse finds the catalog course named by the {code} URL parameter
+func (h *CatalogHandler) catalogCourse(r *http.Request) (*store.CatalogCourse, error) {
+	code, err := url.PathUnescape(chi.URLParam(r, "code"))
+	if err != nil {
+		return nil, problems.BadRequest("Invalid course code")
+	}
+	catalog, err := h.Store.Catalog.GetCatalogCourseByCode(r.Context(), code)
+	if err != nil {
+		return nil, problems.NotFound("Catalog course not found").Wrap(err)
+	}
+	return catalog, nil
+}
+
+// offering finds the offering named by the {offeringId} URL parameter, which
+// must belong to the catalog course in the URL
+func (h *CatalogHandler) offering(r *http.Request) (*store.Offering, error) {
+	catalog, err := h.catalogCourse(r)
+	if err != nil {
+		return nil, err
+	}
+	offeringID, err := idParam(r, "offeringId", "offering")
+	if err != nil {
+		return nil, err
+	}
+	course, err := h.Store.Courses.GetCourseByID(r.Context(), offeringID)
+	if err != nil {
+		return nil, problems.NotFound("Offering not found").Wrap(err)
+	}
+	if course.CatalogCourseID != catalog.ID {
+		return nil, problems.NotFound("Offering not found")
+	}
+	offering := course.Offering()
+	return &offering, nil
+}
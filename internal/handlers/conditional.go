@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/problems"
+)
+
+// modifiedDetail is reported when a write carries a stale version
+const modifiedDetail = "The resource has changed since it was read; fetch it again and retry"
+
+// catalogConflictDetail is reported when a course write gives a course details
+// other than its catalog course's
+const catalogConflictDetail = "Code, name, description and credit hours must match the catalog course unless you own it or one of its offerings; change them with PATCH /v1/catalog/{code}"
+
+// entityTag is the strong ETag for a row version
+func entityTag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// writeValidators sets the headers clients use for conditional requests
+func writeValidators(w http.ResponseWriter, version int64, modified time.Time) {
+	w.Header().Set("ETag", entityTag(version))
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// writeCacheHeaders lets shared caches keep a public read for as long as the
+// API's own cache may serve it; age is how long the API has held it already.
+// Without a cache, ttl is 0 and nothing is set.
+func writeCacheHeaders(w http.ResponseWriter, ttl, age time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(ttl.Seconds())))
+	w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+}
+
+// notModified answers a conditional GET with 304 when the client's copy is
+// current. If-None-Match takes precedence over If-Modified-Since.
+func notModified(w http.ResponseWriter, r *http.Request, version int64, modified time.Time) bool {
+	current := false
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		current = matchesTag(header, entityTag(version), true)
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.IsZero() {
+		current = !modified.Truncate(time.Second).After(since)
+	}
+	if current {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return current
+}
+
+// checkIfMatch enforces If-Match on a write, answering 412 when the client holds
+// an old version and 428 when REQUIRE_IF_MATCH is set and the header is missing
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+			problems.Write(w, r, problems.PreconditionRequired("Send If-Match with the ETag from a previous read"))
+			return false
+		}
+		return true
+	}
+	if !matchesTag(header, entityTag(version), false) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return false
+	}
+	return true
+}
+
+// matchesTag looks for tag in an If-Match or If-None-Match list. The weak
+// comparison used by If-None-Match ignores the W/ prefix.
+func matchesTag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
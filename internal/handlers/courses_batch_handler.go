@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// maxCourseBatch caps how many courses one batch may write
+const maxCourseBatch = 100
+
+// Batch modes: atomic batches apply all items or none, best-effort batches apply every valid item
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+type courseBatchRequest struct {
+	Mode  string                       `json:"mode"`
+	Items []map[string]json.RawMessage `json:"items"`
+}
+
+// courseBatchResult reports what happened to one item, with an HTTP status
+type courseBatchResult struct {
+	Index  int                   `json:"index"`
+	Status int                   `json:"status"`
+	Course *store.Course         `json:"course,omitempty"`
+	Detail string                `json:"detail,omitempty"`
+	Errors []problems.FieldError `json:"errors,omitempty"`
+}
+
+func (h *CourseHandler) BatchCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Step 1: Authenticate once for the whole batch
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		problems.Write(w, r, problems.Unauthorized("Unauthorized. Missing Basic Auth"))
+		return
+	}
+	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
+	if err != nil {
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
+		return
+	}
+
+	// 🔹 Step 2: Decode the batch
+	var batch courseBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	if batch.Mode == "" {
+		batch.Mode = batchModeAtomic
+	}
+	if batch.Mode != batchModeAtomic && batch.Mode != batchModeBestEffort {
+		problems.Write(w, r, problems.Invalid("Invalid batch", problems.FieldError{Field: "mode", Message: "must be atomic or best_effort"}))
+		return
+	}
+	if len(batch.Items) == 0 || len(batch.Items) > maxCourseBatch {
+		problems.Write(w, r, problems.Invalid("Invalid batch", problems.FieldError{Field: "items", Message: fmt.Sprintf("must hold between 1 and %d courses", maxCourseBatch)}))
+		return
+	}
+
+	// 🔹 Step 3: Load every course being updated in one query
+	results := make([]courseBatchResult, len(batch.Items))
+	ids := make([]uint, len(batch.Items))
+	versions := make([]int64, len(batch.Items))
+	var updateIDs []uint
+	for i, item := range batch.Items {
+		results[i].Index = i
+		if raw, ok := item["id"]; ok {
+			if err := json.Unmarshal(raw, &ids[i]); err != nil {
+				results[i].fail(http.StatusBadRequest, "Invalid course", problems.FieldError{Field: "id", Message: "must be a non-negative integer"})
+				continue
+			}
+			updateIDs = append(updateIDs, ids[i])
+		}
+		if raw, ok := item["version"]; ok {
+			if err := json.Unmarshal(raw, &versions[i]); err != nil {
+				results[i].fail(http.StatusBadRequest, "Invalid course", problems.FieldError{Field: "version", Message: "must be an integer"})
+			}
+		}
+		delete(item, "id")
+		delete(item, "version")
+	}
+	existing := map[uint]store.Course{}
+	if len(updateIDs) > 0 {
+		found, err := h.Store.Courses.GetCoursesByIDs(r.Context(), updateIDs)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Could not fetch courses").Wrap(err))
+			return
+		}
+		for _, course := range found {
+			existing[course.ID] = course
+		}
+	}
+
+	// 🔹 Step 4: Merge each item into its course and validate it
+	courses := make([]*store.Course, len(batch.Items))
+	var instructorIDs []uint
+	for i, item := range batch.Items {
+		if results[i].Status != 0 {
+			continue
+		}
+		course := &store.Course{OwnerUserID: user.ID}
+		if ids[i] != 0 {
+			current, found := existing[ids[i]]
+			switch {
+			case !found:
+				results[i].fail(http.StatusNotFound, fmt.Sprintf("Course %d not found", ids[i]))
+				continue
+			case current.OwnerUserID != user.ID:
+				results[i].fail(http.StatusForbidden, "Unauthorized. Only the owner can update this course")
+				continue
+			case versions[i] != 0 && versions[i] != current.Version:
+				results[i].fail(http.StatusPreconditionFailed, modifiedDetail)
+				continue
+			}
+			course = &current
+			versions[i] = current.Version
+		}
+
+		_, fieldErrors := applyPatch(course, item, store.CourseMutableColumns)
+		if len(fieldErrors) == 0 {
+			fieldErrors = dto.ValidateCourse(course)
+		}
+		if len(fieldErrors) > 0 {
+			results[i].fail(http.StatusBadRequest, "Invalid course", fieldErrors...)
+			continue
+		}
+		courses[i] = course
+		instructorIDs = append(instructorIDs, course.InstructorID)
+	}
+
+	// 🔹 Step 5: Resolve all instructors in one query
+	if len(instructorIDs) > 0 {
+		instructors, err := h.Store.Instructors.ExistingInstructorIDs(r.Context(), instructorIDs)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Could not fetch instructors").Wrap(err))
+			return
+		}
+		for i, course := range courses {
+			if course != nil && !instructors[course.InstructorID] {
+				results[i].fail(http.StatusBadRequest, "Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"})
+				courses[i] = nil
+			}
+		}
+	}
+
+	// 🔹 Step 6: Save the valid courses; an atomic batch with any invalid item saves nothing
+	atomic := batch.Mode == batchModeAtomic
+	var writes []store.CourseWrite
+	var writeIndexes []int
+	for i, course := range courses {
+		if course != nil {
+			writes = append(writes, store.CourseWrite{Course: course, Version: versions[i]})
+			writeIndexes = append(writeIndexes, i)
+		}
+	}
+	if atomic && len(writes) < len(batch.Items) {
+		writes = nil
+	}
+	if len(writes) > 0 {
+		writeErrors, err := h.Store.Courses.SaveCourses(r.Context(), writes, atomic)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Could not save courses").Wrap(err))
+			return
+		}
+		for j, writeErr := range writeErrors {
+			i := writeIndexes[j]
+			switch {
+			case writeErr == nil && ids[i] == 0:
+				results[i] = courseBatchResult{Index: i, Status: http.StatusCreated, Course: courses[i]}
+			case writeErr == nil:
+				results[i] = courseBatchResult{Index: i, Status: http.StatusOK, Course: courses[i]}
+			case errors.Is(writeErr, store.ErrVersionMismatch):
+				results[i].fail(http.StatusPreconditionFailed, modifiedDetail)
+			case errors.Is(writeErr, store.ErrCatalogConflict):
+				results[i].fail(http.StatusConflict, catalogConflictDetail)
+			default:
+				problem := problems.From(problems.Internal("Could not save course").Wrap(writeErr))
+				results[i].fail(problem.Status, problem.Detail)
+			}
+		}
+	}
+
+	// 🔹 Step 7: In an atomic batch that failed, nothing was saved
+	failed := false
+	for _, result := range results {
+		failed = failed || result.Status >= http.StatusBadRequest
+	}
+	if atomic && failed {
+		for i := range results {
+			if results[i].Status < http.StatusBadRequest {
+				results[i] = courseBatchResult{Index: i, Status: http.StatusFailedDependency, Detail: "Not saved because another item in the atomic batch failed"}
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusMultiStatus)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mode":    batch.Mode,
+		"results": results,
+	})
+}
+
+func (result *courseBatchResult) fail(status int, detail string, fieldErrors ...problems.FieldError) {
+	result.Status, result.Detail, result.Errors = status, detail, fieldErrors
+}
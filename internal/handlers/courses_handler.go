@@ -2,13 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/csye7125/team01/internal/analytics"
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
 	"github.com/csye7125/team01/internal/store"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// maxCompareCourses caps how many courses one comparison may include
+const maxCompareCourses = 10
+
+const (
+	defaultCoursePageSize = 25
+	maxCoursePageSize     = 100
+)
+
+// courseExpansions are the relationships course reads may embed with ?expand=
+var courseExpansions = []string{"instructor", "owner", "traces", "sentiment"}
+
+// courseSorts are the sort orders of the v1 course listing
+var courseSorts = sortOptions{
+	{store.CourseSortCode, store.CourseSortCode},
+	{store.CourseSortName, store.CourseSortName},
+	{store.CourseSortSemesterYear, store.CourseSortSemesterYear},
+	{store.CourseSortCreditHours, store.CourseSortCreditHours},
+	{store.CourseSortDateAdded, store.CourseSortDateAdded},
+}
+
 type CourseHandler struct {
 	Store *store.Storage
 }
@@ -23,21 +50,21 @@ func (h *CourseHandler) CreateCourseHandler(w http.ResponseWriter, r *http.Reque
 	// 🔹 Step 1: Extract Basic Auth
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized. Missing Basic Auth"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized. Missing Basic Auth"))
 		return
 	}
 
 	// 🔹 Step 2: Get user ID from username & password
 	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// 🔹 Step 3: Parse JSON request body
 	var course store.Course
 	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
 		return
 	}
 
@@ -50,13 +77,18 @@ func (h *CourseHandler) CreateCourseHandler(w http.ResponseWriter, r *http.Reque
 
 	// 🔹 Step 5: Ensure instructor exists
 	if err := h.Store.Instructors.CheckInstructorExists(r.Context(), course.InstructorID); err != nil {
-		http.Error(w, `{"error": "Instructor does not exist"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Instructor does not exist"))
 		return
 	}
 
 	// 🔹 Step 6: Create Course
-	if err := h.Store.Courses.CreateCourse(r.Context(), &course); err != nil {
-		http.Error(w, `{"error": "Could not create course"}`, http.StatusInternalServerError)
+	err = h.Store.Courses.CreateCourse(r.Context(), &course)
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not create course").Wrap(err))
 		return
 	}
 
@@ -70,17 +102,335 @@ func (h *CourseHandler) GetCourseHandler(w http.ResponseWriter, r *http.Request)
 
 	courseID, err := strconv.Atoi(chi.URLParam(r, "courseId"))
 	if err != nil {
-		http.Error(w, `{"error": "Invalid course ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
 		return
 	}
 
-	course, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID))
+	p, err := projectionParams(r.URL.Query(), store.Course{}, courseExpansions...)
 	if err != nil {
-		http.Error(w, `{"error": "Course not found"}`, http.StatusNotFound)
+		problems.Write(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(course)
+	// 🔹 The version only covers the course, so expanded courses are never cached
+	var course *store.Course
+	var age time.Duration
+	if len(p.expand) == 0 {
+		course, age, err = h.Store.Courses.GetCourseByIDCached(r.Context(), uint(courseID))
+	} else {
+		course, err = h.Store.Courses.GetCourseByID(r.Context(), uint(courseID))
+	}
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
+		return
+	}
+
+	if len(p.expand) == 0 {
+		writeCacheHeaders(w, h.Store.CacheTTL(), age)
+		writeValidators(w, course.Version, course.DateLastUpdated)
+		if notModified(w, r, course.Version, course.DateLastUpdated) {
+			return
+		}
+	}
+	if !p.active() {
+		json.NewEncoder(w).Encode(course)
+		return
+	}
+
+	expanded, err := h.expandCourses(r, p, []store.Course{*course})
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	rendered, err := p.render(course, expanded[0])
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not encode course").Wrap(err))
+		return
+	}
+	json.NewEncoder(w).Encode(rendered)
+}
+
+func (h *CourseHandler) ListCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+
+	filter, err := courseListFilter(r.URL.Query(), courseSorts)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	p, err := projectionParams(r.URL.Query(), store.Course{}, courseExpansions...)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	// 🔹 CSV and NDJSON stream every matching course instead of one page
+	if format := exportFormat(r); format != "" {
+		export, err := newExport(w, format, "courses", p, store.Course{}, courseExpansions...)
+		if err != nil {
+			problems.Write(w, r, err)
+			return
+		}
+		export.finish(r, h.Store.Courses.StreamCourses(r.Context(), filter, func(course *store.Course) error {
+			return export.write(course)
+		}))
+		return
+	}
+
+	list, err := h.Store.Courses.ListCourses(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch courses").Wrap(err))
+		return
+	}
+
+	writePageHeaders(w, r, list.Total, list.Next)
+	if list.Courses == nil {
+		list.Courses = []store.Course{}
+	}
+	if !p.active() {
+		json.NewEncoder(w).Encode(list.Courses)
+		return
+	}
+
+	expanded, err := h.expandCourses(r, p, list.Courses)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	items := make([]interface{}, len(list.Courses))
+	for i := range list.Courses {
+		items[i] = list.Courses[i]
+	}
+	rendered, err := p.renderAll(items, expanded)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not encode courses").Wrap(err))
+		return
+	}
+	json.NewEncoder(w).Encode(rendered)
+}
+
+// expandCourses loads the relationships a course read asked for, one query per
+// relationship whatever the number of courses. Owners and traces need Basic
+// Auth, and users may only read themselves, so other owners expand to null.
+func (h *CourseHandler) expandCourses(r *http.Request, p projection, courses []store.Course) ([]map[string]interface{}, error) {
+	expanded := make([]map[string]interface{}, len(courses))
+	ids := make([]uint, len(courses))
+	for i, course := range courses {
+		expanded[i] = map[string]interface{}{}
+		ids[i] = course.ID
+	}
+	if len(courses) == 0 {
+		return expanded, nil
+	}
+
+	// 🔹 Owners, traces and sentiment are only served to signed-in users, as
+	// on their own routes
+	var viewer *store.User
+	if p.expands("owner") || p.expands("traces") || p.expands("sentiment") {
+		var err error
+		if viewer, err = basicAuthUser(r, h.Store.Users); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.expands("instructor") {
+		instructorIDs := make([]uint, len(courses))
+		for i, course := range courses {
+			instructorIDs[i] = course.InstructorID
+		}
+		instructors, err := h.Store.Instructors.GetInstructorsByIDs(r.Context(), instructorIDs)
+		if err != nil {
+			return nil, problems.Internal("Could not fetch instructors").Wrap(err)
+		}
+		byID := make(map[uint]*store.Instructor, len(instructors))
+		for i := range instructors {
+			byID[instructors[i].InstructorID] = &instructors[i]
+		}
+		for i, course := range courses {
+			expanded[i]["instructor"] = byID[course.InstructorID]
+		}
+	}
+
+	if p.expands("owner") {
+		owner := *viewer
+		owner.Password = ""
+		for i, course := range courses {
+			expanded[i]["owner"] = (*store.User)(nil)
+			if course.OwnerUserID == viewer.ID {
+				expanded[i]["owner"] = &owner
+			}
+		}
+	}
+
+	// 🔹 Traces embed the first page of GET /v1/course/{course_id}/trace
+	if p.expands("traces") {
+		pages, err := h.Store.Traces.FirstTracePages(r.Context(), ids, defaultTracePageSize)
+		if err != nil {
+			return nil, problems.Internal("Could not fetch traces").Wrap(err)
+		}
+		for i, course := range courses {
+			expanded[i]["traces"] = pages[course.ID].Traces
+		}
+	}
+
+	// 🔹 Sentiment embeds the summary and themes of GET /v1/course/{courseId}/sentiment
+	if p.expands("sentiment") {
+		sentiments, err := h.Store.Sentiments.GetCourseSentiments(r.Context(), ids)
+		if err != nil {
+			return nil, problems.Internal("Could not fetch course sentiment").Wrap(err)
+		}
+		for i, course := range courses {
+			expanded[i]["sentiment"] = sentiments[course.ID]
+		}
+	}
+	return expanded, nil
+}
+
+// courseListFilter reads the filters, sort and page of a course listing; the
+// first of sorts is the default
+func courseListFilter(query url.Values, sorts sortOptions) (store.CourseListFilter, error) {
+	filter := store.CourseListFilter{
+		Code:         strings.TrimSpace(query.Get("code")),
+		SemesterTerm: query.Get("semester_term"),
+		Name:         strings.TrimSpace(query.Get("q")),
+	}
+
+	// 🔹 Page size and sort order
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit"), defaultCoursePageSize); err != nil || filter.Limit < 1 || filter.Limit > maxCoursePageSize {
+		return filter, problems.BadRequest(fmt.Sprintf("limit must be between 1 and %d", maxCoursePageSize))
+	}
+	sort, desc := sortParam(query.Get("sort"), sorts[0].name)
+	if filter.Sort, filter.Desc = sorts.key(sort), desc; filter.Sort == "" {
+		return filter, problems.BadRequest(fmt.Sprintf("sort must be %s, optionally prefixed with -", sorts))
+	}
+	if filter.After, err = cursorParam(query.Get("cursor"), filter.Sort, filter.Desc); err != nil {
+		return filter, problems.BadRequest("Invalid cursor")
+	}
+
+	// 🔹 Optional filters
+	if filter.SemesterYear, err = intParam(query.Get("semester_year"), 0); err != nil {
+		return filter, problems.BadRequest("Invalid semester_year")
+	}
+	instructorID, err := intParam(query.Get("instructor_id"), 0)
+	if err != nil || instructorID < 0 {
+		return filter, problems.BadRequest("Invalid instructor_id")
+	}
+	filter.InstructorID = uint(instructorID)
+	ownerUserID, err := intParam(query.Get("owner_user_id"), 0)
+	if err != nil || ownerUserID < 0 {
+		return filter, problems.BadRequest("Invalid owner_user_id")
+	}
+	filter.OwnerUserID = uint(ownerUserID)
+	for name, bound := range map[string]**int{"min_credit_hours": &filter.MinCreditHours, "max_credit_hours": &filter.MaxCreditHours} {
+		if value := query.Get(name); value != "" {
+			hours, err := strconv.Atoi(value)
+			if err != nil || hours < 0 {
+				return filter, problems.BadRequest(fmt.Sprintf("Invalid %s", name))
+			}
+			*bound = &hours
+		}
+	}
+	return filter, nil
+}
+
+func (h *CourseHandler) CompareCoursesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Parse the comma-separated course IDs, keeping request order
+	var ids []uint
+	seen := map[uint]bool{}
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			problems.Write(w, r, problems.BadRequest(fmt.Sprintf("Invalid course ID %q", part)))
+			return
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) < 2 || len(ids) > maxCompareCourses {
+		problems.Write(w, r, problems.BadRequest(fmt.Sprintf("Provide between 2 and %d course IDs", maxCompareCourses)))
+		return
+	}
+
+	// 🔹 Load the courses and make sure all of them exist
+	found, err := h.Store.Courses.GetCoursesByIDs(r.Context(), ids)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch courses").Wrap(err))
+		return
+	}
+	byID := map[uint]store.Course{}
+	for _, course := range found {
+		byID[course.ID] = course
+	}
+	courses := make([]store.Course, 0, len(ids))
+	for _, id := range ids {
+		course, ok := byID[id]
+		if !ok {
+			problems.Write(w, r, problems.NotFound(fmt.Sprintf("Course %d not found", id)))
+			return
+		}
+		courses = append(courses, course)
+	}
+
+	// 🔹 Align parsed results by question
+	results, err := h.Store.Results.GetResultsByCourseIDs(r.Context(), ids)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch course results").Wrap(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(analytics.BuildCourseComparison(courses, results))
+}
+
+func (h *CourseHandler) GetCourseSentimentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	courseID, err := strconv.Atoi(chi.URLParam(r, "courseId"))
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
+		return
+	}
+
+	if _, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID)); err != nil {
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
+		return
+	}
+
+	summary, err := h.Store.Sentiments.GetCourseSummary(r.Context(), uint(courseID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load course sentiment").Wrap(err))
+		return
+	}
+	themes, err := h.Store.Sentiments.GetCourseThemes(r.Context(), uint(courseID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load course themes").Wrap(err))
+		return
+	}
+	traces, err := h.Store.Sentiments.GetTraceSentimentsByCourseID(r.Context(), uint(courseID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load trace sentiment").Wrap(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"course_id": courseID,
+		"summary":   summary,
+		"themes":    themes,
+		"traces":    traces,
+	})
 }
 
 func (h *CourseHandler) UpdateCourseHandler(w http.ResponseWriter, r *http.Request) {
@@ -89,51 +439,81 @@ func (h *CourseHandler) UpdateCourseHandler(w http.ResponseWriter, r *http.Reque
 	// 🔹 Extract Basic Auth credentials
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized. Missing Basic Auth"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized. Missing Basic Auth"))
 		return
 	}
 
 	// 🔹 Get user ID from credentials
 	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// 🔹 Extract course ID from URL
 	courseID, err := strconv.Atoi(chi.URLParam(r, "courseId"))
 	if err != nil {
-		http.Error(w, `{"error": "Invalid course ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
 		return
 	}
 
 	// 🔹 Get the course details
 	course, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID))
 	if err != nil {
-		http.Error(w, `{"error": "Course not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
 		return
 	}
 
 	// 🔹 Ensure the authenticated user is the course owner
 	if user.ID != course.OwnerUserID {
-		http.Error(w, `{"error": "Unauthorized. Only the owner can update this course"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("Unauthorized. Only the owner can update this course"))
+		return
+	}
+
+	// 🔹 Make sure the caller saw the current version
+	if !checkIfMatch(w, r, course.Version) {
 		return
 	}
 
-	// 🔹 Decode update data
-	var updateData store.Course
+	// 🔹 Decode the update over the course; the service manages every column
+	// but the mutable ones, so those are kept
+	updateData := *course
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	updateData.ID, updateData.OwnerUserID, updateData.CatalogCourseID = course.ID, course.OwnerUserID, course.CatalogCourseID
+	updateData.DateAdded, updateData.Version = course.DateAdded, course.Version
+
+	// 🔹 Validate the merged course
+	if fieldErrors := dto.ValidateCourse(&updateData); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid course", fieldErrors...))
 		return
 	}
+	if updateData.InstructorID != course.InstructorID {
+		if err := h.Store.Instructors.CheckInstructorExists(r.Context(), updateData.InstructorID); err != nil {
+			problems.Write(w, r, problems.Invalid("Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"}))
+			return
+		}
+	}
 
 	// 🔹 Perform update
-	if err := h.Store.Courses.UpdateCourse(r.Context(), uint(courseID), &updateData); err != nil {
-		http.Error(w, `{"error": "Could not update course"}`, http.StatusInternalServerError)
+	err = h.Store.Courses.UpdateCourse(r.Context(), uint(courseID), course.Version, &updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update course").Wrap(err))
 		return
 	}
 
 	// 🔹 Respond with success
+	writeValidators(w, updateData.Version, updateData.DateLastUpdated)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updateData)
 }
@@ -144,53 +524,86 @@ func (h *CourseHandler) PatchCourseHandler(w http.ResponseWriter, r *http.Reques
 	// 🔹 Extract Basic Auth credentials
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized. Missing Basic Auth"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized. Missing Basic Auth"))
 		return
 	}
 
 	// 🔹 Get user ID from credentials
 	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// 🔹 Extract course ID from URL
 	courseID, err := strconv.Atoi(chi.URLParam(r, "courseId"))
 	if err != nil {
-		http.Error(w, `{"error": "Invalid course ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
 		return
 	}
 
 	// 🔹 Get the course details
 	course, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID))
 	if err != nil {
-		http.Error(w, `{"error": "Course not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
 		return
 	}
 
 	// 🔹 Ensure the authenticated user is the course owner
 	if user.ID != course.OwnerUserID {
-		http.Error(w, `{"error": "Unauthorized. Only the owner can patch this course"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("Unauthorized. Only the owner can patch this course"))
 		return
 	}
 
-	// 🔹 Decode update data
-	var updateData map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+	// 🔹 Make sure the caller saw the current version
+	if !checkIfMatch(w, r, course.Version) {
 		return
 	}
 
+	// 🔹 Merge the patch into the course and validate the result
+	members, err := readPatch(r, course)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	version := course.Version
+	updateData, fieldErrors := applyPatch(course, members, store.CourseMutableColumns)
+	if len(fieldErrors) == 0 {
+		fieldErrors = dto.ValidateCourse(course)
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid course patch", fieldErrors...))
+		return
+	}
+	if _, changed := updateData["instructor_id"]; changed {
+		if err := h.Store.Instructors.CheckInstructorExists(r.Context(), course.InstructorID); err != nil {
+			problems.Write(w, r, problems.Invalid("Invalid course patch", problems.FieldError{Field: "instructor_id", Message: "does not exist"}))
+			return
+		}
+	}
+
 	// 🔹 Perform update
-	if err := h.Store.Courses.PatchCourse(r.Context(), uint(courseID), updateData); err != nil {
-		http.Error(w, `{"error": "Could not patch course"}`, http.StatusInternalServerError)
+	err = h.Store.Courses.PatchCourse(r.Context(), uint(courseID), version, updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not patch course").Wrap(err))
 		return
 	}
 
-	// 🔹 Respond with success
+	// 🔹 Respond with the patched course
+	course.Version = version + 1
+	course.DateLastUpdated = updateData["date_last_updated"].(time.Time)
+	course.CatalogCourseID = updateData["catalog_course_id"].(uint)
+	writeValidators(w, course.Version, course.DateLastUpdated)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updateData)
+	json.NewEncoder(w).Encode(course)
 }
 
 func (h *CourseHandler) DeleteCourseHandler(w http.ResponseWriter, r *http.Request) {
@@ -199,40 +612,50 @@ func (h *CourseHandler) DeleteCourseHandler(w http.ResponseWriter, r *http.Reque
 	// 🔹 Extract Basic Auth credentials
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized. Missing Basic Auth"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized. Missing Basic Auth"))
 		return
 	}
 
 	// 🔹 Get user ID from credentials
 	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// 🔹 Extract course ID from URL
 	courseID, err := strconv.Atoi(chi.URLParam(r, "courseId"))
 	if err != nil {
-		http.Error(w, `{"error": "Invalid course ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
 		return
 	}
 
 	// 🔹 Get the course details
 	course, err := h.Store.Courses.GetCourseByID(r.Context(), uint(courseID))
 	if err != nil {
-		http.Error(w, `{"error": "Course not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
 		return
 	}
 
 	// 🔹 Ensure the authenticated user is the course owner
 	if user.ID != course.OwnerUserID {
-		http.Error(w, `{"error": "Unauthorized. Only the owner can delete this course"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("Unauthorized. Only the owner can delete this course"))
+		return
+	}
+
+	// 🔹 Make sure the caller saw the current version
+	if !checkIfMatch(w, r, course.Version) {
 		return
 	}
 
 	// 🔹 Delete the course
-	if err := h.Store.Courses.DeleteCourse(r.Context(), uint(courseID)); err != nil {
-		http.Error(w, `{"error": "Could not delete course"}`, http.StatusInternalServerError)
+	err = h.Store.Courses.DeleteCourse(r.Context(), uint(courseID), course.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete course").Wrap(err))
 		return
 	}
 
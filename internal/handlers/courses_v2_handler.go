@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// courseSortsV2 are the sort orders of the v2 course listing, named after the response members
+var courseSortsV2 = sortOptions{
+	{"code", store.CourseSortCode},
+	{"name", store.CourseSortName},
+	{"semester_year", store.CourseSortSemesterYear},
+	{"credit_hours", store.CourseSortCreditHours},
+	{"created_at", store.CourseSortDateAdded},
+}
+
+func (h *CourseHandler) ListCoursesV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := courseListFilter(r.URL.Query(), courseSortsV2)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	list, err := h.Store.Courses.ListCourses(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch courses").Wrap(err))
+		return
+	}
+
+	writePageHeaders(w, r, list.Total, list.Next)
+	json.NewEncoder(w).Encode(dto.NewCourses(list.Courses))
+}
+
+func (h *CourseHandler) GetCourseV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	courseID, err := idParam(r, "courseId", "course")
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	course, age, err := h.Store.Courses.GetCourseByIDCached(r.Context(), courseID)
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
+		return
+	}
+
+	writeCacheHeaders(w, h.Store.CacheTTL(), age)
+	writeValidators(w, course.Version, course.DateLastUpdated)
+	if notModified(w, r, course.Version, course.DateLastUpdated) {
+		return
+	}
+	json.NewEncoder(w).Encode(dto.NewCourse(course))
+}
+
+func (h *CourseHandler) CreateCourseV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Authenticate the future owner
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	// 🔹 Build the course from the request and validate it
+	var req dto.CourseRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	course := &store.Course{OwnerUserID: user.ID}
+	req.Apply(course)
+	if fieldErrors := dto.ValidateCourse(course); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid course", fieldErrors...))
+		return
+	}
+	if err := h.Store.Instructors.CheckInstructorExists(r.Context(), course.InstructorID); err != nil {
+		problems.Write(w, r, problems.Invalid("Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"}))
+		return
+	}
+
+	// 🔹 Create the course
+	err = h.Store.Courses.CreateCourse(r.Context(), course)
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not create course").Wrap(err))
+		return
+	}
+
+	writeCreated(w, fmt.Sprintf("/v2/courses/%d", course.ID), course.Version, dto.NewCourse(course))
+}
+
+func (h *CourseHandler) UpdateCourseV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	course, ok := h.courseForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	// 🔹 Replace every field owners may set
+	var req dto.CourseRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	h.saveCourseV2(w, r, course, req, req.Columns())
+}
+
+func (h *CourseHandler) PatchCourseV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	course, ok := h.courseForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	// 🔹 Merge the patch into the fields owners may set
+	req := dto.NewCourseRequest(course)
+	members, err := readPatch(r, req)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	changes, fieldErrors := applyPatch(&req, members, dto.CourseRequestFields)
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid course patch", fieldErrors...))
+		return
+	}
+	h.saveCourseV2(w, r, course, req, changes)
+}
+
+func (h *CourseHandler) DeleteCourseV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	course, ok := h.courseForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Store.Courses.DeleteCourse(r.Context(), course.ID, course.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete course").Wrap(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// courseForWriteV2 loads the course a write targets after checking that the
+// caller owns it and, with If-Match, still has its current version
+func (h *CourseHandler) courseForWriteV2(w http.ResponseWriter, r *http.Request) (*store.Course, bool) {
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+	courseID, err := idParam(r, "courseId", "course")
+	if err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+
+	course, err := h.Store.Courses.GetCourseByID(r.Context(), courseID)
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Course not found").Wrap(err))
+		return nil, false
+	}
+	if user.ID != course.OwnerUserID {
+		problems.Write(w, r, problems.Forbidden("Only the owner can change this course"))
+		return nil, false
+	}
+	return course, checkIfMatch(w, r, course.Version)
+}
+
+// saveCourseV2 validates the course as req leaves it, writes the changed
+// columns and answers with the updated course
+func (h *CourseHandler) saveCourseV2(w http.ResponseWriter, r *http.Request, course *store.Course, req dto.CourseRequest, changes map[string]interface{}) {
+	instructorID := course.InstructorID
+	req.Apply(course)
+	if fieldErrors := dto.ValidateCourse(course); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid course", fieldErrors...))
+		return
+	}
+	if course.InstructorID != instructorID {
+		if err := h.Store.Instructors.CheckInstructorExists(r.Context(), course.InstructorID); err != nil {
+			problems.Write(w, r, problems.Invalid("Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"}))
+			return
+		}
+	}
+
+	err := h.Store.Courses.PatchCourse(r.Context(), course.ID, course.Version, changes)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if errors.Is(err, store.ErrCatalogConflict) {
+		problems.Write(w, r, problems.Conflict(catalogConflictDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update course").Wrap(err))
+		return
+	}
+
+	course.Version++
+	course.DateLastUpdated = changes["date_last_updated"].(time.Time)
+	writeValidators(w, course.Version, course.DateLastUpdated)
+	json.NewEncoder(w).Encode(dto.NewCourse(course))
+}
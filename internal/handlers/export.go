@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+)
+
+// Media types list endpoints stream instead of a JSON page
+const (
+	mediaTypeCSV    = "text/csv"
+	mediaTypeNDJSON = "application/x-ndjson"
+)
+
+// exportFlushRows is how many rows an export buffers before sending them on
+const exportFlushRows = 100
+
+// exportFormat picks the list representation the Accept header prefers:
+// text/csv, application/x-ndjson, or "" for the usual JSON page, which also
+// serves wildcards and media types that are not offered
+func exportFormat(r *http.Request) string {
+	best, bestQ := "", 0.0
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(value, 64); err != nil {
+					continue
+				}
+			}
+			switch mediaType {
+			case mediaTypeCSV, mediaTypeNDJSON, "application/json":
+				if q > bestQ {
+					best, bestQ = mediaType, q
+				}
+			}
+		}
+	}
+	if best == "application/json" {
+		return ""
+	}
+	return best
+}
+
+// IsExport tells whether a request asks for a list as CSV or NDJSON, which
+// streams for as long as rows keep coming
+func IsExport(r *http.Request) bool {
+	return r.Method == http.MethodGet && exportFormat(r) != ""
+}
+
+// export streams the rows of a list as CSV or NDJSON while they are read from
+// the database. Members come in struct order, so CSV columns are stable, and
+// fields selects them as it does for JSON.
+type export struct {
+	w       http.ResponseWriter
+	format  string
+	name    string
+	p       projection
+	columns []string
+	csv     *csv.Writer
+	started bool
+	rows    int
+}
+
+// newExport prepares the export of a list of resource, named name in the
+// download. Relationships cannot be embedded in a row, so expand is rejected.
+func newExport(w http.ResponseWriter, format, name string, p projection, resource interface{}, expandable ...string) (*export, error) {
+	if len(p.expand) > 0 {
+		return nil, problems.BadRequest("CSV and NDJSON exports cannot embed related resources")
+	}
+	e := &export{w: w, format: format, name: name, p: p}
+	for _, member := range jsonMemberNames(reflect.TypeOf(resource)) {
+		if !contains(expandable, member) && (p.fields == nil || p.fields[member]) {
+			e.columns = append(e.columns, member)
+		}
+	}
+	return e, nil
+}
+
+// start sends the headers, and for CSV the header row, once the first row is ready
+func (e *export) start() error {
+	e.started = true
+	extension := "csv"
+	if e.format == mediaTypeNDJSON {
+		extension = "ndjson"
+	}
+	e.w.Header().Set("Content-Type", e.format+"; charset=utf-8")
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.name+"."+extension))
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == mediaTypeCSV {
+		e.csv = csv.NewWriter(e.w)
+		return e.csv.Write(e.columns)
+	}
+	return nil
+}
+
+// write adds one row to the export
+func (e *export) write(item interface{}) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	members, err := e.p.render(item, nil)
+	if err != nil {
+		return err
+	}
+	if e.format == mediaTypeNDJSON {
+		// 🔹 Written by hand to keep the members in column order
+		var line bytes.Buffer
+		line.WriteByte('{')
+		for _, column := range e.columns {
+			value, ok := members[column]
+			if !ok {
+				continue
+			}
+			if line.Len() > 1 {
+				line.WriteByte(',')
+			}
+			key, _ := json.Marshal(column)
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(value)
+		}
+		line.WriteString("}\n")
+		if _, err := e.w.Write(line.Bytes()); err != nil {
+			return err
+		}
+	} else {
+		record := make([]string, len(e.columns))
+		for i, column := range e.columns {
+			record[i] = csvCell(members[column])
+		}
+		if err := e.csv.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if e.rows++; e.rows%exportFlushRows == 0 {
+		e.flush()
+	}
+	return nil
+}
+
+// finish completes an export after the rows ran out or failed. A failure
+// before the first row is reported as usual; after it, the status is already
+// sent, so the response is cut short for clients to notice.
+func (e *export) finish(r *http.Request, err error) {
+	if err != nil && !e.started {
+		problems.Write(e.w, r, problems.Internal(fmt.Sprintf("Could not export %s", e.name)).Wrap(err))
+		return
+	}
+	if err != nil {
+		log.Printf("export of %s failed after %d rows: %v", e.name, e.rows, err)
+		panic(http.ErrAbortHandler)
+	}
+	if !e.started {
+		if err := e.start(); err != nil {
+			log.Printf("export of %s failed: %v", e.name, err)
+			return
+		}
+	}
+	e.flush()
+}
+
+func (e *export) flush() {
+	if e.csv != nil {
+		e.csv.Flush()
+	}
+	http.NewResponseController(e.w).Flush()
+}
+
+// csvCell writes a JSON member as a spreadsheet cell: strings unquoted, null
+// empty, and numbers and booleans as they are; encoding/csv does the escaping.
+// Strings a spreadsheet would run as a formula are prefixed with a quote.
+func csvCell(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var text string
+	if value[0] == '"' && json.Unmarshal(value, &text) == nil {
+		if text != "" && strings.ContainsRune(csvFormulaStarts, rune(text[0])) {
+			return "'" + text
+		}
+		return text
+	}
+	return string(value)
+}
+
+// csvFormulaStarts are the first characters that make a cell a formula
+const csvFormulaStarts = "=+-@\t\r"
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{`"Network Structures"`, "Network Structures"},
+		{`null`, ""},
+		{`""`, ""},
+		{`4`, "4"},
+		{`-5`, "-5"},
+		{`true`, "true"},
+		{`"=HYPERLINK(\"http://example.com\")"`, `'=HYPERLINK("http://example.com")`},
+		{`"+1+1"`, "'+1+1"},
+		{`"-2+3"`, "'-2+3"},
+		{`"@SUM(A1:A2)"`, "'@SUM(A1:A2)"},
+		{`"\tcmd"`, "'\tcmd"},
+		{`"\rcmd"`, "'\rcmd"},
+		{`"a=b"`, "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvCell(json.RawMessage(tt.value)); got != tt.want {
+			t.Errorf("csvCell(%s) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+)
+
+// projection is what a read asks to see with ?fields= and ?expand=
+type projection struct {
+	fields map[string]bool // nil keeps every member
+	expand map[string]bool
+}
+
+// projectionParams reads ?fields= against the JSON members of resource, which
+// must be a struct, and ?expand= against the relationships it may embed.
+// Relationships are only selected with expand, never with fields.
+func projectionParams(query url.Values, resource interface{}, expandable ...string) (projection, error) {
+	var p projection
+
+	if value := query.Get("fields"); value != "" {
+		members := jsonMembers(reflect.TypeOf(resource))
+		p.fields = map[string]bool{}
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if !members[field] || contains(expandable, field) {
+				return p, problems.BadRequest(fmt.Sprintf("Unknown field %q", field))
+			}
+			p.fields[field] = true
+		}
+	}
+
+	if value := query.Get("expand"); value != "" {
+		p.expand = map[string]bool{}
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if !contains(expandable, name) {
+				return p, problems.BadRequest(fmt.Sprintf("Unknown expansion %q; expand must list %s", name, strings.Join(expandable, ", ")))
+			}
+			p.expand[name] = true
+		}
+	}
+	return p, nil
+}
+
+// expands tells whether the read asked to embed the relationship name
+func (p projection) expands(name string) bool {
+	return p.expand[name]
+}
+
+// active tells whether the read asked for anything but the whole resource
+func (p projection) active() bool {
+	return p.fields != nil || len(p.expand) > 0
+}
+
+// render encodes item with only the selected members and adds its expansions,
+// which are kept whatever fields selects
+func (p projection) render(item interface{}, expanded map[string]interface{}) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &members); err != nil {
+		return nil, err
+	}
+	if p.fields != nil {
+		for name := range members {
+			if !p.fields[name] && !p.expand[name] {
+				delete(members, name)
+			}
+		}
+	}
+	for name, value := range expanded {
+		if members[name], err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	return members, nil
+}
+
+// renderAll is render for a page of items; expanded holds each item's expansions
+func (p projection) renderAll(items []interface{}, expanded []map[string]interface{}) ([]map[string]json.RawMessage, error) {
+	rendered := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		var err error
+		if rendered[i], err = p.render(item, expanded[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// jsonMembers are the member names encoding/json gives the fields of a struct type
+func jsonMembers(t reflect.Type) map[string]bool {
+	members := map[string]bool{}
+	for _, name := range jsonMemberNames(t) {
+		members[name] = true
+	}
+	return members
+}
+
+// jsonMemberNames lists the members of a struct type in the order encoding/json writes them
+func jsonMemberNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/csye7125/team01/internal/graph"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+type GraphQLHandler struct {
+	Store  *store.Storage
+	Schema *graphql.Schema
+}
+
+func NewGraphQLHandler(store *store.Storage) *GraphQLHandler {
+	return &GraphQLHandler{Store: store, Schema: graph.NewSchema()}
+}
+
+// graphQLRequest is the body of a GraphQL POST
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLHandler runs a query. Credentials are optional, as fields that need a
+// user check for one themselves, but credentials that are sent must be valid.
+func (h *GraphQLHandler) GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Authenticate the caller, if any
+	var viewer *store.User
+	if _, _, ok := r.BasicAuth(); ok {
+		user, err := basicAuthUser(r, h.Store.Users)
+		if err != nil {
+			problems.Write(w, r, err)
+			return
+		}
+		viewer = user
+	}
+
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid request payload: "+err.Error()))
+		return
+	}
+	if req.Query == "" {
+		problems.Write(w, r, problems.BadRequest("Missing query"))
+		return
+	}
+
+	// 🔹 Reject invalid and too expensive queries before resolving anything
+	if errs := h.Schema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		json.NewEncoder(w).Encode(&graphql.Response{Errors: errs})
+		return
+	}
+	cost, err := graph.QueryCost(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		json.NewEncoder(w).Encode(&graphql.Response{Errors: []*gqlerrors.QueryError{{Message: err.Error()}}})
+		return
+	}
+	if cost > graph.MaxCost {
+		json.NewEncoder(w).Encode(&graphql.Response{Errors: []*gqlerrors.QueryError{{
+			Message:    fmt.Sprintf("query cost %d exceeds the limit of %d", cost, graph.MaxCost),
+			Extensions: map[string]interface{}{"code": "COST_LIMIT_EXCEEDED", "cost": cost, "maxCost": graph.MaxCost},
+		}}})
+		return
+	}
+
+	// ✅ Resolve the query
+	ctx := graph.NewContext(r.Context(), h.Store, viewer)
+	json.NewEncoder(w).Encode(h.Schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
@@ -2,12 +2,37 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/csye7125/team01/internal/analytics"
+	"github.com/csye7125/team01/internal/problems"
 	"github.com/csye7125/team01/internal/store"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	defaultInstructorPageSize = 25
+	maxInstructorPageSize     = 100
+)
+
+// instructorSorts are the sort orders of the v1 instructor listing
+var instructorSorts = sortOptions{
+	{store.InstructorSortName, store.InstructorSortName},
+	{store.InstructorSortDateCreated, store.InstructorSortDateCreated},
+	{store.InstructorSortRelevance, store.InstructorSortRelevance},
+}
+
+// instructorExpansions are the relationships instructor reads may embed with ?expand=
+var instructorExpansions = []string{"courses", "sentiment"}
+
+// instructorMutableFields are the instructor members clients may change with PATCH
+var instructorMutableFields = []string{"name"}
+
 type InstructorHandler struct {
 	Store *store.Storage
 }
@@ -22,7 +47,7 @@ func (h *InstructorHandler) CreateInstructorHandler(w http.ResponseWriter, r *ht
 	// 🔹 Extract Basic Auth Credentials
 	username, _, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Missing basic auth credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Missing basic auth credentials"))
 		return
 	}
 
@@ -31,7 +56,7 @@ func (h *InstructorHandler) CreateInstructorHandler(w http.ResponseWriter, r *ht
 		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
 		return
 	}
 
@@ -42,7 +67,7 @@ func (h *InstructorHandler) CreateInstructorHandler(w http.ResponseWriter, r *ht
 
 	// 🔹 Call `CreateInstructor` with `username`
 	if err := h.Store.Instructors.CreateInstructor(r.Context(), username, &instructor); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusInternalServerError)
+		problems.Write(w, r, problems.Internal("Could not create instructor").Wrap(err))
 		return
 	}
 
@@ -56,13 +81,284 @@ func (h *InstructorHandler) GetInstructorHandler(w http.ResponseWriter, r *http.
 
 	instructorID := chi.URLParam(r, "instructorId")
 
-	instructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), instructorID)
+	includeCourses, err := includeCoursesParam(r.URL.Query().Get("include"))
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest(err.Error()))
+		return
+	}
+	p, err := instructorProjection(r.URL.Query(), includeCourses)
 	if err != nil {
-		http.Error(w, `{"error": "Instructor not found"}`, http.StatusNotFound)
+		problems.Write(w, r, err)
 		return
 	}
+	includeCourses = p.expands("courses")
 
-	json.NewEncoder(w).Encode(instructor)
+	var instructor *store.Instructor
+	var age time.Duration
+	if includeCourses {
+		instructor, err = h.Store.Instructors.GetInstructorWithCourses(r.Context(), instructorID)
+	} else {
+		instructor, age, err = h.Store.Instructors.GetInstructorByIDCached(r.Context(), instructorID)
+	}
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+	if includeCourses && instructor.Courses == nil {
+		instructor.Courses = []store.Course{}
+	}
+
+	// 🔹 The version only covers the instructor, so expanded instructors are never cached
+	if len(p.expand) == 0 {
+		writeCacheHeaders(w, h.Store.CacheTTL(), age)
+		writeValidators(w, instructor.Version, instructor.DateUpdated)
+		if notModified(w, r, instructor.Version, instructor.DateUpdated) {
+			return
+		}
+	}
+	// 🔹 Embedded courses are part of the instructor, so only fields and sentiment need rendering
+	if p.fields == nil && !p.expands("sentiment") {
+		json.NewEncoder(w).Encode(instructor)
+		return
+	}
+	expanded, err := h.expandInstructors(r, p, []store.Instructor{*instructor})
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	rendered, err := p.render(instructor, expanded[0])
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not encode instructor").Wrap(err))
+		return
+	}
+	json.NewEncoder(w).Encode(rendered)
+}
+
+func (h *InstructorHandler) ListInstructorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+
+	filter, err := instructorListFilter(r.URL.Query(), instructorSorts)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	p, err := instructorProjection(r.URL.Query(), filter.IncludeCourses)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	filter.IncludeCourses = p.expands("courses")
+
+	// 🔹 CSV and NDJSON stream every matching instructor instead of one page
+	if format := exportFormat(r); format != "" {
+		export, err := newExport(w, format, "instructors", p, store.Instructor{}, instructorExpansions...)
+		if err != nil {
+			problems.Write(w, r, err)
+			return
+		}
+		export.finish(r, h.Store.Instructors.StreamInstructors(r.Context(), filter, func(instructor *store.Instructor) error {
+			return export.write(instructor)
+		}))
+		return
+	}
+
+	list, err := h.Store.Instructors.ListInstructors(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch instructors").Wrap(err))
+		return
+	}
+
+	writePageHeaders(w, r, list.Total, list.Next)
+	if list.Instructors == nil {
+		list.Instructors = []store.Instructor{}
+	}
+	if filter.IncludeCourses {
+		for i := range list.Instructors {
+			if list.Instructors[i].Courses == nil {
+				list.Instructors[i].Courses = []store.Course{}
+			}
+		}
+	}
+	if p.fields == nil && !p.expands("sentiment") {
+		json.NewEncoder(w).Encode(list.Instructors)
+		return
+	}
+	expanded, err := h.expandInstructors(r, p, list.Instructors)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	items := make([]interface{}, len(list.Instructors))
+	for i := range list.Instructors {
+		items[i] = list.Instructors[i]
+	}
+	rendered, err := p.renderAll(items, expanded)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not encode instructors").Wrap(err))
+		return
+	}
+	json.NewEncoder(w).Encode(rendered)
+}
+
+// instructorListFilter reads the search, sort and page of an instructor listing.
+// Searches rank the closest names first, and only they may sort by relevance.
+func instructorListFilter(query url.Values, sorts sortOptions) (store.InstructorListFilter, error) {
+	filter := store.InstructorListFilter{Name: strings.TrimSpace(query.Get("q"))}
+
+	var err error
+	if filter.IncludeCourses, err = includeCoursesParam(query.Get("include")); err != nil {
+		return filter, problems.BadRequest(err.Error())
+	}
+	if filter.Limit, err = intParam(query.Get("limit"), defaultInstructorPageSize); err != nil || filter.Limit < 1 || filter.Limit > maxInstructorPageSize {
+		return filter, problems.BadRequest(fmt.Sprintf("limit must be between 1 and %d", maxInstructorPageSize))
+	}
+	defaultSort := store.InstructorSortName
+	if filter.Name != "" {
+		defaultSort = "-" + store.InstructorSortRelevance
+	}
+	sort, desc := sortParam(query.Get("sort"), defaultSort)
+	filter.Sort, filter.Desc = sorts.key(sort), desc
+	if filter.Sort == "" || (filter.Sort == store.InstructorSortRelevance && filter.Name == "") {
+		return filter, problems.BadRequest(fmt.Sprintf("sort must be %s (with q), optionally prefixed with -", sorts))
+	}
+	if filter.After, err = cursorParam(query.Get("cursor"), filter.Sort, filter.Desc); err != nil {
+		return filter, problems.BadRequest("Invalid cursor")
+	}
+	return filter, nil
+}
+
+// expandInstructors loads the relationships an instructor read asked for
+// besides courses, which are loaded with the instructors
+func (h *InstructorHandler) expandInstructors(r *http.Request, p projection, instructors []store.Instructor) ([]map[string]interface{}, error) {
+	expanded := make([]map[string]interface{}, len(instructors))
+	ids := make([]uint, len(instructors))
+	for i, instructor := range instructors {
+		expanded[i] = map[string]interface{}{}
+		ids[i] = instructor.InstructorID
+	}
+
+	// 🔹 Sentiment embeds the summary and themes of GET /v1/instructor/{instructorId}/sentiment,
+	// and like that route needs Basic Auth
+	if p.expands("sentiment") {
+		if _, err := basicAuthUser(r, h.Store.Users); err != nil {
+			return nil, err
+		}
+	}
+	if p.expands("sentiment") && len(instructors) > 0 {
+		sentiments, err := h.Store.Sentiments.GetInstructorSentiments(r.Context(), ids)
+		if err != nil {
+			return nil, problems.Internal("Could not fetch instructor sentiment").Wrap(err)
+		}
+		for i, instructor := range instructors {
+			expanded[i]["sentiment"] = sentiments[instructor.InstructorID]
+		}
+	}
+	return expanded, nil
+}
+
+// instructorProjection reads ?fields= and ?expand= of an instructor read, where
+// expand=courses is the same as include=courses
+func instructorProjection(query url.Values, includeCourses bool) (projection, error) {
+	p, err := projectionParams(query, store.Instructor{}, instructorExpansions...)
+	if err != nil {
+		return p, err
+	}
+	if includeCourses {
+		if p.expand == nil {
+			p.expand = map[string]bool{}
+		}
+		p.expand["courses"] = true
+	}
+	return p, nil
+}
+
+// includeCoursesParam reads the include parameter, where courses is the only option
+func includeCoursesParam(value string) (bool, error) {
+	include := false
+	for _, option := range strings.Split(value, ",") {
+		switch strings.TrimSpace(option) {
+		case "":
+		case "courses":
+			include = true
+		default:
+			return false, fmt.Errorf("unknown include option %q", option)
+		}
+	}
+	return include, nil
+}
+
+func (h *InstructorHandler) GetInstructorAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructorID, err := strconv.ParseUint(chi.URLParam(r, "instructorId"), 10, 32)
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid instructor ID"))
+		return
+	}
+
+	instructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), strconv.FormatUint(instructorID, 10))
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+
+	// 🔹 Weighted scores per semester and question
+	scores, err := h.Store.Results.GetInstructorSemesterScores(r.Context(), uint(instructorID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load instructor scores").Wrap(err))
+		return
+	}
+
+	// 🔹 Department-wide scores for comparison
+	departments, err := h.Store.Results.GetInstructorDepartments(r.Context(), uint(instructorID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load instructor departments").Wrap(err))
+		return
+	}
+	departmentMeans, err := h.Store.Results.GetDepartmentQuestionMeans(r.Context(), departments)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load department scores").Wrap(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(analytics.BuildInstructorReport(instructor, departments, scores, departmentMeans))
+}
+
+func (h *InstructorHandler) GetInstructorSentimentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructorID, err := strconv.ParseUint(chi.URLParam(r, "instructorId"), 10, 32)
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid instructor ID"))
+		return
+	}
+
+	if _, err := h.Store.Instructors.GetInstructorByID(r.Context(), strconv.FormatUint(instructorID, 10)); err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+
+	summary, err := h.Store.Sentiments.GetInstructorSummary(r.Context(), uint(instructorID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load instructor sentiment").Wrap(err))
+		return
+	}
+	themes, err := h.Store.Sentiments.GetInstructorThemes(r.Context(), uint(instructorID))
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not load instructor themes").Wrap(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"instructor_id": instructorID,
+		"summary":       summary,
+		"themes":        themes,
+	})
 }
 
 func (h *InstructorHandler) UpdateInstructorHandler(w http.ResponseWriter, r *http.Request) {
@@ -70,17 +366,39 @@ func (h *InstructorHandler) UpdateInstructorHandler(w http.ResponseWriter, r *ht
 
 	instructorID := chi.URLParam(r, "instructorId")
 
-	var updateData store.Instructor
+	instructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), instructorID)
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+	if !checkIfMatch(w, r, instructor.Version) {
+		return
+	}
+
+	// ✅ Decode the update over the instructor, keeping the columns the service manages
+	updateData := *instructor
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	updateData.InstructorID, updateData.UserID, updateData.DateCreated, updateData.Version = instructor.InstructorID, instructor.UserID, instructor.DateCreated, instructor.Version
+	updateData.Courses, updateData.Relevance = nil, nil
+	if strings.TrimSpace(updateData.Name) == "" {
+		problems.Write(w, r, problems.Invalid("Invalid instructor", problems.FieldError{Field: "name", Message: "is required"}))
 		return
 	}
 
-	if err := h.Store.Instructors.UpdateInstructor(r.Context(), instructorID, &updateData); err != nil {
-		http.Error(w, "Could not update instructor", http.StatusInternalServerError)
+	err = h.Store.Instructors.UpdateInstructor(r.Context(), instructorID, instructor.Version, &updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update instructor").Wrap(err))
 		return
 	}
 
+	writeValidators(w, updateData.Version, updateData.DateUpdated)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updateData)
 }
@@ -93,13 +411,21 @@ func (h *InstructorHandler) DeleteInstructorHandler(w http.ResponseWriter, r *ht
 	// ✅ Check if the instructor exists before deleting
 	instructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), instructorID)
 	if err != nil {
-		http.Error(w, `{"error": "Instructor not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+	if !checkIfMatch(w, r, instructor.Version) {
 		return
 	}
 
 	// ✅ Proceed with deletion if the instructor exists
-	if err := h.Store.Instructors.DeleteInstructor(r.Context(), instructorID); err != nil {
-		http.Error(w, `{"error": "Could not delete instructor"}`, http.StatusInternalServerError)
+	err = h.Store.Instructors.DeleteInstructor(r.Context(), instructorID, instructor.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete instructor").Wrap(err))
 		return
 	}
 
@@ -120,29 +446,44 @@ func (h *InstructorHandler) PatchInstructorHandler(w http.ResponseWriter, r *htt
 	// ✅ Check if the instructor exists
 	existingInstructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), instructorID)
 	if err != nil {
-		http.Error(w, `{"error": "Instructor not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
 		return
 	}
-
-	// ✅ Parse JSON request body
-	var updateData store.Instructor
-	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+	if !checkIfMatch(w, r, existingInstructor.Version) {
 		return
 	}
 
-	// ✅ Perform partial update only for provided fields
-	if updateData.Name != "" {
-		existingInstructor.Name = updateData.Name
+	// ✅ Merge the patch into the instructor and validate the result
+	members, err := readPatch(r, existingInstructor)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	version := existingInstructor.Version
+	updateData, fieldErrors := applyPatch(existingInstructor, members, instructorMutableFields)
+	if len(fieldErrors) == 0 && strings.TrimSpace(existingInstructor.Name) == "" {
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: "name", Message: "is required"})
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid instructor patch", fieldErrors...))
+		return
 	}
 
 	// ✅ Call the update function
-	if err := h.Store.Instructors.UpdateInstructor(r.Context(), instructorID, existingInstructor); err != nil {
-		http.Error(w, `{"error": "Could not update instructor"}`, http.StatusInternalServerError)
+	err = h.Store.Instructors.PatchInstructor(r.Context(), instructorID, version, updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update instructor").Wrap(err))
 		return
 	}
 
 	// ✅ Return updated instructor
+	existingInstructor.Version = version + 1
+	existingInstructor.DateUpdated = updateData["date_updated"].(time.Time)
+	writeValidators(w, existingInstructor.Version, existingInstructor.DateUpdated)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingInstructor)
 }
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// instructorSortsV2 are the sort orders of the v2 instructor listing, named after the response members
+var instructorSortsV2 = sortOptions{
+	{"name", store.InstructorSortName},
+	{"created_at", store.InstructorSortDateCreated},
+	{"relevance", store.InstructorSortRelevance},
+}
+
+func (h *InstructorHandler) ListInstructorsV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := instructorListFilter(r.URL.Query(), instructorSortsV2)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	list, err := h.Store.Instructors.ListInstructors(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch instructors").Wrap(err))
+		return
+	}
+
+	writePageHeaders(w, r, list.Total, list.Next)
+	json.NewEncoder(w).Encode(dto.NewInstructors(list.Instructors, filter.IncludeCourses))
+}
+
+func (h *InstructorHandler) GetInstructorV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructorID, err := idParam(r, "instructorId", "instructor")
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	includeCourses, err := includeCoursesParam(r.URL.Query().Get("include"))
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest(err.Error()))
+		return
+	}
+
+	var instructor *store.Instructor
+	var age time.Duration
+	if includeCourses {
+		instructor, err = h.Store.Instructors.GetInstructorWithCourses(r.Context(), strconv.FormatUint(uint64(instructorID), 10))
+	} else {
+		instructor, age, err = h.Store.Instructors.GetInstructorByIDCached(r.Context(), strconv.FormatUint(uint64(instructorID), 10))
+	}
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return
+	}
+
+	// 🔹 The version only covers the instructor, so embedded courses are never cached
+	if !includeCourses {
+		writeCacheHeaders(w, h.Store.CacheTTL(), age)
+		writeValidators(w, instructor.Version, instructor.DateUpdated)
+		if notModified(w, r, instructor.Version, instructor.DateUpdated) {
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(dto.NewInstructor(instructor, includeCourses))
+}
+
+func (h *InstructorHandler) CreateInstructorV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 🔹 Authenticate the user the instructor is linked to
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	var req dto.InstructorRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		problems.Write(w, r, problems.Invalid("Invalid instructor", problems.FieldError{Field: "name", Message: "is required"}))
+		return
+	}
+
+	instructor := &store.Instructor{}
+	req.Apply(instructor)
+	if err := h.Store.Instructors.CreateInstructor(r.Context(), user.Username, instructor); err != nil {
+		problems.Write(w, r, problems.Internal("Could not create instructor").Wrap(err))
+		return
+	}
+
+	writeCreated(w, fmt.Sprintf("/v2/instructors/%d", instructor.InstructorID), instructor.Version, dto.NewInstructor(instructor, false))
+}
+
+func (h *InstructorHandler) UpdateInstructorV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructor, ok := h.instructorForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.InstructorRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	h.saveInstructorV2(w, r, instructor, req, req.Columns())
+}
+
+func (h *InstructorHandler) PatchInstructorV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructor, ok := h.instructorForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	req := dto.NewInstructorRequest(instructor)
+	members, err := readPatch(r, req)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	changes, fieldErrors := applyPatch(&req, members, dto.InstructorRequestFields)
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid instructor patch", fieldErrors...))
+		return
+	}
+	h.saveInstructorV2(w, r, instructor, req, changes)
+}
+
+func (h *InstructorHandler) DeleteInstructorV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	instructor, ok := h.instructorForWriteV2(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Store.Instructors.DeleteInstructor(r.Context(), strconv.FormatUint(uint64(instructor.InstructorID), 10), instructor.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete instructor").Wrap(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// instructorForWriteV2 loads the instructor a write targets after checking,
+// with If-Match, that the caller still has its current version
+func (h *InstructorHandler) instructorForWriteV2(w http.ResponseWriter, r *http.Request) (*store.Instructor, bool) {
+	if _, err := basicAuthUser(r, h.Store.Users); err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+	instructorID, err := idParam(r, "instructorId", "instructor")
+	if err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+
+	instructor, err := h.Store.Instructors.GetInstructorByID(r.Context(), strconv.FormatUint(uint64(instructorID), 10))
+	if err != nil {
+		problems.Write(w, r, problems.NotFound("Instructor not found").Wrap(err))
+		return nil, false
+	}
+	return instructor, checkIfMatch(w, r, instructor.Version)
+}
+
+// saveInstructorV2 validates the instructor as req leaves it, writes the
+// changed columns and answers with the updated instructor
+func (h *InstructorHandler) saveInstructorV2(w http.ResponseWriter, r *http.Request, instructor *store.Instructor, req dto.InstructorRequest, changes map[string]interface{}) {
+	req.Apply(instructor)
+	if strings.TrimSpace(instructor.Name) == "" {
+		problems.Write(w, r, problems.Invalid("Invalid instructor", problems.FieldError{Field: "name", Message: "is required"}))
+		return
+	}
+
+	err := h.Store.Instructors.PatchInstructor(r.Context(), strconv.FormatUint(uint64(instructor.InstructorID), 10), instructor.Version, changes)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update instructor").Wrap(err))
+		return
+	}
+
+	instructor.Version++
+	instructor.DateUpdated = changes["date_updated"].(time.Time)
+	writeValidators(w, instructor.Version, instructor.DateUpdated)
+	json.NewEncoder(w).Encode(dto.NewInstructor(instructor, false))
+}
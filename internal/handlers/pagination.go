@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// writePageHeaders sets the Link and X-Total-Count headers of a cursor-paginated list
+func writePageHeaders(w http.ResponseWriter, r *http.Request, total int64, next *store.Cursor) {
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(r, ""))}
+	if next != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(r, next.Encode())))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+}
+
+// pageURL returns the request URL with its cursor replaced
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	page := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return page.String()
+}
+
+// sortParam splits a sort parameter such as "-name" into its key and direction
+func sortParam(value, fallback string) (string, bool) {
+	if value == "" {
+		value = fallback
+	}
+	return strings.TrimPrefix(value, "-"), strings.HasPrefix(value, "-")
+}
+
+// sortOption maps a sort name of the API to the store's sort key
+type sortOption struct {
+	name string
+	key  string
+}
+
+// sortOptions are the sort orders a listing accepts, in documentation order
+type sortOptions []sortOption
+
+// key returns the store's sort key for name, or "" if the listing does not offer it
+func (options sortOptions) key(name string) string {
+	for _, option := range options {
+		if option.name == name {
+			return option.key
+		}
+	}
+	return ""
+}
+
+// String lists the sort names for error messages, as in "code, name or date_added"
+func (options sortOptions) String() string {
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = option.name
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// cursorParam decodes an optional cursor, which must belong to the requested sort
+func cursorParam(token, sort string, desc bool) (*store.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	cursor, err := store.DecodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, store.ErrInvalidCursor
+	}
+	return cursor, nil
+}
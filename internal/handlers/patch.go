@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// jsonPatchOp is one operation of an RFC 6902 JSON Patch document
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// readPatch decodes a PATCH body into the members it sets, where null clears a
+// member. RFC 7396 merge patches (also accepted as application/json) are used as
+// they are; RFC 6902 JSON Patch documents are applied to current, which must be
+// a flat resource, and reduced to the members they change.
+func readPatch(r *http.Request, current interface{}) (map[string]json.RawMessage, error) {
+	mediaType := mergePatchType
+	if header := r.Header.Get("Content-Type"); header != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(header); err != nil {
+			return nil, problems.BadRequest("Invalid Content-Type")
+		}
+	}
+
+	switch mediaType {
+	case mergePatchType, "application/json":
+		var members map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&members); err != nil || members == nil {
+			return nil, problems.BadRequest("A merge patch must be a JSON object")
+		}
+		return members, nil
+	case jsonPatchType:
+		var ops []jsonPatchOp
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			return nil, problems.BadRequest("A JSON Patch must be an array of operations")
+		}
+		return applyJSONPatch(current, ops)
+	default:
+		return nil, problems.New(http.StatusUnsupportedMediaType, fmt.Sprintf("PATCH accepts %s or %s", mergePatchType, jsonPatchType))
+	}
+}
+
+// applyJSONPatch runs the operations against the current document and returns
+// the members whose value changed, with null for removed ones
+func applyJSONPatch(current interface{}, ops []jsonPatchOp) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var original, document map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(raw, &document)
+
+	for i, op := range ops {
+		member, ok := pointerMember(op.Path)
+		if !ok {
+			return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].path", i), Message: "must point at a top-level member such as /name"})
+		}
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].value", i), Message: "is required"})
+			}
+			if _, exists := document[member]; op.Op == "replace" && !exists {
+				return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].path", i), Message: "does not exist"})
+			}
+			document[member] = op.Value
+		case "remove":
+			if _, exists := document[member]; !exists {
+				return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].path", i), Message: "does not exist"})
+			}
+			delete(document, member)
+		case "copy", "move":
+			from, ok := pointerMember(op.From)
+			value, exists := document[from]
+			if !ok || !exists {
+				return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].from", i), Message: "does not exist"})
+			}
+			if op.Op == "move" {
+				delete(document, from)
+			}
+			document[member] = value
+		case "test":
+			if !jsonEqual(document[member], op.Value) {
+				return nil, problems.Conflict(fmt.Sprintf("JSON Patch test failed for %s", op.Path))
+			}
+		default:
+			return nil, problems.Invalid("Invalid JSON Patch", problems.FieldError{Field: fmt.Sprintf("[%d].op", i), Message: "must be add, remove, replace, move, copy or test"})
+		}
+	}
+
+	members := map[string]json.RawMessage{}
+	for name, value := range document {
+		if !jsonEqual(original[name], value) {
+			members[name] = value
+		}
+	}
+	for name := range original {
+		if _, kept := document[name]; !kept {
+			members[name] = json.RawMessage("null")
+		}
+	}
+	return members, nil
+}
+
+// pointerMember resolves a JSON pointer of a single reference token
+func pointerMember(pointer string) (string, bool) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", false
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), true
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var left, right interface{}
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// applyPatch sets the patched members on target, a pointer to a resource whose
+// JSON names match its columns. Only members in mutable may change; null resets
+// one to its zero value. It returns the changed columns for the store.
+func applyPatch(target interface{}, members map[string]json.RawMessage, mutable []string) (map[string]interface{}, []problems.FieldError) {
+	value := reflect.ValueOf(target).Elem()
+	fields := map[string]reflect.Value{}
+	for i := 0; i < value.NumField(); i++ {
+		if name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			fields[name] = value.Field(i)
+		}
+	}
+	allowed := map[string]bool{}
+	for _, name := range mutable {
+		allowed[name] = true
+	}
+
+	changes := map[string]interface{}{}
+	var errs []problems.FieldError
+	for name, raw := range members {
+		field, known := fields[name]
+		switch {
+		case !known:
+			errs = append(errs, problems.FieldError{Field: name, Message: "is not a known field"})
+			continue
+		case !allowed[name]:
+			errs = append(errs, problems.FieldError{Field: name, Message: "is read-only"})
+			continue
+		}
+
+		updated := reflect.New(field.Type())
+		if string(raw) != "null" {
+			if err := json.Unmarshal(raw, updated.Interface()); err != nil {
+				errs = append(errs, problems.FieldError{Field: name, Message: "must be " + kindName(field.Kind())})
+				continue
+			}
+		}
+		field.Set(updated.Elem())
+		changes[name] = updated.Elem().Interface()
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return changes, errs
+}
+
+// kindName describes the JSON value a field of the given kind accepts
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	default:
+		return "a valid value"
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/csye7125/team01/internal/problems"
+)
+
+type patchedResource struct {
+	Name        string  `json:"name"`
+	Code        string  `json:"code"`
+	CreditHours int     `json:"credit_hours"`
+	Description *string `json:"description"`
+}
+
+func testDocument() patchedResource {
+	description := "Cloud systems"
+	return patchedResource{Name: "Network Structures", Code: "CSYE7125", CreditHours: 4, Description: &description}
+}
+
+func patchOps(t *testing.T, document string) []jsonPatchOp {
+	t.Helper()
+	var ops []jsonPatchOp
+	if err := json.Unmarshal([]byte(document), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func sameMembers(got map[string]json.RawMessage, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for name, value := range want {
+		if raw, ok := got[name]; !ok || !jsonEqual(raw, json.RawMessage(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  string
+		want map[string]string
+	}{
+		{
+			name: "replace",
+			ops:  `[{"op": "replace", "path": "/name", "value": "Advanced Cloud"}]`,
+			want: map[string]string{"name": `"Advanced Cloud"`},
+		},
+		{
+			name: "remove becomes null",
+			ops:  `[{"op": "remove", "path": "/description"}]`,
+			want: map[string]string{"description": `null`},
+		},
+		{
+			name: "copy",
+			ops:  `[{"op": "copy", "from": "/code", "path": "/name"}]`,
+			want: map[string]string{"name": `"CSYE7125"`},
+		},
+		{
+			name: "move clears the source",
+			ops:  `[{"op": "move", "from": "/code", "path": "/name"}]`,
+			want: map[string]string{"name": `"CSYE7125"`, "code": `null`},
+		},
+		{
+			name: "passing test guards the change",
+			ops:  `[{"op": "test", "path": "/credit_hours", "value": 4}, {"op": "replace", "path": "/credit_hours", "value": 3}]`,
+			want: map[string]string{"credit_hours": `3`},
+		},
+		{
+			name: "unchanged values are left out",
+			ops:  `[{"op": "replace", "path": "/name", "value": "Network Structures"}, {"op": "add", "path": "/credit_hours", "value": 4.0}]`,
+			want: map[string]string{},
+		},
+		{
+			name: "later operations see earlier ones",
+			ops:  `[{"op": "replace", "path": "/name", "value": "Draft"}, {"op": "test", "path": "/name", "value": "Draft"}, {"op": "replace", "path": "/name", "value": "Final"}]`,
+			want: map[string]string{"name": `"Final"`},
+		},
+		{
+			name: "escaped pointer",
+			ops:  `[{"op": "add", "path": "/a~1b~0c", "value": 1}]`,
+			want: map[string]string{"a/b~c": `1`},
+		},
+	}
+	for _, tt := range tests {
+		got, err := applyJSONPatch(testDocument(), patchOps(t, tt.ops))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !sameMembers(got, tt.want) {
+			t.Errorf("%s: changed members %s, want %v", tt.name, mustJSON(got), tt.want)
+		}
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ops    string
+		status int
+		field  string
+	}{
+		{"failed test", `[{"op": "test", "path": "/credit_hours", "value": 3}]`, http.StatusConflict, ""},
+		{"nested path", `[{"op": "replace", "path": "/instructor/name", "value": "x"}]`, http.StatusBadRequest, "[0].path"},
+		{"relative path", `[{"op": "replace", "path": "name", "value": "x"}]`, http.StatusBadRequest, "[0].path"},
+		{"replace missing member", `[{"op": "replace", "path": "/missing", "value": 1}]`, http.StatusBadRequest, "[0].path"},
+		{"remove missing member", `[{"op": "remove", "path": "/missing"}]`, http.StatusBadRequest, "[0].path"},
+		{"value required", `[{"op": "add", "path": "/name"}]`, http.StatusBadRequest, "[0].value"},
+		{"missing from", `[{"op": "move", "from": "/missing", "path": "/name"}]`, http.StatusBadRequest, "[0].from"},
+		{"unknown op", `[{"op": "increment", "path": "/credit_hours"}]`, http.StatusBadRequest, "[0].op"},
+		{"second operation", `[{"op": "replace", "path": "/name", "value": "x"}, {"op": "bogus", "path": "/name"}]`, http.StatusBadRequest, "[1].op"},
+	}
+	for _, tt := range tests {
+		_, err := applyJSONPatch(testDocument(), patchOps(t, tt.ops))
+		var problem *problems.Error
+		if !errors.As(err, &problem) {
+			t.Errorf("%s: error %v, want a problem", tt.name, err)
+			continue
+		}
+		if problem.Status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, problem.Status, tt.status)
+		}
+		if tt.field != "" && (len(problem.Fields) != 1 || problem.Fields[0].Field != tt.field) {
+			t.Errorf("%s: fields %+v, want %s", tt.name, problem.Fields, tt.field)
+		}
+	}
+}
+
+func mustJSON(value interface{}) string {
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
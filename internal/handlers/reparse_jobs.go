@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/csye7125/team01/internal/parsers"
+)
+
+const (
+	// maxRunningReparses bounds the reparses running at once on a replica; each
+	// parses its traces one at a time
+	maxRunningReparses = 2
+	// keptReparseJobs is how many jobs, running or finished, a replica remembers
+	keptReparseJobs = 50
+)
+
+const (
+	reparseRunning  = "running"
+	reparseFinished = "finished"
+)
+
+// Outcomes of reparsing one trace
+const (
+	traceReparsed    = "reparsed"
+	traceUnsupported = "unsupported"
+	traceSkipped     = "skipped"
+	traceFailed      = "failed"
+)
+
+// reparseJob is one background reparse as GET /v1/admin/traces/reparse/{jobId}
+// reports it. Skipped traces were being parsed by another job at the time.
+type reparseJob struct {
+	ID           string       `json:"job_id"`
+	Parser       parsers.Info `json:"parser"`
+	State        string       `json:"state"`
+	Matched      int          `json:"matched"`
+	TraceIDs     []uint       `json:"trace_ids"`
+	Reparsed     int          `json:"reparsed"`
+	Unsupported  int          `json:"unsupported"`
+	Skipped      int          `json:"skipped"`
+	Failed       int          `json:"failed"`
+	DateStarted  time.Time    `json:"date_started"`
+	DateFinished *time.Time   `json:"date_finished"`
+}
+
+// reparseJobs tracks the reparses of this replica. Jobs live in memory, so
+// they are only found on the replica that runs them and lost on restart.
+type reparseJobs struct {
+	mu      sync.Mutex
+	running int
+	jobs    map[string]*reparseJob
+	order   []string
+}
+
+func newReparseJobs() *reparseJobs {
+	return &reparseJobs{jobs: map[string]*reparseJob{}}
+}
+
+// start registers a running job, or returns false when maxRunningReparses are
+// already running
+func (j *reparseJobs) start(parser parsers.Info, traceIDs []uint) (reparseJob, bool) {
+	random := make([]byte, 8)
+	rand.Read(random)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running >= maxRunningReparses {
+		return reparseJob{}, false
+	}
+	j.running++
+
+	job := &reparseJob{
+		ID:          hex.EncodeToString(random),
+		Parser:      parser,
+		State:       reparseRunning,
+		Matched:     len(traceIDs),
+		TraceIDs:    traceIDs,
+		DateStarted: time.Now(),
+	}
+	j.jobs[job.ID] = job
+	j.order = append(j.order, job.ID)
+
+	// 🔹 Forget the oldest finished jobs
+	for i := 0; len(j.order) > keptReparseJobs && i < len(j.order); {
+		if j.jobs[j.order[i]].State == reparseFinished {
+			delete(j.jobs, j.order[i])
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+	return *job, true
+}
+
+// record counts the outcome of one trace of a job
+func (j *reparseJobs) record(id, outcome string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.jobs[id]
+	switch outcome {
+	case traceReparsed:
+		job.Reparsed++
+	case traceUnsupported:
+		job.Unsupported++
+	case traceSkipped:
+		job.Skipped++
+	default:
+		job.Failed++
+	}
+}
+
+// finish marks a job finished and frees its slot
+func (j *reparseJobs) finish(id string) reparseJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	job := j.jobs[id]
+	job.State, job.DateFinished = reparseFinished, &now
+	j.running--
+	return *job
+}
+
+// get returns a copy of a job
+func (j *reparseJobs) get(id string) (reparseJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return reparseJob{}, false
+	}
+	return *job, true
+}
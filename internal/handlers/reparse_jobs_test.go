@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/csye7125/team01/internal/parsers"
+)
+
+func TestReparseJobsBoundRunningJobs(t *testing.T) {
+	jobs := newReparseJobs()
+	parser := parsers.Info{Name: "trace-pdf", Version: 2}
+
+	var started []string
+	for i := 0; i < maxRunningReparses; i++ {
+		job, ok := jobs.start(parser, []uint{1, 2})
+		if !ok {
+			t.Fatalf("job %d was refused", i)
+		}
+		started = append(started, job.ID)
+	}
+	if _, ok := jobs.start(parser, nil); ok {
+		t.Fatal("a job started beyond maxRunningReparses")
+	}
+
+	jobs.record(started[0], traceReparsed)
+	jobs.record(started[0], traceSkipped)
+	job := jobs.finish(started[0])
+	if job.State != reparseFinished || job.Reparsed != 1 || job.Skipped != 1 || job.DateFinished == nil {
+		t.Errorf("finished job = %+v", job)
+	}
+	if _, ok := jobs.start(parser, nil); !ok {
+		t.Error("a finished job kept its slot")
+	}
+	if got, ok := jobs.get(started[1]); !ok || got.State != reparseRunning {
+		t.Errorf("get() = %+v, %v, want the running job", got, ok)
+	}
+}
+
+func TestReparseJobsForgetOldFinishedJobs(t *testing.T) {
+	jobs := newReparseJobs()
+	first, _ := jobs.start(parsers.Info{}, nil)
+	jobs.finish(first.ID)
+	for i := 0; i < keptReparseJobs; i++ {
+		job, _ := jobs.start(parsers.Info{}, nil)
+		jobs.finish(job.ID)
+	}
+	if _, ok := jobs.get(first.ID); ok {
+		t.Error("the oldest finished job was kept")
+	}
+	if len(jobs.jobs) != keptReparseJobs {
+		t.Errorf("kept %d jobs, want %d", len(jobs.jobs), keptReparseJobs)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+)
+
+type SearchHandler struct {
+	Store *store.Storage
+}
+
+func NewSearchHandler(store *store.Storage) *SearchHandler {
+	return &SearchHandler{Store: store}
+}
+
+func (h *SearchHandler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		problems.Write(w, r, problems.BadRequest("Missing search query q"))
+		return
+	}
+
+	// 🔹 Pagination
+	page, err := intParam(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		problems.Write(w, r, problems.BadRequest("Invalid page"))
+		return
+	}
+	pageSize, err := intParam(query.Get("page_size"), defaultSearchPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxSearchPageSize {
+		problems.Write(w, r, problems.BadRequest(fmt.Sprintf("page_size must be between 1 and %d", maxSearchPageSize)))
+		return
+	}
+
+	filter := store.SearchFilter{
+		Query:        q,
+		SemesterTerm: query.Get("semester_term"),
+		Limit:        pageSize,
+		Offset:       (page - 1) * pageSize,
+	}
+
+	// 🔹 Optional filters
+	if kinds := query.Get("type"); kinds != "" {
+		for _, kind := range strings.Split(kinds, ",") {
+			switch kind {
+			case store.SearchKindCourse, store.SearchKindInstructor, store.SearchKindComment:
+				filter.Kinds = append(filter.Kinds, kind)
+			default:
+				problems.Write(w, r, problems.BadRequest(fmt.Sprintf("Unknown result type %q", kind)))
+				return
+			}
+		}
+	}
+	if filter.SemesterYear, err = intParam(query.Get("semester_year"), 0); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid semester_year"))
+		return
+	}
+	instructorID, err := intParam(query.Get("instructor_id"), 0)
+	if err != nil || instructorID < 0 {
+		problems.Write(w, r, problems.BadRequest("Invalid instructor_id"))
+		return
+	}
+	filter.InstructorID = uint(instructorID)
+
+	result, err := h.Store.Search.Search(r.Context(), filter)
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Search failed").Wrap(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":     q,
+		"results":   result.Hits,
+		"facets":    result.Facets,
+		"total":     result.Total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// intParam parses an optional integer query parameter
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -4,10 +4,15 @@ import (
 	"cloud.google.com/go/storage"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/csye7125/team01/internal/ingest"
+	"github.com/csye7125/team01/internal/parsers"
+	"github.com/csye7125/team01/internal/problems"
 	"github.com/csye7125/team01/internal/store"
 	"github.com/go-chi/chi/v5"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"strconv"
@@ -18,12 +23,23 @@ import (
 type TraceHandler struct {
 	Store      *store.Storage
 	BucketName string
+	Pipeline   *parsers.Pipeline
+	Reparses   *reparseJobs
 }
 
-func NewTraceHandler(store *store.Storage, bucketName string) *TraceHandler {
+const (
+	defaultTracePageSize = 25
+	maxTracePageSize     = 100
+	defaultReparseLimit  = 50
+	maxReparseLimit      = 200
+)
+
+func NewTraceHandler(store *store.Storage, bucketName string, pipeline *parsers.Pipeline) *TraceHandler {
 	return &TraceHandler{
 		Store:      store,
 		BucketName: bucketName,
+		Pipeline:   pipeline,
+		Reparses:   newReparseJobs(),
 	}
 }
 
@@ -33,27 +49,178 @@ func (h *TraceHandler) GetTraceHandler(w http.ResponseWriter, r *http.Request) {
 	courseID := chi.URLParam(r, "course_id")
 	traceID := chi.URLParam(r, "trace_id")
 
+	p, err := projectionParams(r.URL.Query(), store.Trace{}, "course")
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
 	trace, err := h.Store.Traces.GetTraceByID(r.Context(), courseID, traceID)
 	if err != nil {
-		http.Error(w, `{"error": "Trace not found"}`, http.StatusNotFound)
+		problems.Write(w, r, problems.NotFound("Trace not found").Wrap(err))
 		return
 	}
 
-	json.NewEncoder(w).Encode(trace)
+	if !p.active() {
+		json.NewEncoder(w).Encode(trace)
+		return
+	}
+	expanded, err := h.expandTraces(r, p, []store.Trace{*trace})
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	rendered, err := p.render(trace, expanded[0])
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not encode trace").Wrap(err))
+		return
+	}
+	json.NewEncoder(w).Encode(rendered)
 }
 
 func (h *TraceHandler) GetAllTracesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
 
-	courseID := chi.URLParam(r, "course_id")
+	courseID, err := strconv.ParseUint(chi.URLParam(r, "course_id"), 10, 32)
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.TraceListFilter{CourseID: uint(courseID), FileNamePrefix: query.Get("file_name_prefix")}
+	p, err := projectionParams(query, store.Trace{}, "course")
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+
+	// 🔹 Page size and sort order, newest first by default
+	if filter.Limit, err = intParam(query.Get("limit"), defaultTracePageSize); err != nil || filter.Limit < 1 || filter.Limit > maxTracePageSize {
+		problems.Write(w, r, problems.BadRequest(fmt.Sprintf("limit must be between 1 and %d", maxTracePageSize)))
+		return
+	}
+	filter.Sort, filter.Desc = sortParam(query.Get("sort"), "-"+store.TraceSortDateCreated)
+	if filter.Sort != store.TraceSortDateCreated && filter.Sort != store.TraceSortFileName {
+		problems.Write(w, r, problems.BadRequest("sort must be date_created or file_name, optionally prefixed with -"))
+		return
+	}
+
+	// 🔹 Cursors only continue the sort they were issued for
+	if filter.After, err = cursorParam(query.Get("cursor"), filter.Sort, filter.Desc); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+
+	// 🔹 Optional filters
+	userID, err := intParam(query.Get("user_id"), 0)
+	if err != nil || userID < 0 {
+		problems.Write(w, r, problems.BadRequest("Invalid user_id"))
+		return
+	}
+	filter.UserID = uint(userID)
+	if filter.CreatedFrom, err = timeParam(query.Get("created_from"), false); err != nil {
+		problems.Write(w, r, problems.BadRequest("created_from must be an RFC 3339 timestamp or YYYY-MM-DD date"))
+		return
+	}
+	if filter.CreatedTo, err = timeParam(query.Get("created_to"), true); err != nil {
+		problems.Write(w, r, problems.BadRequest("created_to must be an RFC 3339 timestamp or YYYY-MM-DD date"))
+		return
+	}
+
+	// 🔹 CSV and NDJSON stream every matching trace instead of one page
+	if format := exportFormat(r); format != "" {
+		export, err := newExport(w, format, "traces", p, store.Trace{}, "course")
+		if err != nil {
+			problems.Write(w, r, err)
+			return
+		}
+		export.finish(r, h.Store.Traces.StreamTraces(r.Context(), filter, func(trace *store.Trace) error {
+			return export.write(trace)
+		}))
+		return
+	}
+
+	list, err := h.Store.Traces.ListTraces(r.Context(), filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		problems.Write(w, r, problems.BadRequest("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch traces").Wrap(err))
+		return
+	}
+
+	// 🔹 Paging metadata travels in headers so the body stays a plain list
+	writePageHeaders(w, r, list.Total, list.Next)
+
+	if list.Traces == nil {
+		list.Traces = []store.Trace{}
+	}
+	if !p.active() {
+		json.NewEncoder(w).Encode(list.Traces)
+		return
+	}
 
-	traces, err := h.Store.Traces.GetTracesByCourseID(r.Context(), courseID)
+	expanded, err := h.expandTraces(r, p, list.Traces)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	items := make([]interface{}, len(list.Traces))
+	for i := range list.Traces {
+		items[i] = list.Traces[i]
+	}
+	rendered, err := p.renderAll(items, expanded)
 	if err != nil {
-		http.Error(w, `{"error": "Could not fetch traces"}`, http.StatusInternalServerError)
+		problems.Write(w, r, problems.Internal("Could not encode traces").Wrap(err))
 		return
 	}
+	json.NewEncoder(w).Encode(rendered)
+}
+
+// expandTraces loads the courses of traces in one query when expand=course is asked for
+func (h *TraceHandler) expandTraces(r *http.Request, p projection, traces []store.Trace) ([]map[string]interface{}, error) {
+	expanded := make([]map[string]interface{}, len(traces))
+	if !p.expands("course") || len(traces) == 0 {
+		return expanded, nil
+	}
 
-	json.NewEncoder(w).Encode(traces)
+	courseIDs := make([]uint, len(traces))
+	for i, trace := range traces {
+		courseIDs[i] = trace.CourseID
+	}
+	courses, err := h.Store.Courses.GetCoursesByIDs(r.Context(), courseIDs)
+	if err != nil {
+		return nil, problems.Internal("Could not fetch courses").Wrap(err)
+	}
+	byID := make(map[uint]*store.Course, len(courses))
+	for i := range courses {
+		byID[courses[i].ID] = &courses[i]
+	}
+	for i, trace := range traces {
+		expanded[i] = map[string]interface{}{"course": byID[trace.CourseID]}
+	}
+	return expanded, nil
+}
+
+// timeParam parses an optional RFC 3339 timestamp or date; an end date covers the whole day
+func timeParam(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
 }
 
 func extractFileNameFromURL(url string) string {
@@ -70,27 +237,25 @@ func (h *TraceHandler) DeleteTraceHandler(w http.ResponseWriter, r *http.Request
 	// ✅ Step 1: Check if the trace exists
 	trace, err := h.Store.Traces.GetTraceByID(r.Context(), courseID, traceID)
 	if err != nil {
-		http.Error(w, `{"error": "Trace not found"}`, http.StatusNotFound)
-		return
-	}
-
-	// ✅ Step 2: Extract file path from GCS URL
-	fileName := extractFileNameFromURL(trace.BucketPath)
-
-	// ✅ Step 3: Delete file from GCS
-	err = h.deleteFileFromGCS(r.Context(), fileName)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to delete file from GCS"}`, http.StatusInternalServerError)
+		problems.Write(w, r, problems.NotFound("Trace not found").Wrap(err))
 		return
 	}
 
-	// ✅ Step 4: Delete trace from database
+	// ✅ Step 2: Delete trace from database
 	if err := h.Store.Traces.DeleteTrace(r.Context(), courseID, traceID); err != nil {
-		http.Error(w, `{"error": "Could not delete trace from database"}`, http.StatusInternalServerError)
+		problems.Write(w, r, problems.Internal("Could not delete trace from database").Wrap(err))
 		return
 	}
 
-	// ✅ Step 5: Respond with success
+	// ✅ Step 3: Delete file from GCS once the deletion is committed; a file left behind only costs storage
+	fileName := extractFileNameFromURL(trace.BucketPath)
+	store.AfterCommit(r.Context(), func() {
+		if err := h.deleteFileFromGCS(context.WithoutCancel(r.Context()), fileName); err != nil {
+			log.Printf("❌ Could not delete %s of trace %d from GCS: %v", fileName, trace.TraceID, err)
+		}
+	})
+
+	// ✅ Step 4: Respond with success
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -100,58 +265,132 @@ func (h *TraceHandler) UploadTraceHandler(w http.ResponseWriter, r *http.Request
 	courseIDStr := chi.URLParam(r, "course_id")
 	courseID, err := strconv.ParseUint(courseIDStr, 10, 32)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid course ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid course ID"))
 		return
 	}
 
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized: Missing Basic Auth"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized: Missing Basic Auth"))
 		return
 	}
 
 	user, err := h.Store.Users.GetUserByCredentials(r.Context(), username, password)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	err = r.ParseMultipartForm(50 << 20) // support larger payload
 	if err != nil {
-		http.Error(w, `{"error": "Failed to parse multipart form"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Failed to parse multipart form"))
 		return
 	}
 
 	formFiles := r.MultipartForm.File["files"] // same key as Postman
 
-	var uploadedTraces []*store.Trace
+	// 🔹 Spreadsheets are mapped through a column profile, "default" unless the form names one
+	profiles, err := ingest.Profiles()
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Column profiles are misconfigured").Wrap(err))
+		return
+	}
+	profileName := r.FormValue("profile")
+	if profileName == "" {
+		profileName = ingest.DefaultProfileName
+	}
+	profile, ok := profiles[profileName]
+	if !ok {
+		problems.Write(w, r, problems.BadRequest(fmt.Sprintf("Unknown column profile %q", profileName)))
+		return
+	}
+
+	// 🔹 Parse every file before anything is uploaded, so invalid spreadsheets reject the whole request
+	type preparedFile struct {
+		header   *multipart.FileHeader
+		file     multipart.File
+		parser   parsers.Parser
+		parsed   *parsers.Parsed
+		parseErr error
+	}
+	type fileErrors struct {
+		FileName  string            `json:"file_name"`
+		RowErrors []ingest.RowError `json:"row_errors"`
+	}
+	var prepared []preparedFile
+	var invalid []fileErrors
 
 	for _, fileHeader := range formFiles {
 		file, err := fileHeader.Open()
 		if err != nil {
-			http.Error(w, `{"error": "Failed to open file"}`, http.StatusInternalServerError)
+			problems.Write(w, r, problems.Internal("Failed to open file").Wrap(err))
 			return
 		}
 		defer file.Close()
 
-		uniqueFilename := fileHeader.Filename
+		data, err := io.ReadAll(file)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Failed to read file").Wrap(err))
+			return
+		}
+		doc, err := parsers.NewDocument(fileHeader.Filename, data, profile)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Failed to read file").Wrap(err))
+			return
+		}
+
+		parser, parsed, err := h.Pipeline.Parse(r.Context(), doc)
+		var validationErr *parsers.ValidationError
+		if errors.As(err, &validationErr) {
+			invalid = append(invalid, fileErrors{FileName: fileHeader.Filename, RowErrors: validationErr.RowErrors})
+			continue
+		}
+		prepared = append(prepared, preparedFile{header: fileHeader, file: file, parser: parser, parsed: parsed, parseErr: err})
+	}
+
+	if len(invalid) > 0 {
+		problems.Write(w, r, problems.Unprocessable("Spreadsheet validation failed").With("files", invalid))
+		return
+	}
+
+	var uploadedTraces []*store.Trace
+
+	for _, entry := range prepared {
+		if _, err := entry.file.Seek(0, io.SeekStart); err != nil {
+			problems.Write(w, r, problems.Internal("Failed to read file").Wrap(err))
+			return
+		}
+
+		uniqueFilename := entry.header.Filename
 
-		gcsURL, err := h.uploadFileToGCS(r.Context(), file, uniqueFilename)
+		gcsURL, err := h.uploadFileToGCS(r.Context(), entry.file, uniqueFilename)
 		if err != nil {
-			http.Error(w, `{"error": "Failed to upload file to GCS"}`, http.StatusInternalServerError)
+			problems.Write(w, r, problems.Internal("Failed to upload file to GCS").Wrap(err))
 			return
 		}
 
 		trace := &store.Trace{
-			CourseID:    uint(courseID),
-			UserID:      user.ID,
-			FileName:    fileHeader.Filename,
-			BucketPath:  gcsURL,
-			DateCreated: time.Now(),
+			CourseID:     uint(courseID),
+			UserID:       user.ID,
+			FileName:     entry.header.Filename,
+			BucketPath:   gcsURL,
+			DateCreated:  time.Now(),
+			ParseProfile: profileName,
 		}
 
-		if err := h.Store.Traces.CreateTrace(r.Context(), trace); err != nil {
-			http.Error(w, `{"error": "Could not save trace metadata"}`, http.StatusInternalServerError)
+		// 🔹 The trace and what was parsed from it are saved together or not at all.
+		// A document that could not be parsed is still kept; the failure is recorded on the trace.
+		err = h.Store.WithTransaction(r.Context(), func(ctx context.Context) error {
+			if err := h.Store.Traces.CreateTrace(ctx, trace); err != nil {
+				return fmt.Errorf("failed to save trace metadata: %w", err)
+			}
+			if entry.parseErr != nil {
+				return h.Pipeline.RecordFailure(ctx, trace, entry.parser, entry.parseErr)
+			}
+			return h.Pipeline.Save(ctx, trace, entry.parser, entry.parsed)
+		})
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Could not save trace").Wrap(err))
 			return
 		}
 
@@ -162,6 +401,166 @@ func (h *TraceHandler) UploadTraceHandler(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(uploadedTraces)
 }
 
+type reparseRequest struct {
+	Parser          string `json:"parser"`
+	CourseID        uint   `json:"course_id"`
+	IncludeUnparsed bool   `json:"include_unparsed"`
+	Limit           int    `json:"limit"`
+}
+
+// ListParsersHandler lists the registered trace parsers
+func (h *TraceHandler) ListParsersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Pipeline.Registry.List())
+}
+
+// ReparseTracesHandler starts re-running the latest version of a parser over traces an older version handled.
+// The traces are reparsed in the background; each trace's parse status shows how it went.
+func (h *TraceHandler) ReparseTracesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req reparseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid request body"))
+		return
+	}
+	parser, ok := h.Pipeline.Registry.Latest(req.Parser)
+	if !ok {
+		problems.Write(w, r, problems.BadRequest("Unknown parser"))
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultReparseLimit
+	}
+	if req.Limit > maxReparseLimit {
+		req.Limit = maxReparseLimit
+	}
+
+	profiles, err := ingest.Profiles()
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Column profiles are misconfigured").Wrap(err))
+		return
+	}
+
+	traces, err := h.Store.Traces.GetTracesForReparse(r.Context(), store.ReparseFilter{
+		ParserName:      parser.Name(),
+		ParserVersion:   parser.Version(),
+		CourseID:        req.CourseID,
+		IncludeUnparsed: req.IncludeUnparsed,
+		Limit:           req.Limit,
+	})
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not fetch traces").Wrap(err))
+		return
+	}
+
+	// 🔹 Downloading and parsing can outlast the request, so the work carries on
+	// after the response, in one of a bounded number of jobs
+	traceIDs := make([]uint, len(traces))
+	for i := range traces {
+		traceIDs[i] = traces[i].TraceID
+	}
+	job, ok := h.Reparses.start(parsers.Info{Name: parser.Name(), Version: parser.Version()}, traceIDs)
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		problems.Write(w, r, problems.New(http.StatusServiceUnavailable, "Too many reparses are running; retry once one finishes"))
+		return
+	}
+	go h.reparse(context.WithoutCancel(r.Context()), job.ID, parser, profiles, traces)
+
+	w.Header().Set("Location", "/v1/admin/traces/reparse/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetReparseJobHandler reports the progress of a reparse this replica runs or ran
+func (h *TraceHandler) GetReparseJobHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := h.Reparses.get(chi.URLParam(r, "jobId"))
+	if !ok {
+		problems.Write(w, r, problems.NotFound("Reparse job not found"))
+		return
+	}
+	json.NewEncoder(w).Encode(job)
+}
+
+// reparse runs parser over each trace on its own; one bad document does not stop the rest
+func (h *TraceHandler) reparse(ctx context.Context, jobID string, parser parsers.Parser, profiles map[string]ingest.ColumnProfile, traces []store.Trace) {
+	for i := range traces {
+		trace := &traces[i]
+		outcome, err := h.reparseTrace(ctx, parser, profiles, trace)
+		if err != nil {
+			log.Printf("reparse of trace %d with %s v%d failed: %v", trace.TraceID, parser.Name(), parser.Version(), err)
+		}
+		h.Reparses.record(jobID, outcome)
+	}
+	job := h.Reparses.finish(jobID)
+	log.Printf("reparse %s with %s v%d finished: %d traces, %d reparsed, %d unsupported, %d skipped, %d failed",
+		job.ID, parser.Name(), parser.Version(), job.Matched, job.Reparsed, job.Unsupported, job.Skipped, job.Failed)
+}
+
+// reparseTrace runs parser over one trace while holding its parse lock, so
+// overlapping reparses never parse the same trace at once; a trace whose lock
+// is taken is skipped. Failures are recorded on the trace and also returned.
+func (h *TraceHandler) reparseTrace(ctx context.Context, parser parsers.Parser, profiles map[string]ingest.ColumnProfile, trace *store.Trace) (string, error) {
+	outcome := traceFailed
+	var cause error
+	err := h.Store.WithTransaction(ctx, func(ctx context.Context) error {
+		locked, err := h.Store.Traces.TryLockParse(ctx, trace.TraceID)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			outcome = traceSkipped
+			return nil
+		}
+
+		// 🔹 Spreadsheets are read with the profile they were uploaded with; older traces predate the column
+		profileName := trace.ParseProfile
+		if profileName == "" {
+			profileName = ingest.DefaultProfileName
+		}
+		profile, ok := profiles[profileName]
+		if !ok {
+			cause = fmt.Errorf("unknown column profile %q", profileName)
+			return h.Pipeline.RecordFailure(ctx, trace, parser, cause)
+		}
+
+		data, err := h.downloadFileFromGCS(ctx, extractFileNameFromURL(trace.BucketPath))
+		if err != nil {
+			cause = err
+			return nil
+		}
+		doc, err := parsers.NewDocument(trace.FileName, data, profile)
+		if err != nil {
+			cause = err
+			return nil
+		}
+
+		// 🔹 A trace the parser does not handle is marked so later reparses pass it over
+		if parser.Detect(doc) <= 0 {
+			outcome = traceUnsupported
+			return h.Pipeline.RecordUnsupported(ctx, trace, parser)
+		}
+
+		parsed, err := parser.Parse(ctx, doc)
+		if err != nil {
+			cause = err
+			return h.Pipeline.RecordFailure(ctx, trace, parser, err)
+		}
+		if err := h.Pipeline.Save(ctx, trace, parser, parsed); err != nil {
+			return err
+		}
+		outcome = traceReparsed
+		return nil
+	})
+	if err != nil {
+		return traceFailed, err
+	}
+	return outcome, cause
+}
+
 func (h *TraceHandler) uploadFileToGCS(ctx context.Context, file multipart.File, fileName string) (string, error) {
 	// Log the environment variable path
 
@@ -234,3 +633,23 @@ func (h *TraceHandler) deleteFileFromGCS(ctx context.Context, fileName string) e
 	fmt.Println("File deleted successfully from GCS:", fileName)
 	return nil
 }
+
+func (h *TraceHandler) downloadFileFromGCS(ctx context.Context, fileName string) ([]byte, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	reader, err := client.Bucket(h.BucketName).Object(fileName).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file in GCS: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from GCS: %w", err)
+	}
+	return data, nil
+}
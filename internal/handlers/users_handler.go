@@ -2,14 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
 	"github.com/csye7125/team01/internal/store"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+// userMutableFields are the user members clients may change with PATCH
+var userMutableFields = []string{"first_name", "last_name", "password"}
+
 type UserHandler struct {
 	Store *store.Storage
 }
@@ -23,16 +30,16 @@ func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request)
 
 	var user store.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
 		return
 	}
 
 	if err := h.Store.Users.CreateUser(r.Context(), &user); err != nil {
-		if err == gorm.ErrDuplicatedKey {
-			http.Error(w, `{"error": "User already exists"}`, http.StatusConflict)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			problems.Write(w, r, problems.Conflict("User already exists"))
 			return
 		}
-		http.Error(w, `{"error": "Could not create user"}`, http.StatusInternalServerError)
+		problems.Write(w, r, problems.Internal("Could not create user").Wrap(err))
 		return
 	}
 
@@ -46,20 +53,20 @@ func (h *UserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Extract Basic Auth credentials (username & password)
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized: Missing credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized: Missing credentials"))
 		return
 	}
 
 	// Step 2: Retrieve the user by username
 	user, err := h.Store.Users.GetUserByUsername(r.Context(), username)
 	if err != nil {
-		http.Error(w, `{"error": "Unauthorized: Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized: Invalid credentials"))
 		return
 	}
 
 	// Step 3: Validate password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		http.Error(w, `{"error": "Unauthorized: Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized: Invalid credentials"))
 		return
 	}
 
@@ -67,18 +74,22 @@ func (h *UserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "userId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid user ID"))
 		return
 	}
 
 	// Step 5: Ensure authenticated user can only access their own data
 	if user.ID != uint(id) {
-		http.Error(w, `{"error": "Forbidden: You can only access your own user data"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("Forbidden: You can only access your own user data"))
 		return
 	}
 
 	// Step 6: Remove password before returning user data
 	user.Password = ""
+	writeValidators(w, user.Version, user.AccountUpdated)
+	if notModified(w, r, user.Version, user.AccountUpdated) {
+		return
+	}
 
 	// Step 7: Return user JSON response
 	json.NewEncoder(w).Encode(user)
@@ -91,47 +102,135 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 	idStr := chi.URLParam(r, "userId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid user ID"))
 		return
 	}
 
 	// Authenticate user using Basic Auth
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Retrieve user from the database using the username
 	authUser, err := h.Store.Users.GetUserByUsername(r.Context(), username)
 	if err != nil || bcrypt.CompareHashAndPassword([]byte(authUser.Password), []byte(password)) != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// Ensure that the authenticated user can only update their own details
 	if authUser.ID != uint(id) {
-		http.Error(w, `{"error": "You are not authorized to update this user"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("You are not authorized to update this user"))
+		return
+	}
+	if !checkIfMatch(w, r, authUser.Version) {
 		return
 	}
 
-	// Decode request body into the update struct
-	var updateData store.User
+	// Decode the update over the user, keeping the columns the service manages
+	// and the current password unless a new one is given
+	updateData := *authUser
+	updateData.Password = ""
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, `{"error": "Invalid request payload"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid request payload"))
+		return
+	}
+	updateData.ID, updateData.Username, updateData.AccountCreated, updateData.Version = authUser.ID, authUser.Username, authUser.AccountCreated, authUser.Version
+	if fieldErrors := dto.ValidateUser(&updateData, nil); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid user", fieldErrors...))
 		return
 	}
 
 	// Perform the update
-	if err := h.Store.Users.UpdateUser(r.Context(), uint(id), &updateData); err != nil {
-		http.Error(w, `{"error": "Could not update user"}`, http.StatusInternalServerError)
+	err = h.Store.Users.UpdateUser(r.Context(), uint(id), authUser.Version, &updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update user").Wrap(err))
 		return
 	}
 
+	// Never echo the password hash back
+	updateData.Password = ""
+	writeValidators(w, updateData.Version, updateData.AccountUpdated)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updateData)
 }
 
+func (h *UserHandler) PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract user ID from the URL
+	idStr := chi.URLParam(r, "userId")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		problems.Write(w, r, problems.BadRequest("Invalid user ID"))
+		return
+	}
+
+	// Authenticate user using Basic Auth
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		problems.Write(w, r, problems.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Retrieve user from the database using the username
+	authUser, err := h.Store.Users.GetUserByUsername(r.Context(), username)
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(authUser.Password), []byte(password)) != nil {
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
+		return
+	}
+
+	// Ensure that the authenticated user can only update their own details
+	if authUser.ID != uint(id) {
+		problems.Write(w, r, problems.Forbidden("You are not authorized to update this user"))
+		return
+	}
+	if !checkIfMatch(w, r, authUser.Version) {
+		return
+	}
+
+	// Merge the patch into the user, whose password hash is never exposed, and validate the result
+	user := *authUser
+	user.Password = ""
+	members, err := readPatch(r, user)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	updateData, fieldErrors := applyPatch(&user, members, userMutableFields)
+	if len(fieldErrors) == 0 {
+		fieldErrors = dto.ValidateUser(&user, updateData)
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid user patch", fieldErrors...))
+		return
+	}
+
+	// Perform the update
+	err = h.Store.Users.PatchUser(r.Context(), uint(id), authUser.Version, updateData)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update user").Wrap(err))
+		return
+	}
+
+	user.Password = ""
+	user.Version = authUser.Version + 1
+	user.AccountUpdated = updateData["account_updated"].(time.Time)
+	writeValidators(w, user.Version, user.AccountUpdated)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
 func (h *UserHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -139,33 +238,41 @@ func (h *UserHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request)
 	idStr := chi.URLParam(r, "userId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+		problems.Write(w, r, problems.BadRequest("Invalid user ID"))
 		return
 	}
 
 	// Authenticate user using Basic Auth
 	username, password, ok := r.BasicAuth()
 	if !ok {
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Retrieve user from the database using the username
 	authUser, err := h.Store.Users.GetUserByUsername(r.Context(), username)
 	if err != nil || bcrypt.CompareHashAndPassword([]byte(authUser.Password), []byte(password)) != nil {
-		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+		problems.Write(w, r, problems.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// Ensure that the authenticated user can only delete their own account
 	if authUser.ID != uint(id) {
-		http.Error(w, `{"error": "You are not authorized to delete this user"}`, http.StatusForbidden)
+		problems.Write(w, r, problems.Forbidden("You are not authorized to delete this user"))
+		return
+	}
+	if !checkIfMatch(w, r, authUser.Version) {
 		return
 	}
 
 	// Perform deletion
-	if err := h.Store.Users.DeleteUser(r.Context(), uint(id)); err != nil {
-		http.Error(w, `{"error": "Could not delete user"}`, http.StatusInternalServerError)
+	err = h.Store.Users.DeleteUser(r.Context(), uint(id), authUser.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete user").Wrap(err))
 		return
 	}
 
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	"gorm.io/gorm"
+)
+
+func (h *UserHandler) CreateUserV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req dto.CreateUserRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	user := req.User()
+	fieldErrors := dto.ValidateUser(user, map[string]interface{}{"password": user.Password})
+	if strings.TrimSpace(user.Username) == "" {
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: "username", Message: "is required"})
+	}
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid user", fieldErrors...))
+		return
+	}
+
+	if err := h.Store.Users.CreateUser(r.Context(), user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			problems.Write(w, r, problems.Conflict("User already exists"))
+			return
+		}
+		problems.Write(w, r, problems.Internal("Could not create user").Wrap(err))
+		return
+	}
+
+	writeCreated(w, fmt.Sprintf("/v2/users/%d", user.ID), user.Version, dto.NewUser(user))
+}
+
+func (h *UserHandler) GetUserV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := h.selfV2(w, r)
+	if !ok {
+		return
+	}
+
+	writeValidators(w, user.Version, user.AccountUpdated)
+	if notModified(w, r, user.Version, user.AccountUpdated) {
+		return
+	}
+	json.NewEncoder(w).Encode(dto.NewUser(user))
+}
+
+func (h *UserHandler) UpdateUserV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := h.selfV2(w, r)
+	if !ok || !checkIfMatch(w, r, user.Version) {
+		return
+	}
+
+	var req dto.UserRequest
+	if err := decodeRequest(r, &req); err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	h.saveUserV2(w, r, user, req, req.Columns())
+}
+
+func (h *UserHandler) PatchUserV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := h.selfV2(w, r)
+	if !ok || !checkIfMatch(w, r, user.Version) {
+		return
+	}
+
+	req := dto.NewUserRequest(user)
+	members, err := readPatch(r, req)
+	if err != nil {
+		problems.Write(w, r, err)
+		return
+	}
+	changes, fieldErrors := applyPatch(&req, members, dto.UserRequestFields)
+	if len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid user patch", fieldErrors...))
+		return
+	}
+	h.saveUserV2(w, r, user, req, changes)
+}
+
+func (h *UserHandler) DeleteUserV2Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := h.selfV2(w, r)
+	if !ok || !checkIfMatch(w, r, user.Version) {
+		return
+	}
+
+	err := h.Store.Users.DeleteUser(r.Context(), user.ID, user.Version)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not delete user").Wrap(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// selfV2 authenticates the caller and makes sure the URL names their own user
+func (h *UserHandler) selfV2(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	user, err := basicAuthUser(r, h.Store.Users)
+	if err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+	userID, err := idParam(r, "userId", "user")
+	if err != nil {
+		problems.Write(w, r, err)
+		return nil, false
+	}
+	if user.ID != userID {
+		problems.Write(w, r, problems.Forbidden("You can only access your own user"))
+		return nil, false
+	}
+	return user, true
+}
+
+// saveUserV2 validates the user as req leaves it, writes the changed columns
+// and answers with the updated user
+func (h *UserHandler) saveUserV2(w http.ResponseWriter, r *http.Request, user *store.User, req dto.UserRequest, changes map[string]interface{}) {
+	if fieldErrors := dto.ValidateUser(&store.User{FirstName: req.FirstName, LastName: req.LastName, Password: req.Password}, changes); len(fieldErrors) > 0 {
+		problems.Write(w, r, problems.Invalid("Invalid user", fieldErrors...))
+		return
+	}
+
+	err := h.Store.Users.PatchUser(r.Context(), user.ID, user.Version, changes)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		problems.Write(w, r, problems.PreconditionFailed(modifiedDetail))
+		return
+	}
+	if err != nil {
+		problems.Write(w, r, problems.Internal("Could not update user").Wrap(err))
+		return
+	}
+
+	req.Apply(user)
+	user.Version++
+	user.AccountUpdated = changes["account_updated"].(time.Time)
+	writeValidators(w, user.Version, user.AccountUpdated)
+	json.NewEncoder(w).Encode(dto.NewUser(user))
+}
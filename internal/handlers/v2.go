@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// The v2 handlers speak the request and response types of internal/dto
+// instead of encoding store models.
+
+// basicAuthUser authenticates the caller from Basic Auth credentials
+func basicAuthUser(r *http.Request, users *store.UserStore) (*store.User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, problems.Unauthorized("Unauthorized. Missing Basic Auth")
+	}
+	user, err := users.GetUserByCredentials(r.Context(), username, password)
+	if err != nil {
+		return nil, problems.Unauthorized("Invalid credentials")
+	}
+	return user, nil
+}
+
+// decodeRequest reads a v2 request body, rejecting members the request type does not have
+func decodeRequest(r *http.Request, req interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return problems.BadRequest("Invalid request payload: " + err.Error())
+	}
+	return nil
+}
+
+// idParam reads a numeric ID from the URL path
+func idParam(r *http.Request, name, resource string) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, problems.BadRequest("Invalid " + resource + " ID")
+	}
+	return uint(id), nil
+}
+
+// writeCreated answers a create with the new resource, where it lives and its validators
+func writeCreated(w http.ResponseWriter, location string, version int64, resource interface{}) {
+	w.Header().Set("Location", location)
+	w.Header().Set("ETag", entityTag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resource)
+}
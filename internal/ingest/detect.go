@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats of uploaded trace files
+const (
+	FormatPDF     = "pdf"
+	FormatCSV     = "csv"
+	FormatXLSX    = "xlsx"
+	FormatUnknown = "unknown"
+)
+
+// File is an uploaded file that can be read at random offsets, like multipart.File
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// DetectFormat identifies a file from its content rather than its name or declared type
+func DetectFormat(file File, size int64) (string, error) {
+	head := make([]byte, 4096)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return FormatPDF, nil
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		// XLSX is a zip archive holding a workbook part
+		archive, err := zip.NewReader(file, size)
+		if err != nil {
+			return FormatUnknown, nil
+		}
+		for _, part := range archive.File {
+			if part.Name == "xl/workbook.xml" {
+				return FormatXLSX, nil
+			}
+		}
+		return FormatUnknown, nil
+	case looksLikeCSV(head):
+		return FormatCSV, nil
+	}
+	return FormatUnknown, nil
+}
+
+// looksLikeCSV accepts UTF-8 text whose first line holds a delimiter
+func looksLikeCSV(head []byte) bool {
+	if len(head) == 0 || !strings.HasPrefix(http.DetectContentType(head), "text/plain") {
+		return false
+	}
+	// The sniffed window may cut a multi-byte character at its end
+	for i := 0; i < utf8.UTFMax && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+	firstLine, _, _ := strings.Cut(string(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))), "\n")
+	return detectDelimiter(firstLine) != 0
+}
+
+// detectDelimiter picks the most frequent of the usual delimiters in a header line
+func detectDelimiter(line string) rune {
+	best, bestCount := rune(0), 0
+	for _, delimiter := range []rune{',', ';', '\t', '|'} {
+		if count := strings.Count(line, string(delimiter)); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,85 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/csye7125/team01/internal/env"
+)
+
+// Survey result fields a column can map to
+const (
+	FieldQuestion      = "question"
+	FieldResponseCount = "response_count"
+	FieldInvitedCount  = "invited_count"
+	FieldMean          = "mean"
+	FieldMedian        = "median"
+	FieldStdDev        = "std_dev"
+)
+
+// DefaultProfileName is used when an upload does not name a profile
+const DefaultProfileName = "default"
+
+// ColumnProfile maps survey result fields to the header names used by a spreadsheet export.
+// Headers match case-insensitively, ignoring surrounding whitespace.
+type ColumnProfile struct {
+	Columns  map[string][]string `json:"columns"`
+	Required []string            `json:"required"`
+	// Sheet selects the XLSX worksheet; empty means the first one
+	Sheet string `json:"sheet"`
+	// HeaderRow is the 1-based row holding the headers; zero means the first row
+	HeaderRow int `json:"header_row"`
+}
+
+func defaultProfiles() map[string]ColumnProfile {
+	return map[string]ColumnProfile{
+		DefaultProfileName: {
+			Columns: map[string][]string{
+				FieldQuestion:      {"question", "item", "question text", "survey question"},
+				FieldResponseCount: {"response count", "responses", "respondents", "n", "count"},
+				FieldInvitedCount:  {"invited count", "invited", "enrolled", "enrollment"},
+				FieldMean:          {"mean", "average", "avg", "course mean"},
+				FieldMedian:        {"median", "course median"},
+				FieldStdDev:        {"std dev", "std_dev", "standard deviation", "stdev", "sd"},
+			},
+			Required: []string{FieldQuestion, FieldResponseCount, FieldMean},
+		},
+	}
+}
+
+var (
+	profilesOnce sync.Once
+	profiles     map[string]ColumnProfile
+	profilesErr  error
+)
+
+// Profiles returns the built-in profiles extended by TRACE_COLUMN_PROFILES_FILE,
+// a JSON object of profile name to ColumnProfile. It is read once per process.
+func Profiles() (map[string]ColumnProfile, error) {
+	profilesOnce.Do(func() {
+		profiles = defaultProfiles()
+		path := env.GetString("TRACE_COLUMN_PROFILES_FILE", "")
+		if path == "" {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			profilesErr = fmt.Errorf("failed to read column profiles: %w", err)
+			return
+		}
+		var configured map[string]ColumnProfile
+		if err := json.Unmarshal(data, &configured); err != nil {
+			profilesErr = fmt.Errorf("failed to parse column profiles: %w", err)
+			return
+		}
+		for name, profile := range configured {
+			if len(profile.Required) == 0 {
+				profile.Required = defaultProfiles()[DefaultProfileName].Required
+			}
+			profiles[name] = profile
+		}
+	})
+	return profiles, profilesErr
+}
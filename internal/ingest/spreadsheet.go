@@ -0,0 +1,222 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/csye7125/team01/internal/store"
+	"github.com/xuri/excelize/v2"
+)
+
+// RowError describes why one spreadsheet row could not be mapped. Row is 1-based as
+// shown in a spreadsheet; Row 0 means the problem is with the sheet as a whole.
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("row %d, column %q: %s", e.Row, e.Column, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// ReadCSV reads every record of a CSV export, detecting its delimiter from the header
+func ReadCSV(r io.Reader) ([][]string, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(4096)
+	firstLine, _, _ := strings.Cut(strings.TrimPrefix(string(head), "\xef\xbb\xbf"), "\n")
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = detectDelimiter(firstLine)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\xef\xbb\xbf")
+	}
+	return rows, nil
+}
+
+// ReadXLSX reads every row of the named worksheet, or the first one when sheet is empty
+func ReadXLSX(r io.Reader, sheet string) ([][]string, error) {
+	workbook, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	defer workbook.Close()
+
+	if sheet == "" {
+		sheet = workbook.GetSheetName(0)
+	}
+	rows, err := workbook.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheet, err)
+	}
+	return rows, nil
+}
+
+// MapRows turns spreadsheet rows into survey results using a column profile.
+// Every invalid row is reported; results hold only the rows that mapped cleanly.
+func MapRows(rows [][]string, profile ColumnProfile) ([]store.TraceResult, []RowError) {
+	headerRow := max(profile.HeaderRow, 1)
+	if len(rows) < headerRow {
+		return nil, []RowError{{Message: fmt.Sprintf("header row %d not found", headerRow)}}
+	}
+
+	// 🔹 Locate each field's column from the header row
+	columns := map[string]int{}
+	headers := rows[headerRow-1]
+	for field, names := range profile.Columns {
+		for index, header := range headers {
+			if matchesHeader(header, names) {
+				columns[field] = index
+				break
+			}
+		}
+	}
+	var errs []RowError
+	for _, field := range profile.Required {
+		if _, ok := columns[field]; !ok {
+			errs = append(errs, RowError{Row: headerRow, Column: field, Message: "required column is missing"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	// 🔹 Map and validate each data row
+	var results []store.TraceResult
+	for i := headerRow; i < len(rows); i++ {
+		row := rows[i]
+		if isBlank(row) {
+			continue
+		}
+		result, rowErrs := mapRow(i+1, row, columns, profile.Required)
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
+			continue
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 && len(errs) == 0 {
+		errs = append(errs, RowError{Row: headerRow + 1, Message: "no data rows found"})
+	}
+	return results, errs
+}
+
+func mapRow(rowNumber int, row []string, columns map[string]int, required []string) (store.TraceResult, []RowError) {
+	var errs []RowError
+	cell := func(field string) string {
+		index, ok := columns[field]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+	isRequired := map[string]bool{}
+	for _, field := range required {
+		isRequired[field] = true
+	}
+
+	result := store.TraceResult{Question: cell(FieldQuestion)}
+	if result.Question == "" && isRequired[FieldQuestion] {
+		errs = append(errs, RowError{Row: rowNumber, Column: FieldQuestion, Message: "value is required"})
+	}
+
+	integer := func(field string, target *int) {
+		value := cell(field)
+		if value == "" {
+			if isRequired[field] {
+				errs = append(errs, RowError{Row: rowNumber, Column: field, Message: "value is required"})
+			}
+			return
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) || parsed < 0 || parsed != float64(int(parsed)) {
+			errs = append(errs, RowError{Row: rowNumber, Column: field, Message: fmt.Sprintf("%q is not a non-negative whole number", value)})
+			return
+		}
+		*target = int(parsed)
+	}
+	decimal := func(field string, target *float64) {
+		value := cell(field)
+		if value == "" {
+			if isRequired[field] {
+				errs = append(errs, RowError{Row: rowNumber, Column: field, Message: "value is required"})
+			}
+			return
+		}
+		// 🔹 ParseFloat accepts NaN and Inf, which no statistic can be
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) || parsed < 0 {
+			errs = append(errs, RowError{Row: rowNumber, Column: field, Message: fmt.Sprintf("%q is not a non-negative number", value)})
+			return
+		}
+		*target = parsed
+	}
+
+	integer(FieldResponseCount, &result.ResponseCount)
+	integer(FieldInvitedCount, &result.InvitedCount)
+	decimal(FieldMean, &result.Mean)
+	decimal(FieldMedian, &result.Median)
+	decimal(FieldStdDev, &result.StdDev)
+
+	if len(errs) == 0 && result.InvitedCount > 0 && result.ResponseCount > result.InvitedCount {
+		errs = append(errs, RowError{Row: rowNumber, Column: FieldResponseCount, Message: "response count exceeds invited count"})
+	}
+	return result, errs
+}
+
+func matchesHeader(header string, names []string) bool {
+	header = strings.ToLower(strings.TrimSpace(header))
+	for _, name := range names {
+		if header == strings.ToLower(strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSpreadsheet reads a CSV or XLSX upload and maps it through the profile
+func ParseSpreadsheet(file File, format string, profile ColumnProfile) ([]store.TraceResult, []RowError, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("failed to reset file pointer: %w", err)
+	}
+
+	var rows [][]string
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = ReadCSV(file)
+	case FormatXLSX:
+		rows, err = ReadXLSX(file, profile.Sheet)
+	default:
+		return nil, nil, fmt.Errorf("unsupported spreadsheet format %q", format)
+	}
+	if err != nil {
+		return nil, []RowError{{Message: err.Error()}}, nil
+	}
+
+	results, errs := MapRows(rows, profile)
+	return results, errs, nil
+}
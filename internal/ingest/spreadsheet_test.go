@@ -0,0 +1,213 @@
+package ingest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func testProfile(t *testing.T) ColumnProfile {
+	t.Helper()
+	return defaultProfiles()[DefaultProfileName]
+}
+
+// testWorkbook builds an XLSX file with rows on the named sheet
+func testWorkbook(t *testing.T, sheet string, rows [][]interface{}) []byte {
+	t.Helper()
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+	if sheet != "Sheet1" {
+		if _, err := workbook.NewSheet(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := workbook.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buffer, err := workbook.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), FormatPDF},
+		{"comma csv", []byte("Question,Responses,Mean\nClear goals,20,4.5\n"), FormatCSV},
+		{"semicolon csv with BOM", []byte("\xef\xbb\xbfQuestion;Responses;Mean\nClear goals;20;4,5\n"), FormatCSV},
+		{"plain text", []byte("just some notes without delimiters\n"), FormatUnknown},
+		{"binary", []byte{0x00, 0x01, 0x02, 0xff}, FormatUnknown},
+		{"zip without workbook", []byte("PK\x03\x04not really a zip"), FormatUnknown},
+		{"xlsx", testWorkbook(t, "Sheet1", [][]interface{}{{"Question"}}), FormatXLSX},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: DetectFormat = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, err := ReadCSV(strings.NewReader("\xef\xbb\xbfQuestion;Responses;Mean\n\"Goals; clear\";20;4.5\nPace;18\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if rows[0][0] != "Question" {
+		t.Errorf("header %q kept its byte order mark", rows[0][0])
+	}
+	if rows[1][0] != "Goals; clear" || rows[1][2] != "4.5" {
+		t.Errorf("quoted row read as %q", rows[1])
+	}
+	if len(rows[2]) != 2 {
+		t.Errorf("short row read as %q", rows[2])
+	}
+}
+
+func TestMapRows(t *testing.T) {
+	rows := [][]string{
+		{" Survey Question ", "Respondents", "Invited", "Average", "Median", "SD"},
+		{"The course goals were clear", "20", "25", "4.5", "5", "0.7"},
+		{"", "", "", "", "", ""},
+		{"The pace was right", "18", "", "3.9", "", ""},
+	}
+	results, errs := MapRows(rows, testProfile(t))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected row errors: %v", errs)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	first := results[0]
+	if first.Question != "The course goals were clear" || first.ResponseCount != 20 || first.InvitedCount != 25 ||
+		first.Mean != 4.5 || first.Median != 5 || first.StdDev != 0.7 {
+		t.Errorf("first row mapped to %+v", first)
+	}
+	if second := results[1]; second.InvitedCount != 0 || second.Median != 0 || second.Mean != 3.9 {
+		t.Errorf("optional blanks mapped to %+v", second)
+	}
+}
+
+func TestMapRowsReportsEveryInvalidRow(t *testing.T) {
+	rows := [][]string{
+		{"Question", "Responses", "Invited", "Mean"},
+		{"Goals", "twenty", "25", "4.5"},
+		{"", "20", "25", "4.5"},
+		{"Pace", "30", "25", "-1"},
+		{"Workload", "30", "25", "4"},
+		{"Grading", "10.5", "", "4"},
+		{"Feedback", "10", "", "4.2"},
+		{"Clarity", "10", "", "NaN"},
+		{"Pacing", "10", "", "+Inf"},
+		{"Rigor", "Inf", "", "4"},
+	}
+	results, errs := MapRows(rows, testProfile(t))
+	if len(results) != 1 || results[0].Question != "Feedback" {
+		t.Fatalf("results = %+v, want only the Feedback row", results)
+	}
+
+	want := []RowError{
+		{Row: 2, Column: FieldResponseCount},
+		{Row: 3, Column: FieldQuestion},
+		{Row: 4, Column: FieldMean},
+		{Row: 5, Column: FieldResponseCount},
+		{Row: 6, Column: FieldResponseCount},
+		{Row: 8, Column: FieldMean},
+		{Row: 9, Column: FieldMean},
+		{Row: 10, Column: FieldResponseCount},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("row errors = %v, want %d", errs, len(want))
+	}
+	for i := range want {
+		if errs[i].Row != want[i].Row || errs[i].Column != want[i].Column || errs[i].Message == "" {
+			t.Errorf("row error %d = %+v, want row %d column %s", i, errs[i], want[i].Row, want[i].Column)
+		}
+	}
+}
+
+func TestMapRowsMissingColumns(t *testing.T) {
+	_, errs := MapRows([][]string{{"Question", "Comments"}, {"Goals", "fine"}}, testProfile(t))
+	if len(errs) != 2 || errs[0].Row != 1 {
+		t.Fatalf("row errors = %v, want the two missing required columns", errs)
+	}
+
+	_, errs = MapRows([][]string{{"Question", "Responses", "Mean"}}, testProfile(t))
+	if len(errs) != 1 || errs[0].Message != "no data rows found" {
+		t.Fatalf("row errors = %v, want no data rows found", errs)
+	}
+
+	profile := testProfile(t)
+	profile.HeaderRow = 3
+	_, errs = MapRows([][]string{{"Question", "Responses", "Mean"}}, profile)
+	if len(errs) != 1 || errs[0].Row != 0 {
+		t.Fatalf("row errors = %v, want a missing header row", errs)
+	}
+}
+
+func TestParseSpreadsheetXLSX(t *testing.T) {
+	data := testWorkbook(t, "Results", [][]interface{}{
+		{"Fall 2024 evaluation"},
+		{"Item", "N", "Avg"},
+		{"Clear goals", 20, 4.5},
+		{"Fair grading", 19, 4.1},
+	})
+	profile := testProfile(t)
+	profile.Sheet = "Results"
+	profile.HeaderRow = 2
+
+	results, errs, err := ParseSpreadsheet(bytes.NewReader(data), FormatXLSX, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("unexpected row errors: %v", errs)
+	}
+	if len(results) != 2 || results[1].Question != "Fair grading" || results[1].ResponseCount != 19 || results[1].Mean != 4.1 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	profile.Sheet = "Missing"
+	_, errs, err = ParseSpreadsheet(bytes.NewReader(data), FormatXLSX, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Row != 0 {
+		t.Fatalf("row errors = %v, want one for the missing sheet", errs)
+	}
+}
+
+func TestParseSpreadsheetCSV(t *testing.T) {
+	data := []byte("Question\tResponses\tMean\nClear goals\t20\t4.5\n")
+	results, errs, err := ParseSpreadsheet(bytes.NewReader(data), FormatCSV, testProfile(t))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("ParseSpreadsheet: %v %v", err, errs)
+	}
+	if len(results) != 1 || results[0].ResponseCount != 20 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	if _, _, err := ParseSpreadsheet(bytes.NewReader(data), FormatPDF, testProfile(t)); err == nil {
+		t.Fatal("ParseSpreadsheet accepted a PDF")
+	}
+}
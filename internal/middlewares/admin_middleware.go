@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// AdminMiddleware admits only users listed in ADMIN_USERNAMES; it must run after BasicAuthMiddleware
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(UserContextKey).(*store.User)
+		if !ok || !isAdmin(user.Username) {
+			w.Header().Set("Content-Type", "application/json")
+			problems.Write(w, r, problems.Forbidden("Forbidden"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isAdmin(username string) bool {
+	for _, admin := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && admin == username {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/csye7125/team01/internal/problems"
 	"github.com/csye7125/team01/internal/store"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -44,7 +45,7 @@ func (am *AuthMiddleware) BasicAuthMiddleware(next http.Handler) http.Handler {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			span.SetStatus(codes.Error, "missing authorization header")
-			http.Error(w, `{"error": "Missing Authorization header"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Missing Authorization header"))
 			return
 		}
 
@@ -52,7 +53,7 @@ func (am *AuthMiddleware) BasicAuthMiddleware(next http.Handler) http.Handler {
 		authParts := strings.SplitN(authHeader, " ", 2)
 		if len(authParts) != 2 || authParts[0] != "Basic" {
 			span.SetStatus(codes.Error, "invalid authorization header")
-			http.Error(w, `{"error": "Invalid Authorization header"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Invalid Authorization header"))
 			return
 		}
 
@@ -60,14 +61,14 @@ func (am *AuthMiddleware) BasicAuthMiddleware(next http.Handler) http.Handler {
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "invalid base64 encoding")
-			http.Error(w, `{"error": "Invalid base64 encoding"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Invalid base64 encoding"))
 			return
 		}
 
 		credentials := strings.SplitN(string(payload), ":", 2)
 		if len(credentials) != 2 {
 			span.SetStatus(codes.Error, "invalid credentials format")
-			http.Error(w, `{"error": "Invalid credentials format"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Invalid credentials format"))
 			return
 		}
 
@@ -79,7 +80,7 @@ func (am *AuthMiddleware) BasicAuthMiddleware(next http.Handler) http.Handler {
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "invalid username")
-			http.Error(w, `{"error": "Invalid username or password"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Invalid username or password"))
 			return
 		}
 
@@ -87,7 +88,7 @@ func (am *AuthMiddleware) BasicAuthMiddleware(next http.Handler) http.Handler {
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "invalid password")
-			http.Error(w, `{"error": "Invalid username or password"}`, http.StatusUnauthorized)
+			problems.Write(w, r, problems.Unauthorized("Invalid username or password"))
 			return
 		}
 
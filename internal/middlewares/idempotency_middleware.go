@@ -0,0 +1,196 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/store"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody bounds request bodies, which are buffered to fingerprint them
+	maxIdempotentBody = 64 << 20
+	// defaultIdempotencyLease outlasts the server's request timeouts, so only
+	// requests whose process died stay in flight past it
+	defaultIdempotencyLease = 2 * time.Minute
+)
+
+// replayedHeaders are the response headers stored with a response and sent again on replay
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// IdempotencyMiddleware makes POST requests safe to retry. The first request with
+// an Idempotency-Key runs and its response is stored; retries with the same key
+// and body get that response back instead of running again. It must run after
+// BasicAuthMiddleware, as keys belong to the authenticated user.
+type IdempotencyMiddleware struct {
+	Store IdempotencyStore
+	TTL   time.Duration
+	// Lease is how long a key stays in flight before another request may reclaim it
+	Lease time.Duration
+}
+
+// IdempotencyStore keeps the reservation and stored response of each key
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *store.IdempotencyRecord) (*store.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *store.IdempotencyRecord) error
+	Release(ctx context.Context, record *store.IdempotencyRecord) error
+}
+
+func NewIdempotencyMiddleware(idempotencyStore IdempotencyStore, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{Store: idempotencyStore, TTL: ttl, Lease: defaultIdempotencyLease}
+}
+
+func (im *IdempotencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problems.Write(w, r, problems.BadRequest(fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)))
+			return
+		}
+		user, ok := r.Context().Value(UserContextKey).(*store.User)
+		if !ok {
+			problems.Write(w, r, problems.Unauthorized("Idempotency-Key requires authentication"))
+			return
+		}
+
+		// 🔹 Buffer the body so it can be fingerprinted and still be read by the handler
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problems.Write(w, r, problems.New(http.StatusRequestEntityTooLarge, "Request body is too large"))
+				return
+			}
+			problems.Write(w, r, problems.BadRequest("Could not read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		lockedUntil := now.Add(im.Lease)
+		record := &store.IdempotencyRecord{
+			IdempotencyKey: key,
+			Scope:          fmt.Sprintf("%d %s %s", user.ID, r.Method, r.URL.Path),
+			Fingerprint:    requestFingerprint(r, body),
+			ExpiresAt:      now.Add(im.TTL),
+			LockedUntil:    &lockedUntil,
+		}
+
+		// 🔹 Claim the key, or find out what happened to the request that claimed it
+		existing, err := im.Store.Reserve(r.Context(), record)
+		if err != nil {
+			problems.Write(w, r, problems.Internal("Could not check Idempotency-Key").Wrap(err))
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				problems.Write(w, r, problems.Conflict("Idempotency-Key was already used with a different request"))
+			case existing.State == store.IdempotencyInFlight:
+				w.Header().Set("Retry-After", "1")
+				problems.Write(w, r, problems.Conflict("A request with this Idempotency-Key is still in progress"))
+			default:
+				replay(w, existing)
+			}
+			return
+		}
+
+		// 🔹 Run the request, releasing the key if it does not finish. The outcome is
+		// recorded even if the client has already gone away.
+		storeCtx := context.WithoutCancel(r.Context())
+		completed := false
+		defer func() {
+			if !completed {
+				if err := im.Store.Release(storeCtx, record); err != nil {
+					log.Printf("❌ Could not release Idempotency-Key %q: %v", key, err)
+				}
+			}
+		}()
+
+		recorder := httptest.NewRecorder()
+		next.ServeHTTP(recorder, r)
+
+		// 🔹 Server errors are not stored, so a retry gets another chance
+		if recorder.Code < http.StatusInternalServerError {
+			record.StatusCode = recorder.Code
+			record.Body = recorder.Body.Bytes()
+			record.Headers = map[string]string{}
+			for _, name := range replayedHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					record.Headers[name] = value
+				}
+			}
+			if err := im.Store.Complete(storeCtx, record); err != nil {
+				log.Printf("❌ Could not store response for Idempotency-Key %q: %v", key, err)
+			} else {
+				completed = true
+			}
+		}
+
+		for name, values := range recorder.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(recorder.Code)
+		w.Write(recorder.Body.Bytes())
+	})
+}
+
+// replay sends a stored response again
+func replay(w http.ResponseWriter, record *store.IdempotencyRecord) {
+	for name, value := range record.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// requestFingerprint hashes what makes two requests the same. Multipart bodies
+// are hashed part by part, because clients pick a new boundary for every attempt.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	fmt.Fprintf(hash, "%s\n", mediaType)
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		parts := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				return hex.EncodeToString(hash.Sum(nil))
+			}
+			if err != nil {
+				break
+			}
+			fmt.Fprintf(hash, "%q %q %q\n", part.FormName(), part.FileName(), part.Header.Get("Content-Type"))
+			partHash := sha256.New()
+			io.Copy(partHash, part)
+			hash.Write(partHash.Sum(nil))
+		}
+		// 🔹 Malformed multipart bodies fall back to their raw bytes
+		hash = sha256.New()
+		fmt.Fprintf(hash, "%s %s?%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery, mediaType)
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
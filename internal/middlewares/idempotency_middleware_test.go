@@ -0,0 +1,239 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// memoryIdempotencyStore follows the contract of store.IdempotencyStore without a database
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]store.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]store.IdempotencyRecord{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, record *store.IdempotencyRecord) (*store.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := record.IdempotencyKey + "\x00" + record.Scope
+	now := time.Now()
+	if existing, ok := s.records[id]; ok {
+		expired := !existing.ExpiresAt.After(now)
+		abandoned := existing.State == store.IdempotencyInFlight && existing.LockedUntil != nil && !existing.LockedUntil.After(now)
+		if !expired && !abandoned {
+			return &existing, nil
+		}
+	}
+	record.State = store.IdempotencyInFlight
+	s.records[id] = *record
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, record *store.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := record.IdempotencyKey + "\x00" + record.Scope
+	if s.owns(id, record) {
+		record.State = store.IdempotencyCompleted
+		s.records[id] = *record
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, record *store.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := record.IdempotencyKey + "\x00" + record.Scope
+	if s.owns(id, record) {
+		delete(s.records, id)
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) owns(id string, record *store.IdempotencyRecord) bool {
+	existing, ok := s.records[id]
+	return ok && existing.State == store.IdempotencyInFlight && existing.LockedUntil.Equal(*record.LockedUntil)
+}
+
+// countingHandler creates a course per call and answers with its number
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	status := h.status
+	if status == 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/v1/course/%d", h.calls))
+	w.Header().Set("X-Request-Only", "yes")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"course_id": %d}`, h.calls)
+}
+
+func idempotentRequest(userID uint, key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/course", bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+	return r.WithContext(context.WithValue(r.Context(), UserContextKey, &store.User{ID: userID}))
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	next := &countingHandler{}
+	handler := NewIdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour).Handler(next)
+
+	first := serve(handler, idempotentRequest(1, "key-1", `{"code": "CSYE7125"}`))
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request got %d, replayed %q", first.Code, first.Header().Get("Idempotent-Replayed"))
+	}
+
+	retry := serve(handler, idempotentRequest(1, "key-1", `{"code": "CSYE7125"}`))
+	if next.calls != 1 {
+		t.Fatalf("handler ran %d times, want once", next.calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry got %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Location") != "/v1/course/1" {
+		t.Fatalf("retry headers %v", retry.Header())
+	}
+	if retry.Header().Get("X-Request-Only") != "" {
+		t.Fatal("retry replayed a header that is not stored")
+	}
+}
+
+func TestIdempotencyKeysAreScoped(t *testing.T) {
+	next := &countingHandler{}
+	handler := NewIdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour).Handler(next)
+
+	serve(handler, idempotentRequest(1, "shared", `{}`))
+	serve(handler, idempotentRequest(2, "shared", `{}`))
+	serve(handler, idempotentRequest(1, "", `{}`))
+	serve(handler, idempotentRequest(1, "", `{}`))
+	if next.calls != 4 {
+		t.Fatalf("handler ran %d times, want 4", next.calls)
+	}
+}
+
+func TestIdempotencyConflicts(t *testing.T) {
+	idempotencyStore := newMemoryIdempotencyStore()
+	next := &countingHandler{}
+	handler := NewIdempotencyMiddleware(idempotencyStore, time.Hour).Handler(next)
+
+	serve(handler, idempotentRequest(1, "key-1", `{"code": "CSYE7125"}`))
+	if w := serve(handler, idempotentRequest(1, "key-1", `{"code": "CSYE6225"}`)); w.Code != http.StatusConflict {
+		t.Fatalf("reused key with another body got %d, want 409", w.Code)
+	}
+
+	// 🔹 A request still holding its lease blocks retries
+	lockedUntil := time.Now().Add(time.Minute)
+	idempotencyStore.Reserve(context.Background(), &store.IdempotencyRecord{
+		IdempotencyKey: "key-2",
+		Scope:          "1 POST /v1/course",
+		Fingerprint:    requestFingerprint(idempotentRequest(1, "key-2", `{}`), []byte(`{}`)),
+		ExpiresAt:      time.Now().Add(time.Hour),
+		LockedUntil:    &lockedUntil,
+	})
+	w := serve(handler, idempotentRequest(1, "key-2", `{}`))
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("retry in flight got %d with Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if next.calls != 1 {
+		t.Fatalf("handler ran %d times, want once", next.calls)
+	}
+}
+
+func TestIdempotencyReclaimsAbandonedKeys(t *testing.T) {
+	idempotencyStore := newMemoryIdempotencyStore()
+	next := &countingHandler{}
+	middleware := NewIdempotencyMiddleware(idempotencyStore, time.Hour)
+	handler := middleware.Handler(next)
+
+	// 🔹 A reservation whose process died keeps the key until its lease ends
+	lockedUntil := time.Now().Add(-time.Second)
+	idempotencyStore.Reserve(context.Background(), &store.IdempotencyRecord{
+		IdempotencyKey: "key-1",
+		Scope:          "1 POST /v1/course",
+		Fingerprint:    requestFingerprint(idempotentRequest(1, "key-1", `{}`), []byte(`{}`)),
+		ExpiresAt:      time.Now().Add(time.Hour),
+		LockedUntil:    &lockedUntil,
+	})
+
+	if w := serve(handler, idempotentRequest(1, "key-1", `{}`)); w.Code != http.StatusCreated {
+		t.Fatalf("retry after the lease got %d, want 201", w.Code)
+	}
+	if w := serve(handler, idempotentRequest(1, "key-1", `{}`)); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("reclaimed key did not store its response")
+	}
+	if next.calls != 1 {
+		t.Fatalf("handler ran %d times, want once", next.calls)
+	}
+
+	record := idempotencyStore.records["key-1\x001 POST /v1/course"]
+	if record.LockedUntil == nil || record.LockedUntil.Before(time.Now().Add(middleware.Lease-time.Minute)) {
+		t.Fatalf("reservation leased until %v, want about %v from now", record.LockedUntil, middleware.Lease)
+	}
+}
+
+func TestIdempotencyServerErrorsAreRetried(t *testing.T) {
+	next := &countingHandler{status: http.StatusServiceUnavailable}
+	handler := NewIdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour).Handler(next)
+
+	serve(handler, idempotentRequest(1, "key-1", `{}`))
+	next.status = 0
+	if w := serve(handler, idempotentRequest(1, "key-1", `{}`)); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry after a server error got %d, want the request to run again", w.Code)
+	}
+	if next.calls != 2 {
+		t.Fatalf("handler ran %d times, want twice", next.calls)
+	}
+}
+
+func TestRequestFingerprintIgnoresMultipartBoundary(t *testing.T) {
+	upload := func(boundary, content string) *http.Request {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.SetBoundary(boundary)
+		part, _ := form.CreateFormFile("files", "trace.pdf")
+		part.Write([]byte(content))
+		form.Close()
+		r := httptest.NewRequest(http.MethodPost, "/v1/course/1/trace", &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		return r
+	}
+	fingerprint := func(r *http.Request) string {
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		return requestFingerprint(r, body.Bytes())
+	}
+
+	if fingerprint(upload("first-boundary", "%PDF")) != fingerprint(upload("second-boundary", "%PDF")) {
+		t.Fatal("the same upload with a new boundary got a new fingerprint")
+	}
+	if fingerprint(upload("first-boundary", "%PDF")) == fingerprint(upload("first-boundary", "%PDF-changed")) {
+		t.Fatal("different uploads got the same fingerprint")
+	}
+}
@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"github.com/csye7125/team01/internal/problems"
+	"net/http"
+)
+
+// docsPage renders the specification with Swagger UI
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Course TRACE API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// SpecHandler serves the specification as JSON
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	spec, err := JSON()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		problems.Write(w, r, problems.Internal("Could not load API specification"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// DocsHandler serves the interactive documentation
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	"github.com/csye7125/team01/internal/problems"
+	validator "github.com/pb33f/libopenapi-validator"
+	"github.com/pb33f/libopenapi-validator/errors"
+	"github.com/pb33f/libopenapi-validator/helpers"
+)
+
+// quotedName finds the parameter named in a validation message
+var quotedName = regexp.MustCompile(`'([^']+)'`)
+
+// Middleware rejects requests whose parameters or JSON bodies do not match the
+// specification. With validateResponses set, successful responses are checked too
+// and a mismatch is turned into a 500, which is meant for test environments.
+func Middleware(v validator.Validator, validateResponses bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, errs := v.ValidateHttpRequestSync(r); len(errs) > 0 {
+				// 🔹 Unknown routes fall through to the router; credentials are checked by the auth middleware
+				if !routeMissing(errs) {
+					if reason, ok := unsupportedContentType(errs); ok {
+						problems.Write(w, r, problems.New(http.StatusUnsupportedMediaType, reason))
+						return
+					}
+					if fields := requestProblems(errs); len(fields) > 0 {
+						problems.Write(w, r, problems.Invalid("Request does not match the API specification", fields...))
+						return
+					}
+				}
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// 🔹 Buffer the response so it can be checked before it is sent; exports stream straight through
+			buffer := &responseBuffer{w: w, recorder: httptest.NewRecorder()}
+			next.ServeHTTP(buffer, r)
+			if buffer.passThrough {
+				return
+			}
+			recorder := buffer.recorder
+			response := recorder.Result()
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				if _, errs := v.ValidateHttpResponse(r, response); len(errs) > 0 {
+					invalid := problems.Internal("Response does not match the API specification")
+					invalid.Fields = fieldErrors(errs)
+					problems.Write(w, r, invalid)
+					return
+				}
+			}
+
+			for key, values := range recorder.Header() {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(recorder.Code)
+			w.Write(recorder.Body.Bytes())
+		})
+	}
+}
+
+// streamed tells whether a response is a CSV or NDJSON export, which is sent
+// while it is read from the database and cannot be checked as one document
+func streamed(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "text/csv" || mediaType == "application/x-ndjson"
+}
+
+// responseBuffer holds a response back until it has been validated, unless
+// its headers show a streamed export, which is passed on as it is written
+type responseBuffer struct {
+	w           http.ResponseWriter
+	recorder    *httptest.ResponseRecorder
+	decided     bool
+	passThrough bool
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.recorder.Header()
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.decided {
+		return
+	}
+	b.decided = true
+	if code >= 200 && code < 300 && streamed(b.recorder.Header()) {
+		b.passThrough = true
+		for key, values := range b.recorder.Header() {
+			b.w.Header()[key] = values
+		}
+		b.w.WriteHeader(code)
+		return
+	}
+	b.recorder.WriteHeader(code)
+}
+
+func (b *responseBuffer) Write(data []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	if b.passThrough {
+		return b.w.Write(data)
+	}
+	return b.recorder.Write(data)
+}
+
+// Flush sends what a streamed export has written so far
+func (b *responseBuffer) Flush() {
+	if b.passThrough {
+		http.NewResponseController(b.w).Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, for write deadlines
+func (b *responseBuffer) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+func routeMissing(errs []*errors.ValidationError) bool {
+	for _, err := range errs {
+		if (err.ValidationType == helpers.ParameterValidationPath && err.ValidationSubType == "missing") ||
+			err.ValidationSubType == helpers.RequestMissingOperation {
+			return true
+		}
+	}
+	return false
+}
+
+func unsupportedContentType(errs []*errors.ValidationError) (string, bool) {
+	for _, err := range errs {
+		if err.ValidationType == helpers.RequestBodyValidation && err.ValidationSubType == helpers.RequestBodyContentType {
+			return err.Reason, true
+		}
+	}
+	return "", false
+}
+
+func requestProblems(errs []*errors.ValidationError) []problems.FieldError {
+	var relevant []*errors.ValidationError
+	for _, err := range errs {
+		if err.ValidationType != "security" {
+			relevant = append(relevant, err)
+		}
+	}
+	return fieldErrors(relevant)
+}
+
+// fieldErrors names where each failure occurred: a parameter such as
+// query.limit or a field of the request body such as body.username
+func fieldErrors(errs []*errors.ValidationError) []problems.FieldError {
+	fields := make([]problems.FieldError, 0, len(errs))
+	for _, err := range errs {
+		if len(err.SchemaValidationErrors) == 0 {
+			field := err.ValidationType
+			if err.ValidationType == helpers.ParameterValidation {
+				field = err.ValidationSubType
+				if name := quotedName.FindStringSubmatch(err.Message); name != nil {
+					field += "." + name[1]
+				}
+			}
+			fields = append(fields, problems.FieldError{Field: field, Message: err.Reason})
+			continue
+		}
+		for _, failure := range err.SchemaValidationErrors {
+			fields = append(fields, problems.FieldError{Field: "body" + schemaField(failure.Location), Message: failure.Reason})
+		}
+	}
+	return fields
+}
+
+// schemaField turns a schema keyword location such as /properties/tags/items/type
+// into the path of the offending field, here .tags[]
+func schemaField(location string) string {
+	var field strings.Builder
+	segments := strings.Split(location, "/")
+	for i := 0; i < len(segments); i++ {
+		switch segments[i] {
+		case "properties":
+			if i+1 < len(segments) {
+				field.WriteString("." + segments[i+1])
+				i++
+			}
+		case "items":
+			field.WriteString("[]")
+		}
+	}
+	return field.String()
+}
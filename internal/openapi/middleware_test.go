@@ -0,0 +1,63 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareStreamsExports(t *testing.T) {
+	v, err := NewValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mediaType := range []string{"text/csv", "application/x-ndjson"} {
+		w := httptest.NewRecorder()
+		sentBeforeEnd := false
+		handler := Middleware(v, true)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+			rw.WriteHeader(http.StatusOK)
+			fmt.Fprintln(rw, "first row")
+			http.NewResponseController(rw).Flush()
+			sentBeforeEnd = w.Flushed && w.Body.Len() > 0
+			fmt.Fprintln(rw, "second row")
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/courses", nil)
+		r.Header.Set("Accept", mediaType)
+		handler.ServeHTTP(w, r)
+
+		if !sentBeforeEnd {
+			t.Errorf("%s: rows were held back until the export ended", mediaType)
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "first row\nsecond row\n" {
+			t.Errorf("%s: got %d %q", mediaType, w.Code, w.Body)
+		}
+	}
+}
+
+func TestMiddlewareValidatesJSONResponses(t *testing.T) {
+	v, err := NewValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 🔹 A page without its required members does not reach the client
+	handler := Middleware(v, true)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("ETag", `"1"`)
+		rw.WriteHeader(http.StatusOK)
+		fmt.Fprint(rw, `{"unexpected": true}`)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/courses", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("invalid response passed with %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Fatal("headers of the rejected response were sent")
+	}
+}
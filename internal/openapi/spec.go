@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pb33f/libopenapi"
+	validator "github.com/pb33f/libopenapi-validator"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+	specErr  error
+)
+
+// JSON returns the API specification as JSON
+func JSON() ([]byte, error) {
+	specOnce.Do(func() {
+		var document interface{}
+		if specErr = yaml.Unmarshal(specYAML, &document); specErr != nil {
+			return
+		}
+		specJSON, specErr = json.Marshal(document)
+	})
+	return specJSON, specErr
+}
+
+// NewValidator checks the specification against the OpenAPI schema and
+// returns a validator for requests and responses
+func NewValidator() (validator.Validator, error) {
+	document, err := libopenapi.NewDocument(specYAML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+	}
+	v, errs := validator.NewValidator(document)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to build OpenAPI model: %v", errs)
+	}
+	if ok, problems := v.ValidateDocument(); !ok {
+		messages := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			messages = append(messages, problem.Message)
+		}
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", strings.Join(messages, "; "))
+	}
+	return v, nil
+}
+
+// CheckRoutes fails when the router serves a route the specification does not describe
+func CheckRoutes(routes chi.Routes) error {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(specYAML, &spec); err != nil {
+		return err
+	}
+
+	var missing []string
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if _, ok := spec.Paths[route][strings.ToLower(method)]; !ok {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("routes missing from the OpenAPI document: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
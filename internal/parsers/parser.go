@@ -0,0 +1,81 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/csye7125/team01/internal/ingest"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// Document is an uploaded trace file handed to parsers
+type Document struct {
+	FileName string
+	Format   string
+	Data     []byte
+	// Profile maps spreadsheet columns; PDF parsers ignore it
+	Profile ingest.ColumnProfile
+
+	pagesOnce sync.Once
+	pages     []store.TracePage
+	pagesErr  error
+}
+
+func NewDocument(fileName string, data []byte, profile ingest.ColumnProfile) (*Document, error) {
+	format, err := ingest.DetectFormat(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{FileName: fileName, Format: format, Data: data, Profile: profile}, nil
+}
+
+// Pages returns the extracted text of a PDF, page by page, extracting it once
+func (d *Document) Pages() ([]store.TracePage, error) {
+	d.pagesOnce.Do(func() {
+		if d.Format != ingest.FormatPDF {
+			return
+		}
+		d.pages, d.pagesErr = extractPDFPages(d.Data)
+	})
+	return d.pages, d.pagesErr
+}
+
+// Text returns the whole extracted text of a PDF
+func (d *Document) Text() string {
+	pages, _ := d.Pages()
+	var text strings.Builder
+	for _, page := range pages {
+		text.WriteString(page.Text)
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// Parsed is everything a parser extracted from a document
+type Parsed struct {
+	Results  []store.TraceResult
+	Comments []store.TraceComment
+	Pages    []store.TracePage
+}
+
+// Parser understands one survey layout. Detect returns a confidence between 0 (cannot
+// handle the document) and 1 (certain); the registry routes each upload to the most
+// confident parser. Version must increase whenever the parser's output changes.
+type Parser interface {
+	Name() string
+	Version() int
+	Detect(doc *Document) float64
+	Parse(ctx context.Context, doc *Document) (*Parsed, error)
+}
+
+// ValidationError reports rows of a structured document that could not be mapped
+type ValidationError struct {
+	RowErrors []ingest.RowError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d invalid rows", len(e.RowErrors))
+}
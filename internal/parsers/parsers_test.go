@@ -0,0 +1,162 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/csye7125/team01/internal/ingest"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// fakeParser is as confident as it is told about every document
+type fakeParser struct {
+	name       string
+	version    int
+	confidence float64
+}
+
+func (p fakeParser) Name() string                 { return p.name }
+func (p fakeParser) Version() int                 { return p.version }
+func (p fakeParser) Detect(doc *Document) float64 { return p.confidence }
+func (p fakeParser) Parse(ctx context.Context, doc *Document) (*Parsed, error) {
+	return &Parsed{}, nil
+}
+
+// pdfDocument is a PDF whose pages are already extracted
+func pdfDocument(pages ...store.TracePage) *Document {
+	doc := &Document{FileName: "trace.pdf", Format: ingest.FormatPDF}
+	doc.pagesOnce.Do(func() { doc.pages = pages })
+	return doc
+}
+
+func TestRegistryMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		parsers []Parser
+		want    Info
+		wantOK  bool
+	}{
+		{"none registered", nil, Info{}, false},
+		{"nobody confident", []Parser{fakeParser{"a", 1, 0}, fakeParser{"b", 1, -1}}, Info{}, false},
+		{"most confident wins", []Parser{fakeParser{"a", 1, 0.3}, fakeParser{"b", 1, 0.8}, fakeParser{"c", 1, 0.1}}, Info{"b", 1}, true},
+		{"ties go to the newer version", []Parser{fakeParser{"a", 1, 0.5}, fakeParser{"a", 2, 0.5}, fakeParser{"a", 3, 0.4}}, Info{"a", 2}, true},
+		{"ties at one version keep the first", []Parser{fakeParser{"a", 1, 0.5}, fakeParser{"b", 1, 0.5}}, Info{"a", 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, ok := NewRegistry(tt.parsers...).Match(&Document{})
+			if ok != tt.wantOK {
+				t.Fatalf("Match() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (Info{parser.Name(), parser.Version()}) != tt.want {
+				t.Errorf("Match() = %s v%d, want %s v%d", parser.Name(), parser.Version(), tt.want.Name, tt.want.Version)
+			}
+		})
+	}
+}
+
+func TestRegistryLatestAndList(t *testing.T) {
+	registry := NewRegistry(fakeParser{"b", 1, 0}, fakeParser{"a", 2, 0}, fakeParser{"a", 3, 0}, fakeParser{"a", 1, 0})
+
+	latest, ok := registry.Latest("a")
+	if !ok || latest.Version() != 3 {
+		t.Errorf("Latest(a) = %v, %v, want version 3", latest, ok)
+	}
+	if _, ok := registry.Latest("missing"); ok {
+		t.Error("Latest(missing) found a parser")
+	}
+
+	want := []Info{{"a", 1}, {"a", 2}, {"a", 3}, {"b", 1}}
+	if got := registry.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultRegistryRoutesDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *Document
+		want string
+	}{
+		{"csv", &Document{Format: ingest.FormatCSV}, "spreadsheet"},
+		{"xlsx", &Document{Format: ingest.FormatXLSX}, "spreadsheet"},
+		{"trace report", pdfDocument(store.TracePage{Page: 1, Text: "TRACE Report\nGoals were clear 20 25 4.5 5 0.7"}), "trace-pdf"},
+		{"trace report without scores", pdfDocument(store.TracePage{Page: 1, Text: "TRACE Report\nComments"}), "trace-pdf"},
+		{"other pdf", pdfDocument(store.TracePage{Page: 1, Text: "Syllabus"}), "pdf-text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, ok := DefaultRegistry().Match(tt.doc)
+			if !ok || parser.Name() != tt.want {
+				t.Errorf("Match() = %v, %v, want %s", parser, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := DefaultRegistry().Match(&Document{Format: "txt"}); ok {
+		t.Error("a text file matched a parser")
+	}
+}
+
+func TestTracePDFParserParse(t *testing.T) {
+	doc := pdfDocument(
+		store.TracePage{Page: 1, Text: "TRACE Report\nGoals were clear 20 25 4.5 5 0.7\nWhat did you like?\nThe labs\n\nThe pace"},
+		store.TracePage{Page: 2, Text: "Workload 18 25 3.9 4 1.1\nafter a result"},
+	)
+	parsed, err := tracePDFParser{}.Parse(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantResults := []store.TraceResult{
+		{Question: "Goals were clear", ResponseCount: 20, InvitedCount: 25, Mean: 4.5, Median: 5, StdDev: 0.7},
+		{Question: "Workload", ResponseCount: 18, InvitedCount: 25, Mean: 3.9, Median: 4, StdDev: 1.1},
+	}
+	if !reflect.DeepEqual(parsed.Results, wantResults) {
+		t.Errorf("results = %+v, want %+v", parsed.Results, wantResults)
+	}
+	wantComments := []store.TraceComment{
+		{Question: "What did you like?", Body: "The labs", Page: 1},
+		{Question: "What did you like?", Body: "The pace", Page: 1},
+	}
+	if !reflect.DeepEqual(parsed.Comments, wantComments) {
+		t.Errorf("comments = %+v, want %+v", parsed.Comments, wantComments)
+	}
+	if len(parsed.Pages) != 2 {
+		t.Errorf("pages = %d, want 2", len(parsed.Pages))
+	}
+}
+
+func TestSpreadsheetParserParse(t *testing.T) {
+	profile := ingest.ColumnProfile{
+		Columns: map[string][]string{
+			ingest.FieldQuestion:      {"question"},
+			ingest.FieldResponseCount: {"responses"},
+			ingest.FieldMean:          {"mean"},
+		},
+		Required: []string{ingest.FieldQuestion, ingest.FieldResponseCount, ingest.FieldMean},
+	}
+
+	doc, err := NewDocument("trace.csv", []byte("Question,Responses,Mean\nGoals,20,4.5\n"), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := spreadsheetParser{}.Parse(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Results) != 1 || parsed.Results[0].Question != "Goals" || parsed.Results[0].Mean != 4.5 {
+		t.Errorf("results = %+v, want the Goals row", parsed.Results)
+	}
+
+	doc, err = NewDocument("trace.csv", []byte("Question,Responses,Mean\nGoals,twenty,4.5\n"), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = spreadsheetParser{}.Parse(context.Background(), doc)
+	var validation *ValidationError
+	if !errors.As(err, &validation) || len(validation.RowErrors) != 1 {
+		t.Errorf("Parse() error = %v, want one invalid row", err)
+	}
+}
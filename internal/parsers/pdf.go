@@ -0,0 +1,161 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/csye7125/team01/internal/ingest"
+	"github.com/csye7125/team01/internal/store"
+	"github.com/ledongthuc/pdf"
+)
+
+var (
+	// resultLine is a question followed by responses, invited, mean, median and std dev
+	resultLine = regexp.MustCompile(`^(.*[A-Za-z].*?)\s+(\d+)\s+(\d+)\s+(\d+(?:\.\d+)?)\s+(\d+(?:\.\d+)?)\s+(\d+(?:\.\d+)?)$`)
+	// commentPrompt is an open-ended question that introduces free-text answers
+	commentPrompt = regexp.MustCompile(`(?i)(comment|what .*\?|please .*\?|suggest)`)
+)
+
+// pdfTextParser keeps only the text of a PDF it has no better parser for,
+// so the document can still be searched and asked about
+type pdfTextParser struct{}
+
+func (pdfTextParser) Name() string { return "pdf-text" }
+
+func (pdfTextParser) Version() int { return 1 }
+
+func (pdfTextParser) Detect(doc *Document) float64 {
+	if doc.Format == ingest.FormatPDF {
+		return 0.1
+	}
+	return 0
+}
+
+func (pdfTextParser) Parse(ctx context.Context, doc *Document) (*Parsed, error) {
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil, err
+	}
+	return &Parsed{Pages: pages}, nil
+}
+
+// tracePDFParser reads TRACE report PDFs: score tables become results and the
+// answers to open-ended prompts become comments
+type tracePDFParser struct{}
+
+func (tracePDFParser) Name() string { return "trace-pdf" }
+
+func (tracePDFParser) Version() int { return 1 }
+
+func (tracePDFParser) Detect(doc *Document) float64 {
+	if doc.Format != ingest.FormatPDF {
+		return 0
+	}
+	text := doc.Text()
+	if !strings.Contains(strings.ToUpper(text), "TRACE") {
+		return 0
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if resultLine.MatchString(strings.TrimSpace(line)) {
+			return 0.8
+		}
+	}
+	return 0.3
+}
+
+func (tracePDFParser) Parse(ctx context.Context, doc *Document) (*Parsed, error) {
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &Parsed{Pages: pages}
+	prompt := ""
+	for _, page := range pages {
+		for _, line := range strings.Split(page.Text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if match := resultLine.FindStringSubmatch(line); match != nil {
+				parsed.Results = append(parsed.Results, resultFromMatch(match))
+				prompt = ""
+				continue
+			}
+			if commentPrompt.MatchString(line) {
+				prompt = line
+				continue
+			}
+			if prompt != "" {
+				parsed.Comments = append(parsed.Comments, store.TraceComment{
+					Question: prompt,
+					Body:     line,
+					Page:     page.Page,
+				})
+			}
+		}
+	}
+	return parsed, nil
+}
+
+func resultFromMatch(match []string) store.TraceResult {
+	responses, _ := strconv.Atoi(match[2])
+	invited, _ := strconv.Atoi(match[3])
+	mean, _ := strconv.ParseFloat(match[4], 64)
+	median, _ := strconv.ParseFloat(match[5], 64)
+	stdDev, _ := strconv.ParseFloat(match[6], 64)
+	return store.TraceResult{
+		Question:      strings.TrimSpace(match[1]),
+		ResponseCount: responses,
+		InvitedCount:  invited,
+		Mean:          mean,
+		Median:        median,
+		StdDev:        stdDev,
+	}
+}
+
+// extractPDFPages returns the text of every page, one line per text row
+func extractPDFPages(data []byte) (pages []store.TracePage, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			pages, err = nil, fmt.Errorf("failed to read PDF: %v", recovered)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	for number := 1; number <= reader.NumPage(); number++ {
+		page := reader.Page(number)
+		if page.V.IsNull() {
+			continue
+		}
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", number, err)
+		}
+		// PDF coordinates grow upwards, so the top row has the largest position
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Position > rows[j].Position })
+
+		var lines []string
+		for _, row := range rows {
+			var words []string
+			for _, text := range row.Content {
+				words = append(words, text.S)
+			}
+			if line := strings.TrimSpace(strings.Join(words, " ")); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		pages = append(pages, store.TracePage{Page: number, Text: strings.Join(lines, "\n")})
+	}
+	return pages, nil
+}
@@ -0,0 +1,80 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/csye7125/team01/internal/retrieval"
+	"github.com/csye7125/team01/internal/sentiment"
+	"github.com/csye7125/team01/internal/store"
+)
+
+// ErrNoParser is returned when no registered parser accepts a document
+var ErrNoParser = errors.New("no parser can handle this document")
+
+// Pipeline parses trace documents and stores what they contain
+type Pipeline struct {
+	Storage   *store.Storage
+	Registry  *Registry
+	Retriever retrieval.Retriever
+	Analyzer  *sentiment.Analyzer
+}
+
+func NewPipeline(storage *store.Storage, registry *Registry, retriever retrieval.Retriever, analyzer *sentiment.Analyzer) *Pipeline {
+	return &Pipeline{Storage: storage, Registry: registry, Retriever: retriever, Analyzer: analyzer}
+}
+
+// Parse routes a document to the best matching parser
+func (p *Pipeline) Parse(ctx context.Context, doc *Document) (Parser, *Parsed, error) {
+	parser, ok := p.Registry.Match(doc)
+	if !ok {
+		return nil, nil, ErrNoParser
+	}
+	parsed, err := parser.Parse(ctx, doc)
+	return parser, parsed, err
+}
+
+// Save attaches parsed content to its trace and records which parser produced it.
+// Everything is written in one transaction, so a failed save leaves the previous content in place.
+func (p *Pipeline) Save(ctx context.Context, trace *store.Trace, parser Parser, parsed *Parsed) error {
+	return p.Storage.WithTransaction(ctx, func(ctx context.Context) error {
+		for i := range parsed.Results {
+			parsed.Results[i].CourseID = trace.CourseID
+		}
+		if err := p.Storage.Results.ReplaceResults(ctx, trace.TraceID, parsed.Results); err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
+		}
+
+		for i := range parsed.Comments {
+			parsed.Comments[i].CourseID = trace.CourseID
+		}
+		if err := p.Storage.Comments.ReplaceComments(ctx, trace.TraceID, parsed.Comments); err != nil {
+			return fmt.Errorf("failed to save comments: %w", err)
+		}
+		if err := sentiment.AnalyzeTrace(ctx, p.Storage, p.Analyzer, trace); err != nil {
+			return fmt.Errorf("failed to analyse comments: %w", err)
+		}
+
+		if err := retrieval.SaveTraceText(ctx, p.Storage, p.Retriever, trace, parsed.Pages); err != nil {
+			return fmt.Errorf("failed to index text: %w", err)
+		}
+
+		return p.Storage.Traces.RecordParse(ctx, trace, parser.Name(), parser.Version(), store.ParseStatusParsed, "")
+	})
+}
+
+// RecordFailure notes on the trace that parsing did not succeed
+func (p *Pipeline) RecordFailure(ctx context.Context, trace *store.Trace, parser Parser, cause error) error {
+	if parser == nil {
+		return p.Storage.Traces.RecordParse(ctx, trace, "", 0, store.ParseStatusUnsupported, cause.Error())
+	}
+	return p.Storage.Traces.RecordParse(ctx, trace, parser.Name(), parser.Version(), store.ParseStatusFailed, cause.Error())
+}
+
+// RecordUnsupported notes on the trace that parser does not handle its
+// document, so reparses with this version of parser pass the trace over
+func (p *Pipeline) RecordUnsupported(ctx context.Context, trace *store.Trace, parser Parser) error {
+	return p.Storage.Traces.RecordParse(ctx, trace, parser.Name(), parser.Version(), store.ParseStatusUnsupported,
+		fmt.Sprintf("%s does not handle this document", parser.Name()))
+}
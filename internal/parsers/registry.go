@@ -0,0 +1,94 @@
+package parsers
+
+import (
+	"sort"
+	"sync"
+)
+
+// Info describes a registered parser
+type Info struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// Registry holds the available parsers. Several versions of one parser may be
+// registered; Match weighs them by confidence and then by version.
+type Registry struct {
+	mu      sync.RWMutex
+	parsers []Parser
+}
+
+func NewRegistry(parsers ...Parser) *Registry {
+	registry := &Registry{}
+	for _, parser := range parsers {
+		registry.Register(parser)
+	}
+	return registry
+}
+
+// DefaultRegistry holds the parsers shipped with the service
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		spreadsheetParser{},
+		tracePDFParser{},
+		pdfTextParser{},
+	)
+}
+
+func (r *Registry) Register(parser Parser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parsers = append(r.parsers, parser)
+}
+
+// Match returns the parser most confident it can handle the document
+func (r *Registry) Match(doc *Document) (Parser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best Parser
+	bestConfidence := 0.0
+	for _, parser := range r.parsers {
+		confidence := parser.Detect(doc)
+		if confidence <= 0 {
+			continue
+		}
+		if best == nil || confidence > bestConfidence ||
+			(confidence == bestConfidence && parser.Version() > best.Version()) {
+			best, bestConfidence = parser, confidence
+		}
+	}
+	return best, best != nil
+}
+
+// Latest returns the newest registered version of the named parser
+func (r *Registry) Latest(name string) (Parser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest Parser
+	for _, parser := range r.parsers {
+		if parser.Name() == name && (latest == nil || parser.Version() > latest.Version()) {
+			latest = parser
+		}
+	}
+	return latest, latest != nil
+}
+
+// List describes every registered parser, sorted by name and version
+func (r *Registry) List() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]Info, 0, len(r.parsers))
+	for _, parser := range r.parsers {
+		infos = append(infos, Info{Name: parser.Name(), Version: parser.Version()})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].Version < infos[j].Version
+	})
+	return infos
+}
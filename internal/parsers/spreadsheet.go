@@ -0,0 +1,33 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/csye7125/team01/internal/ingest"
+)
+
+// spreadsheetParser maps CSV and XLSX exports through the document's column profile
+type spreadsheetParser struct{}
+
+func (spreadsheetParser) Name() string { return "spreadsheet" }
+
+func (spreadsheetParser) Version() int { return 1 }
+
+func (spreadsheetParser) Detect(doc *Document) float64 {
+	if doc.Format == ingest.FormatCSV || doc.Format == ingest.FormatXLSX {
+		return 1
+	}
+	return 0
+}
+
+func (spreadsheetParser) Parse(ctx context.Context, doc *Document) (*Parsed, error) {
+	results, rowErrors, err := ingest.ParseSpreadsheet(bytes.NewReader(doc.Data), doc.Format, doc.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrors) > 0 {
+		return nil, &ValidationError{RowErrors: rowErrors}
+	}
+	return &Parsed{Results: results}, nil
+}
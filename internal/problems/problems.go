@@ -0,0 +1,191 @@
+package problems
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ContentType is the media type of RFC 7807 problem documents
+const ContentType = "application/problem+json"
+
+// FieldError points at one invalid part of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem document
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Extensions are additional members specific to one problem type
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	raw, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return raw, err
+	}
+	members := map[string]interface{}{}
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// Error is an error that knows how it should be reported to clients
+type Error struct {
+	Status     int
+	Type       string
+	Detail     string
+	Fields     []FieldError
+	Extensions map[string]interface{}
+	cause      error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Detail + ": " + e.cause.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Wrap records the underlying error. Database errors with a meaning of their
+// own, such as missing rows, decide the reported status.
+func (e *Error) Wrap(err error) *Error {
+	e.cause = err
+	return e
+}
+
+// With adds an extension member to the problem document
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = map[string]interface{}{}
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func New(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
+
+func BadRequest(detail string) *Error { return New(http.StatusBadRequest, detail) }
+
+func Unauthorized(detail string) *Error { return New(http.StatusUnauthorized, detail) }
+
+func Forbidden(detail string) *Error { return New(http.StatusForbidden, detail) }
+
+func NotFound(detail string) *Error { return New(http.StatusNotFound, detail) }
+
+func Conflict(detail string) *Error { return New(http.StatusConflict, detail) }
+
+func Unprocessable(detail string) *Error { return New(http.StatusUnprocessableEntity, detail) }
+
+func PreconditionFailed(detail string) *Error { return New(http.StatusPreconditionFailed, detail) }
+
+func PreconditionRequired(detail string) *Error { return New(http.StatusPreconditionRequired, detail) }
+
+func Internal(detail string) *Error { return New(http.StatusInternalServerError, detail) }
+
+// Invalid reports a request that failed validation, field by field
+func Invalid(detail string, fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: "validation-error", Detail: detail, Fields: fields}
+}
+
+// From resolves any error to the problem reported for it
+func From(err error) *Error {
+	var problem *Error
+	if errors.As(err, &problem) {
+		if problem.cause == nil {
+			return problem
+		}
+		if status, ok := storeStatus(problem.cause); ok {
+			return &Error{Status: status, Type: problem.Type, Detail: problem.Detail, Fields: problem.Fields, Extensions: problem.Extensions, cause: problem.cause}
+		}
+		if problem.Status >= http.StatusInternalServerError {
+			return problem
+		}
+		// 🔹 A lookup that failed for reasons other than a missing row is a server error
+		return &Error{Status: http.StatusInternalServerError, Detail: "An unexpected error occurred", cause: problem.cause}
+	}
+	if status, ok := storeStatus(err); ok {
+		return &Error{Status: status, cause: err}
+	}
+	return &Error{Status: http.StatusInternalServerError, Detail: "An unexpected error occurred", cause: err}
+}
+
+// storeStatus maps database errors that clients can act on
+func storeStatus(err error) (int, bool) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound, true
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return http.StatusConflict, true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return http.StatusConflict, true
+		case "23503": // foreign_key_violation
+			return http.StatusConflict, true
+		}
+	}
+	return 0, false
+}
+
+// Write sends err as a problem document
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	problem := From(err)
+	document := Problem{
+		Type:       typeURI(problem),
+		Title:      http.StatusText(problem.Status),
+		Status:     problem.Status,
+		Detail:     problem.Detail,
+		Instance:   r.URL.Path,
+		Errors:     problem.Fields,
+		Extensions: problem.Extensions,
+	}
+	if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+		document.TraceID = spanContext.TraceID().String()
+	}
+	if problem.Status >= http.StatusInternalServerError {
+		log.Printf("❌ %s %s failed (trace %s): %v", r.Method, r.URL.Path, document.TraceID, problem)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(document)
+}
+
+// typeURI identifies the problem type, derived from the status unless set explicitly
+func typeURI(problem *Error) string {
+	name := problem.Type
+	if name == "" {
+		name = strings.ToLower(strings.ReplaceAll(http.StatusText(problem.Status), " ", "-"))
+	}
+	return "/problems/" + name
+}
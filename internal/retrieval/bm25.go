@@ -0,0 +1,154 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// BM25Retriever ranks chunks with Okapi BM25. Chunks and their term frequencies are
+// persisted in Postgres; each course's inverted index is built in memory on first use
+// and rebuilt whenever the course's chunks change, including from another replica.
+type BM25Retriever struct {
+	texts *store.TraceTextStore
+
+	mu      sync.Mutex
+	indexes map[uint]*courseIndex
+}
+
+type posting struct {
+	chunk     int
+	frequency int
+}
+
+// courseIndex is the inverted index of one course's chunks
+type courseIndex struct {
+	version       store.ChunkIndexVersion
+	chunks        []store.TraceChunk
+	postings      map[string][]posting
+	averageLength float64
+}
+
+func NewBM25Retriever(texts *store.TraceTextStore) *BM25Retriever {
+	return &BM25Retriever{texts: texts, indexes: map[uint]*courseIndex{}}
+}
+
+func (r *BM25Retriever) IndexTrace(ctx context.Context, trace *store.Trace, pages []store.TracePage) error {
+	if err := r.texts.ReplaceChunks(ctx, trace.TraceID, Chunk(trace, pages)); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.indexes, trace.CourseID)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *BM25Retriever) Search(ctx context.Context, courseID uint, query string, limit int) ([]Passage, error) {
+	index, err := r.courseIndex(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return index.search(query, limit), nil
+}
+
+// search ranks the chunks of the index against query
+func (index *courseIndex) search(query string, limit int) []Passage {
+	passages := []Passage{}
+	if len(index.chunks) == 0 {
+		return passages
+	}
+
+	// 🔹 Accumulate BM25 scores over the postings of each distinct query term
+	scores := map[int]float64{}
+	seen := map[string]bool{}
+	total := float64(len(index.chunks))
+	for _, term := range Analyze(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := index.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.frequency)
+			length := float64(index.chunks[p.chunk].Length)
+			scores[p.chunk] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/index.averageLength))
+		}
+	}
+
+	for position, score := range scores {
+		chunk := index.chunks[position]
+		passages = append(passages, Passage{
+			TraceChunkID: chunk.TraceChunkID,
+			TraceID:      chunk.TraceID,
+			Page:         chunk.Page,
+			Text:         chunk.Text,
+			Score:        score,
+		})
+	}
+	sort.Slice(passages, func(i, j int) bool {
+		if passages[i].Score != passages[j].Score {
+			return passages[i].Score > passages[j].Score
+		}
+		return passages[i].TraceChunkID < passages[j].TraceChunkID
+	})
+	if len(passages) > limit {
+		passages = passages[:limit]
+	}
+	return passages
+}
+
+// courseIndex returns the cached index of a course, rebuilding it if its chunks changed
+func (r *BM25Retriever) courseIndex(ctx context.Context, courseID uint) (*courseIndex, error) {
+	version, err := r.texts.GetChunkIndexVersion(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	cached, ok := r.indexes[courseID]
+	r.mu.Unlock()
+	if ok && cached.version == version {
+		return cached, nil
+	}
+
+	chunks, err := r.texts.GetChunksByCourseID(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	index := buildIndex(chunks)
+	index.version = version
+
+	r.mu.Lock()
+	r.indexes[courseID] = index
+	r.mu.Unlock()
+	return index, nil
+}
+
+func buildIndex(chunks []store.TraceChunk) *courseIndex {
+	index := &courseIndex{chunks: chunks, postings: map[string][]posting{}}
+	totalLength := 0
+	for position, chunk := range chunks {
+		totalLength += chunk.Length
+		for term, frequency := range chunk.Terms {
+			index.postings[term] = append(index.postings[term], posting{chunk: position, frequency: frequency})
+		}
+	}
+	if len(chunks) > 0 {
+		index.averageLength = float64(totalLength) / float64(len(chunks))
+	}
+	return index
+}
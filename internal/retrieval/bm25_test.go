@@ -0,0 +1,134 @@
+package retrieval
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// testIndex indexes one chunk per text, numbered from 1 in order
+func testIndex(texts ...string) *courseIndex {
+	chunks := make([]store.TraceChunk, len(texts))
+	for i, text := range texts {
+		terms := Analyze(text)
+		frequencies := map[string]int{}
+		for _, term := range terms {
+			frequencies[term]++
+		}
+		chunks[i] = store.TraceChunk{TraceChunkID: uint(i + 1), TraceID: 1, Text: text, Terms: frequencies, Length: len(terms)}
+	}
+	return buildIndex(chunks)
+}
+
+func rankedIDs(passages []Passage) []uint {
+	ids := make([]uint, len(passages))
+	for i, passage := range passages {
+		ids[i] = passage.TraceChunkID
+	}
+	return ids
+}
+
+func TestSearchScore(t *testing.T) {
+	// 🔹 One match in one of two chunks of average length: idf = ln 2 and the
+	// term frequency part is (k1 + 1) / (1 + k1) = 1
+	passages := testIndex("lectures recorded", "exams graded").search("lectures", 10)
+	if len(passages) != 1 || passages[0].TraceChunkID != 1 {
+		t.Fatalf("search returned %v, want chunk 1", rankedIDs(passages))
+	}
+	if math.Abs(passages[0].Score-math.Ln2) > 1e-12 {
+		t.Fatalf("score = %v, want ln 2", passages[0].Score)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		query string
+		want  []uint
+	}{
+		{
+			name:  "more occurrences rank higher",
+			texts: []string{"workload fine overall", "workload workload workload heavy", "nothing relevant here"},
+			query: "workload",
+			want:  []uint{2, 1},
+		},
+		{
+			name:  "rare terms outweigh common ones",
+			texts: []string{"grading fair", "grading slow", "grading quick workload heavy"},
+			query: "grading workload",
+			want:  []uint{3, 1, 2},
+		},
+		{
+			name:  "shorter chunks rank higher for the same frequency",
+			texts: []string{"clarity " + strings.Repeat("filler ", 40), "clarity lacking", "unrelated text"},
+			query: "clarity",
+			want:  []uint{2, 1},
+		},
+		{
+			name:  "inflections match their stem",
+			texts: []string{"weekly assignments were long", "quizzes were short"},
+			query: "assignment",
+			want:  []uint{1},
+		},
+		{
+			name:  "equal scores keep chunk order",
+			texts: []string{"labs helpful", "labs helpful", "other words"},
+			query: "labs",
+			want:  []uint{1, 2},
+		},
+		{
+			name:  "repeated query terms count once",
+			texts: []string{"office hours", "exam review"},
+			query: "exam exam exam hours",
+			want:  []uint{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		if got := rankedIDs(testIndex(tt.texts...).search(tt.query, 10)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ranked %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchWithoutMatches(t *testing.T) {
+	index := testIndex("projects were interesting", "exams were hard")
+	for _, query := range []string{"parking", "what did they say", ""} {
+		if passages := index.search(query, 10); passages == nil || len(passages) != 0 {
+			t.Errorf("search(%q) = %v, want an empty list", query, passages)
+		}
+	}
+	if passages := testIndex().search("projects", 10); passages == nil || len(passages) != 0 {
+		t.Errorf("search over no chunks = %v, want an empty list", passages)
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	index := testIndex("feedback quick", "feedback slow", "feedback detailed feedback", "feedback missing")
+	passages := index.search("feedback", 2)
+	if len(passages) != 2 || passages[0].TraceChunkID != 3 {
+		t.Fatalf("search returned %v, want 2 passages led by chunk 3", rankedIDs(passages))
+	}
+}
+
+func TestChunkOverlap(t *testing.T) {
+	words := make([]string, 200)
+	for i := range words {
+		words[i] = "word"
+	}
+	words[100] = "needle"
+	chunks := Chunk(&store.Trace{TraceID: 4, CourseID: 9}, []store.TracePage{{Page: 2, Text: strings.Join(words, " ")}})
+
+	// 🔹 Windows start every chunkWords - chunkOverlap words, so word 100 is in the first two
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks of 200 words, want 2", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Terms["needle"] != 1 || chunk.Page != 2 || chunk.TraceID != 4 || chunk.CourseID != 9 || chunk.Position != i {
+			t.Errorf("chunk %d = %+v", i, chunk)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package retrieval
+
+import (
+	"context"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// Passage is a chunk of trace text matched by a query
+type Passage struct {
+	TraceChunkID uint    `json:"trace_chunk_id"`
+	TraceID      uint    `json:"trace_id"`
+	Page         int     `json:"page"`
+	Text         string  `json:"text"`
+	Score        float64 `json:"score"`
+}
+
+// Retriever indexes trace text and finds the passages most relevant to a question.
+// Implementations own how chunks are represented, so a vector store can replace BM25.
+type Retriever interface {
+	// IndexTrace replaces the indexed passages of a trace with chunks of its pages
+	IndexTrace(ctx context.Context, trace *store.Trace, pages []store.TracePage) error
+	// Search returns up to limit passages of the course's traces, best first
+	Search(ctx context.Context, courseID uint, query string, limit int) ([]Passage, error)
+}
+
+// SaveTraceText stores the extracted pages of a trace and indexes them for retrieval
+func SaveTraceText(ctx context.Context, storage *store.Storage, retriever Retriever, trace *store.Trace, pages []store.TracePage) error {
+	for i := range pages {
+		pages[i].CourseID = trace.CourseID
+	}
+	if err := storage.Texts.ReplacePages(ctx, trace.TraceID, pages); err != nil {
+		return err
+	}
+	return retriever.IndexTrace(ctx, trace, pages)
+}
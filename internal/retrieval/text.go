@@ -0,0 +1,90 @@
+package retrieval
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+const (
+	// chunkWords is the target passage length and chunkOverlap the words shared by neighbours
+	chunkWords   = 120
+	chunkOverlap = 30
+)
+
+var tokenPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+var stopwords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "am": true, "an": true,
+	"and": true, "any": true, "are": true, "as": true, "at": true, "be": true, "been": true,
+	"but": true, "by": true, "can": true, "could": true, "did": true, "do": true, "does": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true, "he": true, "her": true,
+	"his": true, "how": true, "i": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"our": true, "she": true, "so": true, "than": true, "that": true, "the": true, "their": true,
+	"them": true, "there": true, "they": true, "this": true, "to": true, "was": true, "we": true,
+	"were": true, "what": true, "when": true, "which": true, "who": true, "will": true,
+	"with": true, "would": true, "you": true, "your": true, "say": true, "said": true,
+}
+
+// Analyze lowercases, tokenizes, drops stopwords and stems text into index terms
+func Analyze(text string) []string {
+	var terms []string
+	for _, token := range tokenPattern.FindAllString(strings.ToLower(text), -1) {
+		if stopwords[token] {
+			continue
+		}
+		terms = append(terms, stem(token))
+	}
+	return terms
+}
+
+// stem strips common English inflections so "assignments" matches "assignment"
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		return word[:len(word)-3]
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// Chunk splits each page into overlapping word windows with their term statistics
+func Chunk(trace *store.Trace, pages []store.TracePage) []store.TraceChunk {
+	var chunks []store.TraceChunk
+	position := 0
+	for _, page := range pages {
+		words := strings.Fields(page.Text)
+		for start := 0; start < len(words); start += chunkWords - chunkOverlap {
+			end := min(start+chunkWords, len(words))
+			text := strings.Join(words[start:end], " ")
+			terms := Analyze(text)
+			if len(terms) > 0 {
+				frequencies := map[string]int{}
+				for _, term := range terms {
+					frequencies[term]++
+				}
+				chunks = append(chunks, store.TraceChunk{
+					TraceID:  trace.TraceID,
+					CourseID: trace.CourseID,
+					Page:     page.Page,
+					Position: position,
+					Text:     text,
+					Terms:    frequencies,
+					Length:   len(terms),
+				})
+				position++
+			}
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey string
+
+const userKey contextKey = "rpc.user"
+
+// publicMethods can be called without credentials, like their REST counterparts
+var publicMethods = map[string]bool{
+	pb.UserService_CreateUser_FullMethodName:            true,
+	pb.CourseService_GetCourse_FullMethodName:           true,
+	pb.CourseService_ListCourses_FullMethodName:         true,
+	pb.InstructorService_GetInstructor_FullMethodName:   true,
+	pb.InstructorService_ListInstructors_FullMethodName: true,
+}
+
+// authInterceptor authenticates calls from Basic Auth credentials in the
+// authorization metadata. Health checks and reflection stay open.
+type authInterceptor struct {
+	users *store.UserStore
+}
+
+func (a *authInterceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authInterceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate adds the caller to ctx. Credentials are optional on public
+// methods, but credentials that are sent must be valid.
+func (a *authInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, "/"+string(pb.File_team01_v1_team01_proto.Package())+".") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := http.Header{"Authorization": md.Get("authorization")}
+	username, password, ok := (&http.Request{Header: header}).BasicAuth()
+	if !ok {
+		if publicMethods[method] {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing Basic Auth credentials")
+	}
+
+	user, err := a.users.GetUserByCredentials(ctx, username, password)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	return context.WithValue(ctx, userKey, user), nil
+}
+
+// authenticatedStream is a server stream whose context carries the caller
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// caller returns the authenticated user of a call; the interceptor has
+// already rejected anonymous calls to methods that are not public
+func caller(ctx context.Context) *store.User {
+	user, _ := ctx.Value(userKey).(*store.User)
+	return user
+}
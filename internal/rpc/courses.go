@@ -0,0 +1,168 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// courseSorts are the order_by keys of ListCourses
+var courseSorts = map[string]string{
+	"code":          store.CourseSortCode,
+	"name":          store.CourseSortName,
+	"semester_year": store.CourseSortSemesterYear,
+	"credit_hours":  store.CourseSortCreditHours,
+	"created_at":    store.CourseSortDateAdded,
+}
+
+type courseServer struct {
+	pb.UnimplementedCourseServiceServer
+	store *store.Storage
+}
+
+func newCourse(course *store.Course) *pb.Course {
+	return &pb.Course{
+		Id:           uint32(course.ID),
+		Code:         course.Code,
+		Name:         course.Name,
+		Description:  course.Description,
+		SemesterTerm: course.SemesterTerm,
+		SemesterYear: int32(course.SemesterYear),
+		CreditHours:  int32(course.CreditHours),
+		InstructorId: uint32(course.InstructorID),
+		OwnerUserId:  uint32(course.OwnerUserID),
+		CreatedAt:    timestamp(course.DateAdded),
+		UpdatedAt:    timestamp(course.DateLastUpdated),
+		Version:      course.Version,
+	}
+}
+
+func (s *courseServer) GetCourse(ctx context.Context, req *pb.GetCourseRequest) (*pb.Course, error) {
+	course, err := s.store.Courses.GetCourseByID(ctx, uint(req.Id))
+	if err != nil {
+		return nil, storeError(err, "course")
+	}
+	return newCourse(course), nil
+}
+
+func (s *courseServer) ListCourses(ctx context.Context, req *pb.ListCoursesRequest) (*pb.ListCoursesResponse, error) {
+	filter := store.CourseListFilter{
+		Code:         req.Code,
+		SemesterTerm: req.SemesterTerm,
+		SemesterYear: int(req.SemesterYear),
+		InstructorID: uint(req.InstructorId),
+		OwnerUserID:  uint(req.OwnerUserId),
+		Name:         req.Query,
+	}
+	var err error
+	if filter.Limit, err = pageSize(req.PageSize); err != nil {
+		return nil, err
+	}
+	if filter.Sort, filter.Desc, err = orderBy(req.OrderBy, "code", courseSorts); err != nil {
+		return nil, err
+	}
+	if filter.After, err = pageToken(req.PageToken, filter.Sort, filter.Desc); err != nil {
+		return nil, err
+	}
+
+	list, err := s.store.Courses.ListCourses(ctx, filter)
+	if err != nil {
+		return nil, storeError(err, "courses")
+	}
+	resp := &pb.ListCoursesResponse{NextPageToken: nextPageToken(list.Next), TotalSize: list.Total}
+	for i := range list.Courses {
+		resp.Courses = append(resp.Courses, newCourse(&list.Courses[i]))
+	}
+	return resp, nil
+}
+
+func (s *courseServer) CreateCourse(ctx context.Context, req *pb.CreateCourseRequest) (*pb.Course, error) {
+	course := &store.Course{OwnerUserID: caller(ctx).ID}
+	dto.CourseRequest{
+		Code:         req.Code,
+		Name:         req.Name,
+		Description:  req.Description,
+		SemesterTerm: req.SemesterTerm,
+		SemesterYear: int(req.SemesterYear),
+		CreditHours:  int(req.CreditHours),
+		InstructorID: uint(req.InstructorId),
+	}.Apply(course)
+	if fieldErrors := dto.ValidateCourse(course); len(fieldErrors) > 0 {
+		return nil, invalid("Invalid course", fieldErrors...)
+	}
+	if err := s.store.Instructors.CheckInstructorExists(ctx, course.InstructorID); err != nil {
+		return nil, invalid("Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"})
+	}
+
+	if err := s.store.Courses.CreateCourse(ctx, course); err != nil {
+		return nil, storeError(err, "course")
+	}
+	return newCourse(course), nil
+}
+
+func (s *courseServer) UpdateCourse(ctx context.Context, req *pb.UpdateCourseRequest) (*pb.Course, error) {
+	course, err := s.courseForWrite(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔹 Replace every field owners may set
+	update := dto.CourseRequest{
+		Code:         req.Code,
+		Name:         req.Name,
+		Description:  req.Description,
+		SemesterTerm: req.SemesterTerm,
+		SemesterYear: int(req.SemesterYear),
+		CreditHours:  int(req.CreditHours),
+		InstructorID: uint(req.InstructorId),
+	}
+	instructorID := course.InstructorID
+	update.Apply(course)
+	if fieldErrors := dto.ValidateCourse(course); len(fieldErrors) > 0 {
+		return nil, invalid("Invalid course", fieldErrors...)
+	}
+	if course.InstructorID != instructorID {
+		if err := s.store.Instructors.CheckInstructorExists(ctx, course.InstructorID); err != nil {
+			return nil, invalid("Invalid course", problems.FieldError{Field: "instructor_id", Message: "does not exist"})
+		}
+	}
+
+	changes := update.Columns()
+	if err := s.store.Courses.PatchCourse(ctx, course.ID, course.Version, changes); err != nil {
+		return nil, storeError(err, "course")
+	}
+	course.Version++
+	course.DateLastUpdated = changes["date_last_updated"].(time.Time)
+	return newCourse(course), nil
+}
+
+func (s *courseServer) DeleteCourse(ctx context.Context, req *pb.DeleteCourseRequest) (*emptypb.Empty, error) {
+	course, err := s.courseForWrite(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Courses.DeleteCourse(ctx, course.ID, course.Version); err != nil {
+		return nil, storeError(err, "course")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// courseForWrite loads the course a write targets after checking that the
+// caller owns it and that it is still at version
+func (s *courseServer) courseForWrite(ctx context.Context, id uint32, version int64) (*store.Course, error) {
+	course, err := s.store.Courses.GetCourseByID(ctx, uint(id))
+	if err != nil {
+		return nil, storeError(err, "course")
+	}
+	if caller(ctx).ID != course.OwnerUserID {
+		return nil, status.Error(codes.PermissionDenied, "only the owner can change this course")
+	}
+	return course, checkVersion(version, course.Version)
+}
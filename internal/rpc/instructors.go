@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type instructorServer struct {
+	pb.UnimplementedInstructorServiceServer
+	store *store.Storage
+}
+
+func newInstructor(instructor *store.Instructor) *pb.Instructor {
+	return &pb.Instructor{
+		Id:        uint32(instructor.InstructorID),
+		Name:      instructor.Name,
+		UserId:    uint32(instructor.UserID),
+		CreatedAt: timestamp(instructor.DateCreated),
+		UpdatedAt: timestamp(instructor.DateUpdated),
+		Version:   instructor.Version,
+	}
+}
+
+func (s *instructorServer) GetInstructor(ctx context.Context, req *pb.GetInstructorRequest) (*pb.Instructor, error) {
+	instructor, err := s.store.Instructors.GetInstructorByID(ctx, strconv.FormatUint(uint64(req.Id), 10))
+	if err != nil {
+		return nil, storeError(err, "instructor")
+	}
+	return newInstructor(instructor), nil
+}
+
+func (s *instructorServer) ListInstructors(ctx context.Context, req *pb.ListInstructorsRequest) (*pb.ListInstructorsResponse, error) {
+	filter := store.InstructorListFilter{Name: strings.TrimSpace(req.Query), Sort: store.InstructorSortName}
+	if filter.Name != "" {
+		filter.Sort, filter.Desc = store.InstructorSortRelevance, true
+	}
+	var err error
+	if filter.Limit, err = pageSize(req.PageSize); err != nil {
+		return nil, err
+	}
+	if filter.After, err = pageToken(req.PageToken, filter.Sort, filter.Desc); err != nil {
+		return nil, err
+	}
+
+	list, err := s.store.Instructors.ListInstructors(ctx, filter)
+	if err != nil {
+		return nil, storeError(err, "instructors")
+	}
+	resp := &pb.ListInstructorsResponse{NextPageToken: nextPageToken(list.Next), TotalSize: list.Total}
+	for i := range list.Instructors {
+		resp.Instructors = append(resp.Instructors, newInstructor(&list.Instructors[i]))
+	}
+	return resp, nil
+}
+
+func (s *instructorServer) CreateInstructor(ctx context.Context, req *pb.CreateInstructorRequest) (*pb.Instructor, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, invalid("Invalid instructor", problems.FieldError{Field: "name", Message: "is required"})
+	}
+
+	// 🔹 The instructor is linked to the caller
+	instructor := &store.Instructor{}
+	dto.InstructorRequest{Name: req.Name}.Apply(instructor)
+	if err := s.store.Instructors.CreateInstructor(ctx, caller(ctx).Username, instructor); err != nil {
+		return nil, storeError(err, "instructor")
+	}
+	return newInstructor(instructor), nil
+}
+
+func (s *instructorServer) UpdateInstructor(ctx context.Context, req *pb.UpdateInstructorRequest) (*pb.Instructor, error) {
+	instructor, err := s.instructorForWrite(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	update := dto.InstructorRequest{Name: req.Name}
+	update.Apply(instructor)
+	if strings.TrimSpace(instructor.Name) == "" {
+		return nil, invalid("Invalid instructor", problems.FieldError{Field: "name", Message: "is required"})
+	}
+
+	changes := update.Columns()
+	if err := s.store.Instructors.PatchInstructor(ctx, strconv.FormatUint(uint64(instructor.InstructorID), 10), instructor.Version, changes); err != nil {
+		return nil, storeError(err, "instructor")
+	}
+	instructor.Version++
+	instructor.DateUpdated = changes["date_updated"].(time.Time)
+	return newInstructor(instructor), nil
+}
+
+func (s *instructorServer) DeleteInstructor(ctx context.Context, req *pb.DeleteInstructorRequest) (*emptypb.Empty, error) {
+	instructor, err := s.instructorForWrite(ctx, req.Id, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Instructors.DeleteInstructor(ctx, strconv.FormatUint(uint64(instructor.InstructorID), 10), instructor.Version); err != nil {
+		return nil, storeError(err, "instructor")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// instructorForWrite loads the instructor a write targets after checking that
+// it is still at version. Like REST, any authenticated user may change it.
+func (s *instructorServer) instructorForWrite(ctx context.Context, id uint32, version int64) (*store.Instructor, error) {
+	instructor, err := s.store.Instructors.GetInstructorByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, storeError(err, "instructor")
+	}
+	return instructor, checkVersion(version, instructor.Version)
+}
@@ -0,0 +1,157 @@
+// Package rpc serves the gRPC API. Its services read and write the same
+// store.Storage as the REST handlers and follow the same authorization rules.
+package rpc
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/csye7125/team01 --go-grpc_out=../.. --go-grpc_opt=module=github.com/csye7125/team01 team01/v1/team01.proto
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/parsers"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 25
+	maxPageSize     = 100
+)
+
+// NewServer registers the API services on a gRPC server together with the
+// standard health and reflection services
+func NewServer(storage *store.Storage, bucketName string, pipeline *parsers.Pipeline) *grpc.Server {
+	auth := &authInterceptor{users: storage.Users}
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+
+	pb.RegisterUserServiceServer(server, &userServer{store: storage})
+	pb.RegisterCourseServiceServer(server, &courseServer{store: storage})
+	pb.RegisterInstructorServiceServer(server, &instructorServer{store: storage})
+	pb.RegisterTraceServiceServer(server, &traceServer{store: storage, bucketName: bucketName, pipeline: pipeline})
+
+	// 🔹 Every service reports SERVING for as long as the process runs
+	healthServer := health.NewServer()
+	for name := range server.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	return server
+}
+
+// catalogConflictMessage answers course writes that may not change shared catalog details
+const catalogConflictMessage = "code, name, description and credit hours must match the catalog course unless the course owner owns it or one of its offerings; change them on the catalog course"
+
+// errModified is the gRPC form of a failed If-Match
+var errModified = status.Error(codes.FailedPrecondition, "the resource has changed since it was read; fetch it again and retry")
+
+// invalid rejects a request with the failing fields as BadRequest details
+func invalid(message string, fieldErrors ...problems.FieldError) error {
+	details := &errdetails.BadRequest{}
+	for _, fieldError := range fieldErrors {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fieldError.Field,
+			Description: fieldError.Message,
+		})
+	}
+	st, err := status.New(codes.InvalidArgument, message).WithDetails(details)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
+
+// storeError maps a failed store call to a status, logging what the client does not see
+func storeError(err error, what string) error {
+	switch {
+	case errors.Is(err, store.ErrVersionMismatch):
+		return errModified
+	case errors.Is(err, store.ErrCatalogConflict):
+		return status.Error(codes.FailedPrecondition, catalogConflictMessage)
+	case errors.Is(err, store.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, what+" not found")
+	}
+	log.Printf("❌ gRPC call failed on %s: %v", what, err)
+	return status.Error(codes.Internal, "could not process "+what)
+}
+
+// checkVersion is If-Match for gRPC: 0 skips the check
+func checkVersion(requested, current int64) error {
+	if requested != 0 && requested != current {
+		return errModified
+	}
+	return nil
+}
+
+// pageSize reads a page size, where 0 asks for the default
+func pageSize(size int32) (int, error) {
+	if size == 0 {
+		return defaultPageSize, nil
+	}
+	if size < 1 || size > maxPageSize {
+		return 0, invalid("Invalid page size", problems.FieldError{Field: "page_size", Message: fmt.Sprintf("must be between 1 and %d", maxPageSize)})
+	}
+	return int(size), nil
+}
+
+// orderBy reads a sort key like "name" or "-name" against the keys in sorts,
+// which map the API's names to the store's
+func orderBy(value, fallback string, sorts map[string]string) (string, bool, error) {
+	if value == "" {
+		value = fallback
+	}
+	name, desc := strings.CutPrefix(value, "-")
+	sort, ok := sorts[name]
+	if !ok {
+		return "", false, invalid("Invalid order", problems.FieldError{Field: "order_by", Message: "is not a sort key"})
+	}
+	return sort, desc, nil
+}
+
+// pageToken decodes a page_token, which must come from a page in the same order
+func pageToken(token, sort string, desc bool) (*store.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	cursor, err := store.DecodeCursor(token)
+	if err != nil || cursor.Sort != sort || cursor.Desc != desc {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	return cursor, nil
+}
+
+// nextPageToken encodes the cursor of the next page, or "" on the last page
+func nextPageToken(next *store.Cursor) string {
+	if next == nil {
+		return ""
+	}
+	return next.Encode()
+}
+
+// timestamp converts a time for a response, leaving unset times out
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
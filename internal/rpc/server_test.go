@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/csye7125/team01/internal/dbtest"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testUsername = "ada@example.com"
+	testPassword = "s3cret-Passw0rd"
+)
+
+// testConn serves the API over an in-memory listener from a database that
+// knows one user, 1, and one course, 7, owned by ownerUserID
+func testConn(t *testing.T, ownerUserID int64) (*grpc.ClientConn, *dbtest.Driver) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, scripted := dbtest.Open(t,
+		dbtest.Result{
+			Match:   `FROM "users"`,
+			Columns: []string{"id", "first_name", "last_name", "password", "username", "version"},
+			Rows:    [][]driver.Value{{int64(1), "Ada", "Lovelace", string(hash), testUsername, int64(1)}},
+		},
+		dbtest.Result{
+			Match:   `FROM "courses"`,
+			Columns: []string{"course_id", "code", "name", "owner_user_id", "instructor_id", "version"},
+			Rows:    [][]driver.Value{{int64(7), "CSYE7125", "Advanced Cloud", ownerUserID, int64(3), int64(4)}},
+		},
+	)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(store.NewStorage(db), "", nil)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, scripted
+}
+
+// withBasicAuth sends Basic Auth credentials with the calls made with ctx
+func withBasicAuth(ctx context.Context, username, password string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+credentials)
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestAnonymousCalls(t *testing.T) {
+	conn, _ := testConn(t, 1)
+	ctx := testContext(t)
+
+	course, err := pb.NewCourseServiceClient(conn).GetCourse(ctx, &pb.GetCourseRequest{Id: 7})
+	if err != nil || course.Code != "CSYE7125" {
+		t.Fatalf("anonymous GetCourse = %v, %v, want the course", course, err)
+	}
+
+	if _, err := pb.NewUserServiceClient(conn).GetUser(ctx, &pb.GetUserRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("anonymous GetUser error = %v, want Unauthenticated", err)
+	}
+	if _, err := pb.NewCourseServiceClient(conn).DeleteCourse(ctx, &pb.DeleteCourseRequest{Id: 7}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("anonymous DeleteCourse error = %v, want Unauthenticated", err)
+	}
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("anonymous health check = %v, %v, want SERVING", health, err)
+	}
+}
+
+func TestBadCredentials(t *testing.T) {
+	conn, _ := testConn(t, 1)
+	ctx := withBasicAuth(testContext(t), testUsername, "wrong")
+
+	// 🔹 Credentials that are sent must be valid, even for public methods
+	if _, err := pb.NewCourseServiceClient(conn).GetCourse(ctx, &pb.GetCourseRequest{Id: 7}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetCourse with a wrong password error = %v, want Unauthenticated", err)
+	}
+	if _, err := pb.NewUserServiceClient(conn).GetUser(ctx, &pb.GetUserRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser with a wrong password error = %v, want Unauthenticated", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(testContext(t), "authorization", "Bearer token")
+	if _, err := pb.NewUserServiceClient(conn).GetUser(ctx, &pb.GetUserRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser without Basic Auth error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestCourseWritesNeedTheOwner(t *testing.T) {
+	conn, scripted := testConn(t, 2)
+	ctx := withBasicAuth(testContext(t), testUsername, testPassword)
+	courses := pb.NewCourseServiceClient(conn)
+
+	if _, err := courses.UpdateCourse(ctx, &pb.UpdateCourseRequest{Id: 7, Code: "CSYE7125", Name: "Renamed"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("UpdateCourse by a non-owner error = %v, want PermissionDenied", err)
+	}
+	if _, err := courses.DeleteCourse(ctx, &pb.DeleteCourseRequest{Id: 7}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("DeleteCourse by a non-owner error = %v, want PermissionDenied", err)
+	}
+	if scripted.Ran(`UPDATE "courses"`) || scripted.Ran(`DELETE FROM "courses"`) {
+		t.Errorf("a non-owner changed the course: %q", scripted.Statements())
+	}
+}
+
+func TestCourseWritesCheckTheVersion(t *testing.T) {
+	conn, scripted := testConn(t, 1)
+	ctx := withBasicAuth(testContext(t), testUsername, testPassword)
+
+	_, err := pb.NewCourseServiceClient(conn).DeleteCourse(ctx, &pb.DeleteCourseRequest{Id: 7, Version: 3})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteCourse at a stale version error = %v, want FailedPrecondition", err)
+	}
+	if scripted.Ran(`DELETE FROM "courses"`) {
+		t.Error("a stale delete reached the database")
+	}
+}
@@ -0,0 +1,255 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/csye7125/team01/internal/ingest"
+	"github.com/csye7125/team01/internal/parsers"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// maxUploadSize matches the multipart limit of REST uploads
+const maxUploadSize = 50 << 20
+
+// traceSorts are the order_by keys of ListTraces
+var traceSorts = map[string]string{
+	"date_created": store.TraceSortDateCreated,
+	"file_name":    store.TraceSortFileName,
+}
+
+type traceServer struct {
+	pb.UnimplementedTraceServiceServer
+	store      *store.Storage
+	bucketName string
+	pipeline   *parsers.Pipeline
+}
+
+func newTrace(trace *store.Trace) *pb.Trace {
+	resp := &pb.Trace{
+		Id:            uint32(trace.TraceID),
+		CourseId:      uint32(trace.CourseID),
+		UserId:        uint32(trace.UserID),
+		FileName:      trace.FileName,
+		CreatedAt:     timestamp(trace.DateCreated),
+		ParseStatus:   trace.ParseStatus,
+		ParserName:    trace.ParserName,
+		ParserVersion: int32(trace.ParserVersion),
+		ParseError:    trace.ParseError,
+	}
+	if trace.DateParsed != nil {
+		resp.ParsedAt = timestamp(*trace.DateParsed)
+	}
+	return resp
+}
+
+func (s *traceServer) UploadTrace(stream grpc.ClientStreamingServer[pb.UploadTraceRequest, pb.Trace]) error {
+	ctx := stream.Context()
+
+	// 🔹 The first message says what is uploaded
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	metadata := first.GetMetadata()
+	if metadata == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the upload metadata")
+	}
+	var fieldErrors []problems.FieldError
+	if metadata.CourseId == 0 {
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: "metadata.course_id", Message: "is required"})
+	}
+	if strings.TrimSpace(metadata.FileName) == "" {
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: "metadata.file_name", Message: "is required"})
+	}
+	if len(fieldErrors) > 0 {
+		return invalid("Invalid upload", fieldErrors...)
+	}
+	profile, err := columnProfile(metadata.Profile)
+	if err != nil {
+		return err
+	}
+
+	// 🔹 The rest is the file
+	var data bytes.Buffer
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetMetadata() != nil {
+			return status.Error(codes.InvalidArgument, "the upload metadata may only be sent once")
+		}
+		if data.Len()+len(req.GetChunk()) > maxUploadSize {
+			return status.Errorf(codes.ResourceExhausted, "files are limited to %d MiB", maxUploadSize>>20)
+		}
+		data.Write(req.GetChunk())
+	}
+
+	// 🔹 Parse before uploading, so an invalid spreadsheet is never stored
+	doc, err := parsers.NewDocument(metadata.FileName, data.Bytes(), profile)
+	if err != nil {
+		return storeError(err, "file")
+	}
+	parser, parsed, parseErr := s.pipeline.Parse(ctx, doc)
+	var validationErr *parsers.ValidationError
+	if errors.As(parseErr, &validationErr) {
+		return invalid("Spreadsheet validation failed", rowErrors(validationErr.RowErrors)...)
+	}
+
+	gcsURL, err := s.upload(ctx, metadata.FileName, data.Bytes())
+	if err != nil {
+		return storeError(err, "file upload")
+	}
+	trace := &store.Trace{
+		CourseID:    uint(metadata.CourseId),
+		UserID:      caller(ctx).ID,
+		FileName:    metadata.FileName,
+		BucketPath:  gcsURL,
+		DateCreated: time.Now(),
+	}
+	if err := s.store.Traces.CreateTrace(ctx, trace); err != nil {
+		return storeError(err, "trace")
+	}
+
+	// 🔹 A document that could not be parsed is still kept; the failure is recorded on the trace
+	if parseErr != nil {
+		err = s.pipeline.RecordFailure(ctx, trace, parser, parseErr)
+	} else {
+		err = s.pipeline.Save(ctx, trace, parser, parsed)
+	}
+	if err != nil {
+		return storeError(err, "trace")
+	}
+	return stream.SendAndClose(newTrace(trace))
+}
+
+func (s *traceServer) GetTrace(ctx context.Context, req *pb.GetTraceRequest) (*pb.Trace, error) {
+	trace, err := s.store.Traces.GetTraceByID(ctx, strconv.FormatUint(uint64(req.CourseId), 10), strconv.FormatUint(uint64(req.Id), 10))
+	if err != nil {
+		return nil, storeError(err, "trace")
+	}
+	return newTrace(trace), nil
+}
+
+func (s *traceServer) ListTraces(ctx context.Context, req *pb.ListTracesRequest) (*pb.ListTracesResponse, error) {
+	filter := store.TraceListFilter{CourseID: uint(req.CourseId), FileNamePrefix: req.FileNamePrefix}
+	var err error
+	if filter.Limit, err = pageSize(req.PageSize); err != nil {
+		return nil, err
+	}
+	if filter.Sort, filter.Desc, err = orderBy(req.OrderBy, "-date_created", traceSorts); err != nil {
+		return nil, err
+	}
+	if filter.After, err = pageToken(req.PageToken, filter.Sort, filter.Desc); err != nil {
+		return nil, err
+	}
+
+	list, err := s.store.Traces.ListTraces(ctx, filter)
+	if err != nil {
+		return nil, storeError(err, "traces")
+	}
+	resp := &pb.ListTracesResponse{NextPageToken: nextPageToken(list.Next), TotalSize: list.Total}
+	for i := range list.Traces {
+		resp.Traces = append(resp.Traces, newTrace(&list.Traces[i]))
+	}
+	return resp, nil
+}
+
+func (s *traceServer) DeleteTrace(ctx context.Context, req *pb.DeleteTraceRequest) (*emptypb.Empty, error) {
+	courseID, traceID := strconv.FormatUint(uint64(req.CourseId), 10), strconv.FormatUint(uint64(req.Id), 10)
+	trace, err := s.store.Traces.GetTraceByID(ctx, courseID, traceID)
+	if err != nil {
+		return nil, storeError(err, "trace")
+	}
+
+	// 🔹 Remove the file first, so a failure leaves the trace to retry with
+	if err := s.delete(ctx, path.Base(trace.BucketPath)); err != nil {
+		return nil, storeError(err, "file deletion")
+	}
+	if err := s.store.Traces.DeleteTrace(ctx, courseID, traceID); err != nil {
+		return nil, storeError(err, "trace")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// columnProfile looks up the spreadsheet profile of an upload
+func columnProfile(name string) (ingest.ColumnProfile, error) {
+	profiles, err := ingest.Profiles()
+	if err != nil {
+		return ingest.ColumnProfile{}, storeError(err, "column profiles")
+	}
+	if name == "" {
+		name = ingest.DefaultProfileName
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return ingest.ColumnProfile{}, invalid("Invalid upload", problems.FieldError{Field: "metadata.profile", Message: fmt.Sprintf("unknown column profile %q", name)})
+	}
+	return profile, nil
+}
+
+// rowErrors reports spreadsheet cells as field violations
+func rowErrors(errs []ingest.RowError) []problems.FieldError {
+	fieldErrors := make([]problems.FieldError, len(errs))
+	for i, rowError := range errs {
+		field := fmt.Sprintf("row %d", rowError.Row)
+		if rowError.Column != "" {
+			field += ", " + rowError.Column
+		}
+		fieldErrors[i] = problems.FieldError{Field: field, Message: rowError.Message}
+	}
+	return fieldErrors
+}
+
+// upload stores a file in the bucket and returns its URL
+func (s *traceServer) upload(ctx context.Context, fileName string, data []byte) (string, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	writer := client.Bucket(s.bucketName).Object(fileName).NewWriter(ctx)
+	writer.ContentType = http.DetectContentType(data)
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return "", fmt.Errorf("failed to upload file to GCS: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize GCS upload: %w", err)
+	}
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucketName, fileName), nil
+}
+
+// delete removes a file from the bucket
+func (s *traceServer) delete(ctx context.Context, fileName string) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Bucket(s.bucketName).Object(fileName).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete file from GCS: %w", err)
+	}
+	return nil
+}
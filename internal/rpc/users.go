@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/csye7125/team01/internal/dto"
+	"github.com/csye7125/team01/internal/problems"
+	"github.com/csye7125/team01/internal/rpc/pb"
+	"github.com/csye7125/team01/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+)
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	store *store.Storage
+}
+
+func newUser(user *store.User) *pb.User {
+	return &pb.User{
+		Id:        uint32(user.ID),
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		CreatedAt: timestamp(user.AccountCreated),
+		UpdatedAt: timestamp(user.AccountUpdated),
+		Version:   user.Version,
+	}
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	user := dto.CreateUserRequest{
+		Username:  req.Username,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}.User()
+	fieldErrors := dto.ValidateUser(user, map[string]interface{}{"password": user.Password})
+	if strings.TrimSpace(user.Username) == "" {
+		fieldErrors = append(fieldErrors, problems.FieldError{Field: "username", Message: "is required"})
+	}
+	if len(fieldErrors) > 0 {
+		return nil, invalid("Invalid user", fieldErrors...)
+	}
+
+	if err := s.store.Users.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		}
+		return nil, storeError(err, "user")
+	}
+	return newUser(user), nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	user, err := self(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return newUser(user), nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	user, err := self(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.Version, user.Version); err != nil {
+		return nil, err
+	}
+
+	update := dto.UserRequest{FirstName: req.FirstName, LastName: req.LastName, Password: req.Password}
+	changes := update.Columns()
+	if fieldErrors := dto.ValidateUser(&store.User{FirstName: update.FirstName, LastName: update.LastName, Password: update.Password}, changes); len(fieldErrors) > 0 {
+		return nil, invalid("Invalid user", fieldErrors...)
+	}
+	if err := s.store.Users.PatchUser(ctx, user.ID, user.Version, changes); err != nil {
+		return nil, storeError(err, "user")
+	}
+
+	update.Apply(user)
+	user.Version++
+	user.AccountUpdated = changes["account_updated"].(time.Time)
+	return newUser(user), nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	user, err := self(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(req.Version, user.Version); err != nil {
+		return nil, err
+	}
+	if err := s.store.Users.DeleteUser(ctx, user.ID, user.Version); err != nil {
+		return nil, storeError(err, "user")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// self returns the caller, who may only reach their own user
+func self(ctx context.Context, id uint32) (*store.User, error) {
+	user := caller(ctx)
+	if uint32(user.ID) != id {
+		return nil, status.Error(codes.PermissionDenied, "you can only access your own user")
+	}
+	return user, nil
+}
@@ -0,0 +1,236 @@
+package sentiment
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/csye7125/team01/internal/env"
+	"github.com/csye7125/team01/internal/store"
+)
+
+const (
+	// maxPhraseWords is the longest lexicon phrase matched
+	maxPhraseWords = 3
+	// negationWindow is how many preceding words a negation reaches
+	negationWindow = 3
+	// negationFactor flips and dampens a negated score ("not good" is milder than "bad")
+	negationFactor = -0.75
+	// normalizationAlpha squashes raw sums into -1..1, as in VADER
+	normalizationAlpha = 15
+	// neutralThreshold is the normalized score below which a comment is neutral
+	neutralThreshold = 0.05
+)
+
+var (
+	sentenceSplit = regexp.MustCompile(`[.!?;\n]+`)
+	wordPattern   = regexp.MustCompile(`[a-z0-9]+(?:'[a-z]+)?`)
+	contrastWords = map[string]bool{"but": true, "however": true, "although": true, "though": true, "yet": true}
+)
+
+// ThemeScore is the sentiment of the sentences mentioning a theme
+type ThemeScore struct {
+	Theme    string  `json:"theme"`
+	Score    float64 `json:"score"`
+	Mentions int     `json:"mentions"`
+}
+
+type Result struct {
+	Score  float64      `json:"score"`
+	Label  string       `json:"label"`
+	Themes []ThemeScore `json:"themes"`
+}
+
+// Analyzer scores text against a lexicon, with optional per-department lexicons
+type Analyzer struct {
+	base        *compiledLexicon
+	departments map[string]*compiledLexicon
+}
+
+type compiledLexicon struct {
+	words        map[string]float64
+	intensifiers map[string]float64
+	negations    map[string]bool
+	themeTerms   map[string][]string // term -> themes
+}
+
+func NewAnalyzer(config LexiconConfig) *Analyzer {
+	base := DefaultLexicon().merge(config.Lexicon)
+	analyzer := &Analyzer{
+		base:        compile(base),
+		departments: map[string]*compiledLexicon{},
+	}
+	for department, lexicon := range config.Departments {
+		analyzer.departments[strings.ToUpper(department)] = compile(base.merge(lexicon))
+	}
+	return analyzer
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultAnalyzer *Analyzer
+	defaultErr      error
+)
+
+// Default returns the analyzer configured by SENTIMENT_LEXICON_FILE, built once per process
+func Default() (*Analyzer, error) {
+	defaultOnce.Do(func() {
+		config, err := LoadLexiconConfig(env.GetString("SENTIMENT_LEXICON_FILE", ""))
+		if err != nil {
+			defaultErr = err
+			return
+		}
+		defaultAnalyzer = NewAnalyzer(config)
+	})
+	return defaultAnalyzer, defaultErr
+}
+
+func compile(lexicon Lexicon) *compiledLexicon {
+	compiled := &compiledLexicon{
+		words:        lexicon.Words,
+		intensifiers: lexicon.Intensifiers,
+		negations:    map[string]bool{},
+		themeTerms:   map[string][]string{},
+	}
+	for _, negation := range lexicon.Negations {
+		compiled.negations[normalizeTerm(negation)] = true
+	}
+	for theme, terms := range lexicon.Themes {
+		for _, term := range terms {
+			key := normalizeTerm(term)
+			compiled.themeTerms[key] = append(compiled.themeTerms[key], theme)
+		}
+	}
+	return compiled
+}
+
+// Analyze scores a comment using the lexicon of the given department, if one is configured
+func (a *Analyzer) Analyze(text, department string) Result {
+	lexicon := a.base
+	if departmental, ok := a.departments[strings.ToUpper(department)]; ok {
+		lexicon = departmental
+	}
+
+	total := 0.0
+	themeSums := map[string]float64{}
+	themeMentions := map[string]int{}
+	for _, sentence := range sentenceSplit.Split(strings.ToLower(text), -1) {
+		tokens := tokenize(sentence)
+		if len(tokens) == 0 {
+			continue
+		}
+		score, themes := lexicon.scoreSentence(tokens)
+		total += score
+		for theme := range themes {
+			themeSums[theme] += normalize(score)
+			themeMentions[theme]++
+		}
+	}
+
+	result := Result{Score: normalize(total), Themes: []ThemeScore{}}
+	result.Label = label(result.Score)
+	for theme, sum := range themeSums {
+		result.Themes = append(result.Themes, ThemeScore{
+			Theme:    theme,
+			Score:    sum / float64(themeMentions[theme]),
+			Mentions: themeMentions[theme],
+		})
+	}
+	sort.Slice(result.Themes, func(i, j int) bool { return result.Themes[i].Theme < result.Themes[j].Theme })
+	return result
+}
+
+// scoreSentence sums word scores with negation, intensifier and contrast rules,
+// and reports which themes the sentence mentions
+func (l *compiledLexicon) scoreSentence(tokens []string) (float64, map[string]bool) {
+	themes := map[string]bool{}
+
+	// Words after a contrast ("good material, but confusing lectures") carry the sentence
+	contrastAt := -1
+	for i, token := range tokens {
+		if contrastWords[token] {
+			contrastAt = i
+		}
+	}
+
+	score := 0.0
+	for i := 0; i < len(tokens); {
+		phrase, width := l.longestMatch(tokens, i)
+		if width == 0 {
+			i++
+			continue
+		}
+		for _, theme := range l.themeTerms[phrase] {
+			themes[theme] = true
+		}
+
+		if value, ok := l.words[phrase]; ok {
+			// Intensifier directly before the phrase
+			if i > 0 {
+				if weight, ok := l.intensifiers[tokens[i-1]]; ok {
+					value *= weight
+				} else if i > 1 {
+					if weight, ok := l.intensifiers[tokens[i-2]+" "+tokens[i-1]]; ok {
+						value *= weight
+					}
+				}
+			}
+			// Negation within the window before the phrase
+			for j := i - 1; j >= 0 && j >= i-negationWindow; j-- {
+				if l.negations[tokens[j]] || strings.HasSuffix(tokens[j], "n't") {
+					value *= negationFactor
+					break
+				}
+			}
+			if contrastAt >= 0 {
+				if i > contrastAt {
+					value *= 1.5
+				} else {
+					value *= 0.5
+				}
+			}
+			score += value
+		}
+		i += width
+	}
+	return score, themes
+}
+
+// longestMatch finds the longest lexicon or theme phrase starting at tokens[start]
+func (l *compiledLexicon) longestMatch(tokens []string, start int) (string, int) {
+	for width := maxPhraseWords; width > 0; width-- {
+		if start+width > len(tokens) {
+			continue
+		}
+		phrase := strings.Join(tokens[start:start+width], " ")
+		if _, ok := l.words[phrase]; ok {
+			return phrase, width
+		}
+		if _, ok := l.themeTerms[phrase]; ok {
+			return phrase, width
+		}
+	}
+	return "", 0
+}
+
+func tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "’", "'")
+	return wordPattern.FindAllString(text, -1)
+}
+
+func normalize(score float64) float64 {
+	return score / math.Sqrt(score*score+normalizationAlpha)
+}
+
+func label(score float64) string {
+	switch {
+	case score >= neutralThreshold:
+		return store.SentimentPositive
+	case score <= -neutralThreshold:
+		return store.SentimentNegative
+	default:
+		return store.SentimentNeutral
+	}
+}
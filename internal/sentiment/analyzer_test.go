@@ -0,0 +1,115 @@
+package sentiment
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+func TestScoreSentence(t *testing.T) {
+	lexicon := NewAnalyzer(LexiconConfig{}).base
+	tests := []struct {
+		name     string
+		sentence string
+		want     float64
+	}{
+		{"word", "the labs were good", 2},
+		{"unknown words", "the labs were on tuesday", 0},
+		{"negation", "the labs were not good", 2 * negationFactor},
+		{"contracted negation", "i didn't enjoy the labs", 2 * negationFactor},
+		{"negation out of reach", "not at all in any way good", 2},
+		{"intensifier", "very good", 2 * 1.5},
+		{"two word intensifier", "a bit slow", -1 * 0.7},
+		{"negated intensifier", "not very good", 2 * 1.5 * negationFactor},
+		{"phrase over its words", "well organized", 2},
+		{"phrase over an intensifier", "too much reading", -1.5},
+		{"longer phrase", "easy to follow", 2},
+		{"contrast", "good material but confusing lectures", 2*0.5 - 2*1.5},
+		{"last contrast counts", "good, however dull, yet useful", 2*0.5 - 1.5*0.5 + 2*1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := lexicon.scoreSentence(tokenize(tt.sentence))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("scoreSentence(%q) = %v, want %v", tt.sentence, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	analyzer := NewAnalyzer(LexiconConfig{})
+	tests := []struct {
+		text       string
+		wantLabel  string
+		wantThemes []string
+	}{
+		{"Excellent course. The lectures were clear!", store.SentimentPositive, []string{"clarity"}},
+		{"The workload was not manageable and grading felt unfair.", store.SentimentNegative, []string{"grading", "workload"}},
+		{"We met on Tuesdays.", store.SentimentNeutral, nil},
+		{"", store.SentimentNeutral, nil},
+	}
+	for _, tt := range tests {
+		result := analyzer.Analyze(tt.text, "")
+		if result.Label != tt.wantLabel {
+			t.Errorf("Analyze(%q) label = %s (score %v), want %s", tt.text, result.Label, result.Score, tt.wantLabel)
+		}
+		var themes []string
+		for _, theme := range result.Themes {
+			themes = append(themes, theme.Theme)
+		}
+		if !reflect.DeepEqual(themes, tt.wantThemes) {
+			t.Errorf("Analyze(%q) themes = %v, want %v", tt.text, themes, tt.wantThemes)
+		}
+		if result.Score <= -1 || result.Score >= 1 {
+			t.Errorf("Analyze(%q) score = %v, want it within -1..1", tt.text, result.Score)
+		}
+	}
+}
+
+func TestAnalyzeThemeScores(t *testing.T) {
+	result := NewAnalyzer(LexiconConfig{}).Analyze("Great lectures. Confusing lectures.", "")
+	want := []ThemeScore{{Theme: "clarity", Score: (normalize(2.5) + normalize(-2)) / 2, Mentions: 2}}
+	if !reflect.DeepEqual(result.Themes, want) {
+		t.Errorf("themes = %+v, want %+v", result.Themes, want)
+	}
+}
+
+func TestDepartmentLexicons(t *testing.T) {
+	analyzer := NewAnalyzer(LexiconConfig{
+		Lexicon: Lexicon{Words: map[string]float64{"hard": 1}},
+		Departments: map[string]Lexicon{
+			"csye": {
+				Words:  map[string]float64{"Kubernetes": 2, "flaky": -2},
+				Themes: map[string][]string{"tooling": {"cluster"}},
+			},
+		},
+	})
+	tests := []struct {
+		text, department, wantLabel string
+	}{
+		// The base overrides apply everywhere
+		{"hard", "", store.SentimentPositive},
+		{"hard", "CSYE", store.SentimentPositive},
+		// Department words only apply to their department, matched ignoring case
+		{"kubernetes", "CSYE", store.SentimentPositive},
+		{"kubernetes", "csye", store.SentimentPositive},
+		{"kubernetes", "INFO", store.SentimentNeutral},
+		{"the cluster was flaky", "CSYE", store.SentimentNegative},
+		{"the cluster was flaky", "", store.SentimentNeutral},
+		// Department lexicons keep the built-in words
+		{"excellent", "CSYE", store.SentimentPositive},
+	}
+	for _, tt := range tests {
+		if got := analyzer.Analyze(tt.text, tt.department); got.Label != tt.wantLabel {
+			t.Errorf("Analyze(%q, %q) = %s, want %s", tt.text, tt.department, got.Label, tt.wantLabel)
+		}
+	}
+
+	themes := analyzer.Analyze("the cluster was flaky", "CSYE").Themes
+	if len(themes) != 1 || themes[0].Theme != "tooling" {
+		t.Errorf("department themes = %+v, want tooling", themes)
+	}
+}
@@ -0,0 +1,128 @@
+package sentiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Lexicon holds word scores and theme vocabularies.
+// Scores run roughly from -3 (very negative) to 3 (very positive); keys may be phrases.
+type Lexicon struct {
+	Words        map[string]float64  `json:"words"`
+	Themes       map[string][]string `json:"themes"`
+	Negations    []string            `json:"negations"`
+	Intensifiers map[string]float64  `json:"intensifiers"`
+}
+
+// LexiconConfig is the JSON layout of SENTIMENT_LEXICON_FILE. Its base entries extend the
+// built-in lexicon; department entries (keyed by course code prefix, e.g. "CSYE") apply on top.
+type LexiconConfig struct {
+	Lexicon
+	Departments map[string]Lexicon `json:"departments"`
+}
+
+// DefaultLexicon is tuned for course evaluation comments
+func DefaultLexicon() Lexicon {
+	return Lexicon{
+		Words: map[string]float64{
+			// Positive
+			"amazing": 3, "awesome": 3, "excellent": 3, "fantastic": 3, "outstanding": 3, "best": 3,
+			"great": 2.5, "love": 2.5, "loved": 2.5, "wonderful": 2.5, "brilliant": 2.5,
+			"good": 2, "helpful": 2, "enjoyed": 2, "enjoy": 2, "engaging": 2, "interesting": 2,
+			"knowledgeable": 2, "passionate": 2, "approachable": 2, "supportive": 2, "recommend": 2,
+			"clear": 2, "well organized": 2, "well structured": 2, "well explained": 2, "useful": 2,
+			"valuable": 2, "fair": 1.5, "manageable": 1.5, "reasonable": 1.5, "organized": 1.5,
+			"responsive": 1.5, "patient": 1.5, "practical": 1.5, "relevant": 1.5, "learned": 1.5,
+			"informative": 1.5, "thorough": 1.5, "insightful": 2, "fun": 2, "easy to follow": 2,
+			"nice": 1.5, "like": 1, "liked": 1.5, "okay": 0.5, "ok": 0.5, "fine": 0.5, "decent": 1,
+			"improved": 1, "appreciate": 2, "appreciated": 2, "thank": 1.5, "thanks": 1.5,
+			// Negative
+			"terrible": -3, "awful": -3, "horrible": -3, "worst": -3, "useless": -2.5, "hate": -2.5,
+			"bad": -2, "poor": -2, "boring": -2, "confusing": -2, "confused": -1.5, "unclear": -2,
+			"disorganized": -2, "unfair": -2, "unhelpful": -2, "frustrating": -2, "frustrated": -2,
+			"overwhelming": -2, "stressful": -2, "rude": -2.5, "dismissive": -2, "unprepared": -2,
+			"difficult": -1, "hard": -1, "heavy": -1, "excessive": -1.5, "too much": -1.5,
+			"unreasonable": -2, "inconsistent": -1.5, "vague": -1.5, "late": -1, "slow": -1,
+			"rushed": -1.5, "outdated": -1.5, "irrelevant": -1.5, "waste": -2, "wasted": -2,
+			"disappointing": -2, "disappointed": -2, "lacking": -1.5, "lack": -1.5, "tedious": -1.5,
+			"harsh": -1.5, "strict": -1, "unresponsive": -2, "struggled": -1.5, "dull": -1.5,
+		},
+		Themes: map[string][]string{
+			"workload": {
+				"workload", "work load", "homework", "assignment", "assignments", "hours", "time consuming",
+				"busy", "pace", "paced", "deadline", "deadlines", "projects", "project", "reading", "readings",
+			},
+			"clarity": {
+				"clear", "clarity", "unclear", "confusing", "confused", "explain", "explained", "explains",
+				"explanation", "explanations", "understand", "understandable", "organized", "disorganized",
+				"lecture", "lectures", "slides", "instructions", "vague", "easy to follow",
+			},
+			"grading": {
+				"grade", "grades", "grading", "graded", "exam", "exams", "quiz", "quizzes", "rubric",
+				"feedback", "points", "curve", "score", "scores", "marks", "midterm", "final",
+			},
+		},
+		Negations: []string{
+			"not", "no", "never", "neither", "nor", "none", "nothing", "hardly", "barely", "without",
+			"isn't", "wasn't", "aren't", "weren't", "don't", "didn't", "doesn't", "can't", "couldn't",
+			"won't", "wouldn't", "shouldn't", "cannot",
+		},
+		Intensifiers: map[string]float64{
+			"very": 1.5, "really": 1.3, "extremely": 1.8, "incredibly": 1.8, "super": 1.5, "so": 1.3,
+			"highly": 1.5, "absolutely": 1.6, "quite": 1.2, "too": 1.3, "somewhat": 0.7,
+			"slightly": 0.6, "fairly": 0.8, "a bit": 0.7, "kind of": 0.7, "little": 0.7,
+		},
+	}
+}
+
+// LoadLexiconConfig reads a lexicon file; an empty path yields no overrides
+func LoadLexiconConfig(path string) (LexiconConfig, error) {
+	var config LexiconConfig
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read sentiment lexicon: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse sentiment lexicon: %w", err)
+	}
+	return config, nil
+}
+
+// merge returns a copy of l extended and overridden by other
+func (l Lexicon) merge(other Lexicon) Lexicon {
+	merged := Lexicon{
+		Words:        map[string]float64{},
+		Themes:       map[string][]string{},
+		Negations:    append(append([]string{}, l.Negations...), other.Negations...),
+		Intensifiers: map[string]float64{},
+	}
+	for word, score := range l.Words {
+		merged.Words[normalizeTerm(word)] = score
+	}
+	for word, score := range other.Words {
+		merged.Words[normalizeTerm(word)] = score
+	}
+	for theme, terms := range l.Themes {
+		merged.Themes[theme] = append([]string{}, terms...)
+	}
+	for theme, terms := range other.Themes {
+		merged.Themes[theme] = append(merged.Themes[theme], terms...)
+	}
+	for word, weight := range l.Intensifiers {
+		merged.Intensifiers[normalizeTerm(word)] = weight
+	}
+	for word, weight := range other.Intensifiers {
+		merged.Intensifiers[normalizeTerm(word)] = weight
+	}
+	return merged
+}
+
+// normalizeTerm tokenizes a lexicon entry the same way as comment text
+func normalizeTerm(term string) string {
+	return strings.Join(tokenize(term), " ")
+}
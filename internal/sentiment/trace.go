@@ -0,0 +1,41 @@
+package sentiment
+
+import (
+	"context"
+
+	"github.com/csye7125/team01/internal/store"
+)
+
+// AnalyzeTrace scores every extracted comment of a trace and stores the results and rollup
+func AnalyzeTrace(ctx context.Context, storage *store.Storage, analyzer *Analyzer, trace *store.Trace) error {
+	comments, err := storage.Comments.GetCommentsByTraceID(ctx, trace.TraceID)
+	if err != nil {
+		return err
+	}
+
+	// 🔹 Departments may extend the lexicon with their own terms
+	department := ""
+	if course, err := storage.Courses.GetCourseByID(ctx, trace.CourseID); err == nil {
+		department = store.DepartmentOf(course.Code)
+	}
+
+	scores := make([]store.CommentScore, 0, len(comments))
+	for _, comment := range comments {
+		result := analyzer.Analyze(comment.Body, department)
+		score := store.CommentScore{
+			TraceCommentID: comment.TraceCommentID,
+			Score:          result.Score,
+			Label:          result.Label,
+		}
+		for _, theme := range result.Themes {
+			score.Themes = append(score.Themes, store.CommentTheme{
+				Theme:    theme.Theme,
+				Score:    theme.Score,
+				Mentions: theme.Mentions,
+			})
+		}
+		scores = append(scores, score)
+	}
+
+	return storage.Sentiments.SaveTraceAnalysis(ctx, trace.TraceID, trace.CourseID, scores)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/csye7125/team01/internal/cache"
+	"gorm.io/gorm"
+)
+
+// cachedAggregates are the aggregates whose reads may be cached; writes to
+// them announce the changed key on cache.InvalidationChannel
+var cachedAggregates = map[string]bool{AggregateCourse: true, AggregateInstructor: true}
+
+// UseCache serves course and instructor reads by ID from c. A nil c turns
+// caching off.
+func (s *Storage) UseCache(c cache.Cache) {
+	s.Courses.cache = c
+	s.Instructors.cache = c
+}
+
+// CacheTTL is how long cached reads may be served, or 0 without a cache
+func (s *Storage) CacheTTL() time.Duration {
+	if s.Courses.cache == nil {
+		return 0
+	}
+	return s.Courses.cache.TTL()
+}
+
+// cacheKey names an aggregate in the cache, the same way OrderingKey does in
+// the outbox. Numeric IDs are canonical, so a write to "07" invalidates "7".
+func cacheKey(aggregateType string, aggregateID interface{}) string {
+	id := fmt.Sprint(aggregateID)
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		id = strconv.FormatUint(n, 10)
+	}
+	return aggregateType + ":" + id
+}
+
+// notifyInvalidation has every replica drop the aggregate from its cache once
+// tx commits; nothing is sent if it rolls back
+func notifyInvalidation(tx *gorm.DB, aggregateType string, aggregateID interface{}) error {
+	if !cachedAggregates[aggregateType] {
+		return nil
+	}
+	return tx.Exec("SELECT pg_notify(?, ?)", cache.InvalidationChannel, cacheKey(aggregateType, aggregateID)).Error
+}
+
+// invalidate drops a key from c as soon as the write commits, so this replica
+// reads its own writes before the notification arrives
+func invalidate(ctx context.Context, c cache.Cache, key string) {
+	if c != nil {
+		AfterCommit(ctx, func() { c.Delete(ctx, key) })
+	}
+}
+
+// cachedRead serves key from c, or loads and caches it on a miss. Reads inside
+// a transaction bypass the cache, since they may see uncommitted writes. age
+// is how long ago the value was loaded.
+func cachedRead[T any](ctx context.Context, c cache.Cache, key string, load func() (*T, error)) (value *T, age time.Duration, err error) {
+	_, inTransaction := ctx.Value(txKey{}).(*gorm.DB)
+	if c == nil || inTransaction {
+		value, err = load()
+		return value, 0, err
+	}
+
+	entry, ticket, ok := c.Get(ctx, key)
+	if ok {
+		var cached T
+		if json.Unmarshal(entry.Value, &cached) == nil {
+			return &cached, time.Since(entry.StoredAt), nil
+		}
+	}
+	if value, err = load(); err != nil {
+		return nil, 0, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		c.Set(ctx, key, data, ticket)
+	}
+	return value, 0, nil
+}
@@ -0,0 +1,320 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CatalogCourse is a course as the catalog describes it, whatever the
+// semester. Its offerings carry the per-semester details.
+type CatalogCourse struct {
+	ID              uint      `json:"catalog_course_id" gorm:"primaryKey;column:catalog_course_id"`
+	Code            string    `json:"code" gorm:"not null"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	CreditHours     int       `json:"credit_hours"`
+	OwnerUserID     uint      `json:"owner_user_id"`
+	DateAdded       time.Time `json:"date_added" gorm:"default:CURRENT_TIMESTAMP"`
+	DateLastUpdated time.Time `json:"date_last_updated" gorm:"autoUpdateTime"`
+	Version         int64     `json:"version" gorm:"not null;default:1"`
+}
+
+// ErrCatalogConflict is returned when a new offering, or a course its owner
+// may not file under the catalog course, has details other than the catalog
+// course's; those change through PatchCatalogCourse
+var ErrCatalogConflict = errors.New("course details differ from its catalog course")
+
+// CatalogMutableColumns are the catalog course columns that may be changed;
+// the code names the catalog course and is fixed
+var CatalogMutableColumns = []string{"name", "description", "credit_hours"}
+
+// Offering is one semester's section of a catalog course. Offerings are the
+// rows of the courses table, so an offering's ID is the course ID that its
+// traces and the /v1/course routes use, and both share one version.
+type Offering struct {
+	ID              uint      `json:"offering_id" gorm:"primaryKey;column:course_id"`
+	CatalogCourseID uint      `json:"catalog_course_id"`
+	Code            string    `json:"code"`
+	SemesterTerm    string    `json:"semester_term"`
+	SemesterYear    int       `json:"semester_year"`
+	InstructorID    uint      `json:"instructor_id"`
+	OwnerUserID     uint      `json:"owner_user_id"`
+	Manufacturer    string    `json:"manufacturer"`
+	DateAdded       time.Time `json:"date_added"`
+	DateLastUpdated time.Time `json:"date_last_updated"`
+	Version         int64     `json:"version"`
+}
+
+// Offering is the semester section a course row stands for
+func (c Course) Offering() Offering {
+	return Offering{
+		ID:              c.ID,
+		CatalogCourseID: c.CatalogCourseID,
+		Code:            c.Code,
+		SemesterTerm:    c.SemesterTerm,
+		SemesterYear:    c.SemesterYear,
+		InstructorID:    c.InstructorID,
+		OwnerUserID:     c.OwnerUserID,
+		Manufacturer:    c.Manufacturer,
+		DateAdded:       c.DateAdded,
+		DateLastUpdated: c.DateLastUpdated,
+		Version:         c.Version,
+	}
+}
+
+// Offerings converts a page of course rows
+func Offerings(courses []Course) []Offering {
+	offerings := make([]Offering, len(courses))
+	for i, course := range courses {
+		offerings[i] = course.Offering()
+	}
+	return offerings
+}
+
+type CatalogStore struct {
+	db *gorm.DB
+}
+
+func NewCatalogStore(db *gorm.DB) *CatalogStore {
+	return &CatalogStore{db: db}
+}
+
+// GetCatalogCourseByCode finds a catalog course by its code, ignoring case
+func (s *CatalogStore) GetCatalogCourseByCode(ctx context.Context, code string) (*CatalogCourse, error) {
+	var catalog CatalogCourse
+	if err := conn(ctx, s.db).Where("upper(code) = upper(?)", code).First(&catalog).Error; err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// CanEdit tells whether a user may change a catalog course: its owner and the
+// owners of its offerings may, as the change reaches every offering
+func (s *CatalogStore) CanEdit(ctx context.Context, catalog *CatalogCourse, userID uint) (bool, error) {
+	return canEditCatalog(conn(ctx, s.db), catalog, userID)
+}
+
+func canEditCatalog(tx *gorm.DB, catalog *CatalogCourse, userID uint) (bool, error) {
+	if catalog.OwnerUserID == userID {
+		return true, nil
+	}
+	var count int64
+	err := tx.Model(&Course{}).Where("catalog_course_id = ? AND owner_user_id = ?", catalog.ID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// PatchCatalogCourse changes a catalog course if it is still at version and
+// copies the change to every offering
+func (s *CatalogStore) PatchCatalogCourse(ctx context.Context, id uint, version int64, updateData map[string]interface{}) error {
+	delete(updateData, "version")
+	updateData["date_last_updated"] = time.Now()
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &CatalogCourse{}, "catalog_course_id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Model(&CatalogCourse{}).Where("catalog_course_id = ?", id).Updates(updateData).Error; err != nil {
+			return err
+		}
+		var catalog CatalogCourse
+		if err := tx.First(&catalog, id).Error; err != nil {
+			return err
+		}
+		updateData["version"] = catalog.Version
+		if err := recordEvent(tx, AggregateCatalogCourse, id, EventUpdated, catalog); err != nil {
+			return err
+		}
+		return shareCatalog(tx, &catalog)
+	})
+}
+
+// linkCatalog files a course under the catalog course of its code, ignoring
+// case, which is created from the course for a new code. A new offering must
+// have its catalog course's details and fails with ErrCatalogConflict
+// otherwise. An updated course whose owner may edit the catalog course copies
+// its details to it and the other offerings, as PatchCatalogCourse does, so
+// /v1/course writes keep renaming courses; other owners get ErrCatalogConflict.
+// It returns the catalog course's ID, or 0 for a course without a code.
+func linkCatalog(tx *gorm.DB, courseID uint, update bool) (uint, error) {
+	var course Course
+	if err := tx.First(&course, courseID).Error; err != nil {
+		return 0, err
+	}
+
+	if strings.TrimSpace(course.Code) == "" {
+		return 0, nil
+	}
+
+	// 🔹 Find or create the catalog course; concurrent creators of a new code
+	// meet at its unique index
+	catalog := CatalogCourse{
+		Code:        course.Code,
+		Name:        course.Name,
+		Description: course.Description,
+		CreditHours: course.CreditHours,
+		OwnerUserID: course.OwnerUserID,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&catalog).Error; err != nil {
+		return 0, err
+	}
+	if catalog.ID != 0 {
+		if err := recordEvent(tx, AggregateCatalogCourse, catalog.ID, EventCreated, catalog); err != nil {
+			return 0, err
+		}
+	} else if err := tx.Where("upper(code) = upper(?)", course.Code).First(&catalog).Error; err != nil {
+		return 0, err
+	}
+
+	// 🔹 Details that differ are copied to the catalog by those who may edit it
+	if !strings.EqualFold(catalog.Code, course.Code) || catalog.Name != course.Name || catalog.Description != course.Description || catalog.CreditHours != course.CreditHours {
+		if !update {
+			return 0, fmt.Errorf("%w: %s", ErrCatalogConflict, catalog.Code)
+		}
+		allowed, err := canEditCatalog(tx, &catalog, course.OwnerUserID)
+		if err != nil {
+			return 0, err
+		}
+		if !allowed {
+			return 0, fmt.Errorf("%w: %s", ErrCatalogConflict, catalog.Code)
+		}
+		if err := copyToCatalog(tx, &catalog, &course); err != nil {
+			return 0, err
+		}
+	}
+	if course.CatalogCourseID != catalog.ID {
+		if err := tx.Exec("UPDATE courses SET catalog_course_id = ? WHERE course_id = ?", catalog.ID, courseID).Error; err != nil {
+			return 0, err
+		}
+	}
+	return catalog.ID, nil
+}
+
+// copyToCatalog gives catalog the details of course at a new version and
+// shares them with its other offerings
+func copyToCatalog(tx *gorm.DB, catalog *CatalogCourse, course *Course) error {
+	if err := tx.Model(&CatalogCourse{}).Where("catalog_course_id = ?", catalog.ID).Updates(map[string]interface{}{
+		"code":              course.Code,
+		"name":              course.Name,
+		"description":       course.Description,
+		"credit_hours":      course.CreditHours,
+		"date_last_updated": time.Now(),
+		"version":           gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.First(catalog, catalog.ID).Error; err != nil {
+		return err
+	}
+	if err := recordEvent(tx, AggregateCatalogCourse, catalog.ID, EventUpdated, catalog); err != nil {
+		return err
+	}
+	return shareCatalog(tx, catalog)
+}
+
+// shareCatalog copies the catalog details to the offerings of catalog that
+// differ, moving each to a new version
+func shareCatalog(tx *gorm.DB, catalog *CatalogCourse) error {
+	var stale []uint
+	if err := tx.Model(&Course{}).
+		Where("catalog_course_id = ?", catalog.ID).
+		Where("(code, name, description, credit_hours) IS DISTINCT FROM (?, ?, ?, ?)", catalog.Code, catalog.Name, catalog.Description, catalog.CreditHours).
+		Pluck("course_id", &stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	if err := tx.Model(&Course{}).Where("course_id IN ?", stale).Updates(map[string]interface{}{
+		"code":              catalog.Code,
+		"name":              catalog.Name,
+		"description":       catalog.Description,
+		"credit_hours":      catalog.CreditHours,
+		"date_last_updated": time.Now(),
+		"version":           gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	for _, id := range stale {
+		if err := recordCourseUpdated(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureCatalogColumns links the courses table to catalog_courses. It only
+// changes the schema; MigrateCatalog files existing courses.
+func EnsureCatalogColumns(db *gorm.DB) error {
+	statements := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_catalog_courses_code ON catalog_courses (upper(code))`,
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS catalog_course_id bigint REFERENCES catalog_courses (catalog_course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_catalog ON courses (catalog_course_id, course_id)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CatalogMigration is what MigrateCatalog did, or would do
+type CatalogMigration struct {
+	Created int64
+	Linked  int64
+	// Conflicts are the courses left unfiled because their details differ
+	// from the catalog course of their code
+	Conflicts []Course
+}
+
+// errDryRun rolls back a migration that was only reported
+var errDryRun = errors.New("dry run")
+
+// MigrateCatalog files courses without a catalog course under the catalog
+// course of their code, ignoring case. Codes without one get one from their
+// latest course. Only courses whose details match their catalog course are
+// linked; no course details are changed, and the others are reported as
+// conflicts to settle by hand. Unless apply is set, it rolls back and only
+// reports what it would do.
+func MigrateCatalog(db *gorm.DB, apply bool) (*CatalogMigration, error) {
+	var migration CatalogMigration
+	err := db.Transaction(func(tx *gorm.DB) error {
+		created := tx.Exec(`INSERT INTO catalog_courses (code, name, description, credit_hours, owner_user_id, date_added, date_last_updated, version)
+		SELECT DISTINCT ON (upper(code)) code, name, description, credit_hours, owner_user_id, date_added, now(), 1
+		FROM courses
+		WHERE catalog_course_id IS NULL AND code <> ''
+		ORDER BY upper(code), semester_year DESC, date_last_updated DESC, course_id DESC
+		ON CONFLICT DO NOTHING`)
+		if created.Error != nil {
+			return created.Error
+		}
+		migration.Created = created.RowsAffected
+
+		linked := tx.Exec(`UPDATE courses SET catalog_course_id = c.catalog_course_id, version = courses.version + 1
+		FROM catalog_courses c
+		WHERE courses.catalog_course_id IS NULL AND upper(courses.code) = upper(c.code)
+			AND (courses.code, courses.name, courses.description, courses.credit_hours)
+				IS NOT DISTINCT FROM (c.code, c.name, c.description, c.credit_hours)`)
+		if linked.Error != nil {
+			return linked.Error
+		}
+		migration.Linked = linked.RowsAffected
+
+		if err := tx.Where("catalog_course_id IS NULL AND code <> ''").Order("upper(code), course_id").Find(&migration.Conflicts).Error; err != nil {
+			return err
+		}
+		if !apply {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return &migration, nil
+}
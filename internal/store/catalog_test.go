@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/csye7125/team01/internal/dbtest"
+)
+
+var courseColumns = []string{"course_id", "code", "name", "description", "credit_hours", "owner_user_id", "catalog_course_id", "version"}
+
+func courseRow(id int64, code, name string, ownerUserID, catalogCourseID int64) []driver.Value {
+	return []driver.Value{id, code, name, "Cloud systems", int64(4), ownerUserID, catalogCourseID, int64(1)}
+}
+
+var catalogColumns = []string{"catalog_course_id", "code", "name", "description", "credit_hours", "owner_user_id", "version"}
+
+func catalogRow(id int64, code, name string, ownerUserID int64) []driver.Value {
+	return []driver.Value{id, code, name, "Cloud systems", int64(4), ownerUserID, int64(1)}
+}
+
+func TestLinkCatalog(t *testing.T) {
+	tests := []struct {
+		name   string
+		course []driver.Value
+		update bool
+		// offerings counts the course owner's offerings of the catalog course
+		offerings int64
+		wantErr   error
+		// wantCopied tells whether the course details reach the catalog course
+		wantCopied bool
+		wantLinked bool
+	}{
+		{"new offering matching", courseRow(7, "CSYE6225", "Network Structures", 2, 0), false, 0, nil, false, true},
+		{"new offering matching in another case", courseRow(7, "csye6225", "Network Structures", 2, 0), false, 0, nil, false, true},
+		{"new offering differing", courseRow(7, "CSYE6225", "Cloud Computing", 2, 0), false, 0, ErrCatalogConflict, false, false},
+		{"update differing by catalog owner", courseRow(7, "CSYE6225", "Cloud Computing", 1, 3), true, 0, nil, true, false},
+		{"update differing by offering owner", courseRow(7, "CSYE6225", "Cloud Computing", 2, 3), true, 1, nil, true, false},
+		{"update differing by stranger", courseRow(7, "CSYE6225", "Cloud Computing", 2, 0), true, 0, ErrCatalogConflict, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, scripted := dbtest.Open(t,
+				dbtest.Result{Match: `INSERT INTO "catalog_courses"`},
+				dbtest.Result{Match: `FROM "catalog_courses"`, Columns: catalogColumns, Rows: [][]driver.Value{catalogRow(3, "CSYE6225", "Network Structures", 1)}},
+				dbtest.Result{Match: `count(*)`, Columns: []string{"count"}, Rows: [][]driver.Value{{tt.offerings}}},
+				dbtest.Result{Match: "IS DISTINCT FROM", Columns: []string{"course_id"}, Rows: [][]driver.Value{{int64(8)}}},
+				dbtest.Result{Match: `FROM "courses"`, Columns: courseColumns, Rows: [][]driver.Value{tt.course}},
+			)
+
+			id, err := linkCatalog(db, 7, tt.update)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("linkCatalog() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && id != 3 {
+				t.Errorf("linkCatalog() = %d, want catalog course 3", id)
+			}
+			if copied := scripted.Ran(`UPDATE "catalog_courses"`); copied != tt.wantCopied {
+				t.Errorf("catalog course updated = %v, want %v", copied, tt.wantCopied)
+			}
+			if shared := scripted.Ran(`UPDATE "courses"`); shared != tt.wantCopied {
+				t.Errorf("other offerings updated = %v, want %v", shared, tt.wantCopied)
+			}
+			if linked := scripted.Ran("SET catalog_course_id"); linked != tt.wantLinked {
+				t.Errorf("course linked = %v, want %v", linked, tt.wantLinked)
+			}
+		})
+	}
+}
+
+func TestLinkCatalogSkipsCoursesWithoutCode(t *testing.T) {
+	db, scripted := dbtest.Open(t,
+		dbtest.Result{Match: `FROM "courses"`, Columns: courseColumns, Rows: [][]driver.Value{courseRow(7, " ", "Network Structures", 2, 0)}},
+	)
+	id, err := linkCatalog(db, 7, false)
+	if err != nil || id != 0 {
+		t.Fatalf("linkCatalog() = %d, %v, want 0, nil", id, err)
+	}
+	if scripted.Ran("catalog_courses") {
+		t.Error("a course without a code reached the catalog")
+	}
+}
+
+func TestMigrateCatalog(t *testing.T) {
+	for _, apply := range []bool{false, true} {
+		t.Run(map[bool]string{false: "dry run", true: "apply"}[apply], func(t *testing.T) {
+			db, scripted := dbtest.Open(t,
+				dbtest.Result{Match: "INSERT INTO catalog_courses", Affected: 2},
+				dbtest.Result{Match: "UPDATE courses SET catalog_course_id", Affected: 5},
+				dbtest.Result{Match: `FROM "courses"`, Columns: []string{"course_id", "code", "name", "date_added"}, Rows: [][]driver.Value{
+					{int64(9), "csye6225", "Cloud Computing", time.Now()},
+				}},
+			)
+
+			migration, err := MigrateCatalog(db, apply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if migration.Created != 2 || migration.Linked != 5 {
+				t.Errorf("MigrateCatalog() created %d and linked %d, want 2 and 5", migration.Created, migration.Linked)
+			}
+			if len(migration.Conflicts) != 1 || migration.Conflicts[0].ID != 9 {
+				t.Errorf("MigrateCatalog() conflicts = %+v, want course 9", migration.Conflicts)
+			}
+			if scripted.Ran("COMMIT") != apply || scripted.Ran("ROLLBACK") == apply {
+				t.Errorf("apply = %v ran %q", apply, scripted.Statements())
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"github.com/csye7125/team01/internal/cache"
 	"gorm.io/gorm"
+	"strconv"
 	"time"
 )
 
@@ -17,12 +20,62 @@ type Course struct {
 	SemesterYear    int       `json:"semester_year"`
 	DateAdded       time.Time `json:"date_added" gorm:"default:CURRENT_TIMESTAMP"`
 	DateLastUpdated time.Time `json:"date_last_updated" gorm:"autoUpdateTime"`
+	Version         int64     `json:"version" gorm:"not null;default:1"`
 	OwnerUserID     uint      `json:"owner_user_id"`
 	InstructorID    uint      `json:"instructor_id"`
+	// CatalogCourseID is managed by the service, which files every course
+	// under the catalog course of its code
+	CatalogCourseID uint `json:"catalog_course_id" gorm:"->"`
+}
+
+// CourseMutableColumns are the course columns owners may change; the rest are
+// managed by the service
+var CourseMutableColumns = []string{"code", "name", "description", "semester_term", "manufacturer", "credit_hours", "semester_year", "instructor_id"}
+
+// CourseWrite is one course of a batch to create or, when it has an ID, to update.
+// Updates only write the mutable columns and must match Version.
+type CourseWrite struct {
+	Course  *Course
+	Version int64
+}
+
+const (
+	CourseSortCode         = "code"
+	CourseSortName         = "name"
+	CourseSortSemesterYear = "semester_year"
+	CourseSortCreditHours  = "credit_hours"
+	CourseSortDateAdded    = "date_added"
+)
+
+// CourseListFilter selects one page of the course catalog
+type CourseListFilter struct {
+	Code         string
+	SemesterTerm string
+	SemesterYear int
+	InstructorID uint
+	OwnerUserID  uint
+	// CatalogCourseID selects the offerings of one catalog course
+	CatalogCourseID uint
+	MinCreditHours  *int
+	MaxCreditHours  *int
+	// Name matches any course whose name contains it, ignoring case
+	Name  string
+	Sort  string
+	Desc  bool
+	After *Cursor
+	Limit int
+}
+
+// CourseList is one page of courses; Next is nil on the last page
+type CourseList struct {
+	Courses []Course
+	Total   int64
+	Next    *Cursor
 }
 
 type CourseStore struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache cache.Cache
 }
 
 func NewCourseStore(db *gorm.DB) *CourseStore {
@@ -30,30 +83,351 @@ func NewCourseStore(db *gorm.DB) *CourseStore {
 }
 
 func (s *CourseStore) CreateCourse(ctx context.Context, course *Course) error {
-	return s.db.WithContext(ctx).Create(&course).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&course).Error; err != nil {
+			return err
+		}
+		var err error
+		if course.CatalogCourseID, err = linkCatalog(tx, course.ID, false); err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateCourse, course.ID, EventCreated, course)
+	})
 }
 
 func (s *CourseStore) GetCourseByID(ctx context.Context, id uint) (*Course, error) {
 	var course Course
-	if err := s.db.WithContext(ctx).First(&course, id).Error; err != nil {
+	if err := conn(ctx, s.db).First(&course, id).Error; err != nil {
 		return nil, err
 	}
 	return &course, nil
 }
 
-// UpdateCourse updates an existing course
-func (s *CourseStore) UpdateCourse(ctx context.Context, id uint, updateData *Course) error {
+// GetCourseByIDCached is GetCourseByID through the read cache; age is how long
+// ago the course was read from the database
+func (s *CourseStore) GetCourseByIDCached(ctx context.Context, id uint) (*Course, time.Duration, error) {
+	return cachedRead(ctx, s.cache, cacheKey(AggregateCourse, id), func() (*Course, error) {
+		return s.GetCourseByID(ctx, id)
+	})
+}
+
+// SaveCourses applies a batch of writes in one transaction and returns one error,
+// or nil, per write. Atomic batches stop at the first failure and roll back
+// entirely; otherwise each write runs in its own savepoint, so failed writes
+// leave the others in place.
+func (s *CourseStore) SaveCourses(ctx context.Context, writes []CourseWrite, atomic bool) ([]error, error) {
+	results := make([]error, len(writes))
+	defer func() {
+		for _, write := range writes {
+			if write.Course.ID != 0 {
+				invalidate(ctx, s.cache, cacheKey(AggregateCourse, write.Course.ID))
+			}
+		}
+	}()
+	err := conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		for i, write := range writes {
+			if atomic {
+				if results[i] = saveCourse(tx, write); results[i] != nil {
+					return results[i]
+				}
+				continue
+			}
+			savepoint := fmt.Sprintf("course_write_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			if results[i] = saveCourse(tx, write); results[i] != nil {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if atomic && err != nil {
+		for _, result := range results {
+			if result != nil {
+				return results, nil
+			}
+		}
+	}
+	return results, err
+}
+
+func saveCourse(tx *gorm.DB, write CourseWrite) error {
+	course := write.Course
+	if course.ID == 0 {
+		if err := tx.Create(course).Error; err != nil {
+			return err
+		}
+		var err error
+		if course.CatalogCourseID, err = linkCatalog(tx, course.ID, false); err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateCourse, course.ID, EventCreated, course)
+	}
+
+	if err := bumpVersion(tx, &Course{}, "course_id", course.ID, write.Version); err != nil {
+		return err
+	}
+	course.DateLastUpdated = time.Now()
+	columns := append([]string{"date_last_updated"}, CourseMutableColumns...)
+	if err := tx.Model(&Course{}).Where("course_id = ?", course.ID).Select(columns).Updates(course).Error; err != nil {
+		return err
+	}
+	course.Version = write.Version + 1
+	var err error
+	if course.CatalogCourseID, err = linkCatalog(tx, course.ID, true); err != nil {
+		return err
+	}
+	return recordCourseUpdated(tx, course.ID)
+}
+
+// GetCoursesByIDs fetches several courses in one query; missing IDs are simply absent
+func (s *CourseStore) GetCoursesByIDs(ctx context.Context, ids []uint) ([]Course, error) {
+	var courses []Course
+	if err := conn(ctx, s.db).Where("course_id IN ?", ids).Find(&courses).Error; err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+// FirstCoursePagesByInstructor loads the first page of each instructor's courses,
+// as ListCourses would return it in code order, with two queries in total
+func (s *CourseStore) FirstCoursePagesByInstructor(ctx context.Context, instructorIDs []uint, limit int) (map[uint]*CourseList, error) {
+	return s.firstCoursePages(ctx, "instructor_id", instructorIDs, limit, func(course Course) uint { return course.InstructorID })
+}
+
+// FirstCoursePagesByOwner is FirstCoursePagesByInstructor for the courses users own
+func (s *CourseStore) FirstCoursePagesByOwner(ctx context.Context, ownerUserIDs []uint, limit int) (map[uint]*CourseList, error) {
+	return s.firstCoursePages(ctx, "owner_user_id", ownerUserIDs, limit, func(course Course) uint { return course.OwnerUserID })
+}
+
+// firstCoursePages ranks the courses of every group by code and keeps the first
+// limit of each, plus one to tell whether another page follows
+func (s *CourseStore) firstCoursePages(ctx context.Context, column string, ids []uint, limit int, group func(Course) uint) (map[uint]*CourseList, error) {
+	db := conn(ctx, s.db)
+	pages := make(map[uint]*CourseList, len(ids))
+	for _, id := range ids {
+		pages[id] = &CourseList{Courses: []Course{}}
+	}
+
+	var totals []struct {
+		GroupID uint
+		Total   int64
+	}
+	if err := db.Model(&Course{}).Select(column+" AS group_id, COUNT(*) AS total").
+		Where(column+" IN ?", ids).Group(column).Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		pages[total.GroupID].Total = total.Total
+	}
+
+	ranked := db.Model(&Course{}).
+		Select("courses.*, ROW_NUMBER() OVER (PARTITION BY "+column+" ORDER BY code, course_id) AS page_rank").
+		Where(column+" IN ?", ids)
+	var courses []Course
+	if err := db.Table("(?) AS courses", ranked).Where("page_rank <= ?", limit+1).
+		Order("code").Order("course_id").Find(&courses).Error; err != nil {
+		return nil, err
+	}
+	for _, course := range courses {
+		page := pages[group(course)]
+		if len(page.Courses) == limit {
+			last := page.Courses[limit-1]
+			page.Next = &Cursor{Sort: CourseSortCode, Value: courseSortValue(CourseSortCode, last), ID: last.ID}
+			continue
+		}
+		page.Courses = append(page.Courses, course)
+	}
+	return pages, nil
+}
+
+// ListCourses pages through the catalog, ordered by the filter's sort key and course ID
+func (s *CourseStore) ListCourses(ctx context.Context, filter CourseListFilter) (*CourseList, error) {
+	query := s.courseQuery(ctx, filter)
+
+	var list CourseList
+	if err := query.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var after interface{}
+	if filter.After != nil {
+		value, err := parseCourseSortValue(filter.Sort, filter.After.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after = value
+	}
+
+	err := keysetPage(query, filter.Sort, "course_id", filter.Desc, filter.After, after, filter.Limit).Find(&list.Courses).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Courses) > filter.Limit {
+		list.Courses = list.Courses[:filter.Limit]
+		last := list.Courses[len(list.Courses)-1]
+		list.Next = &Cursor{Sort: filter.Sort, Desc: filter.Desc, Value: courseSortValue(filter.Sort, last), ID: last.ID}
+	}
+	return &list, nil
+}
+
+// StreamCourses calls fn with every course matching the filter, in the order
+// ListCourses pages through them; Limit and After do not apply
+func (s *CourseStore) StreamCourses(ctx context.Context, filter CourseListFilter, fn func(*Course) error) error {
+	return streamRows(sortedBy(s.courseQuery(ctx, filter), filter.Sort, "course_id", filter.Desc), fn)
+}
+
+// courseQuery selects the courses matching a filter
+func (s *CourseStore) courseQuery(ctx context.Context, filter CourseListFilter) *gorm.DB {
+	query := conn(ctx, s.db).Model(&Course{})
+	if filter.Code != "" {
+		query = query.Where("upper(code) = upper(?)", filter.Code)
+	}
+	if filter.SemesterTerm != "" {
+		query = query.Where("semester_term = ?", filter.SemesterTerm)
+	}
+	if filter.SemesterYear != 0 {
+		query = query.Where("semester_year = ?", filter.SemesterYear)
+	}
+	if filter.InstructorID != 0 {
+		query = query.Where("instructor_id = ?", filter.InstructorID)
+	}
+	if filter.OwnerUserID != 0 {
+		query = query.Where("owner_user_id = ?", filter.OwnerUserID)
+	}
+	if filter.CatalogCourseID != 0 {
+		query = query.Where("catalog_course_id = ?", filter.CatalogCourseID)
+	}
+	if filter.MinCreditHours != nil {
+		query = query.Where("credit_hours >= ?", *filter.MinCreditHours)
+	}
+	if filter.MaxCreditHours != nil {
+		query = query.Where("credit_hours <= ?", *filter.MaxCreditHours)
+	}
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ? ESCAPE '\\'", "%"+escapeLike(filter.Name)+"%")
+	}
+	return query
+}
+
+// courseSortValue formats a course's sort key for a cursor
+func courseSortValue(sort string, course Course) string {
+	switch sort {
+	case CourseSortName:
+		return course.Name
+	case CourseSortSemesterYear:
+		return strconv.Itoa(course.SemesterYear)
+	case CourseSortCreditHours:
+		return strconv.Itoa(course.CreditHours)
+	case CourseSortDateAdded:
+		return course.DateAdded.Format(time.RFC3339Nano)
+	default:
+		return course.Code
+	}
+}
+
+// parseCourseSortValue reads a cursor's sort key back into the column's type
+func parseCourseSortValue(sort, value string) (interface{}, error) {
+	switch sort {
+	case CourseSortSemesterYear, CourseSortCreditHours:
+		return strconv.Atoi(value)
+	case CourseSortDateAdded:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+
+// EnsureCourseIndexes adds the version column and creates the indexes behind
+// catalog filtering and sorting; the courses table itself is not migrated here
+func EnsureCourseIndexes(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_name_trgm ON courses USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_code_upper ON courses (upper(code), course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_code ON courses (code, course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_name ON courses (name, course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_semester ON courses (semester_year, semester_term, course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_credit_hours ON courses (credit_hours, course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_date_added ON courses (date_added, course_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_instructor ON courses (instructor_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_owner ON courses (owner_user_id)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateCourse writes the mutable columns of updateData to a course if it is
+// still at version, returning ErrVersionMismatch otherwise
+func (s *CourseStore) UpdateCourse(ctx context.Context, id uint, version int64, updateData *Course) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateCourse, id))
 	updateData.DateLastUpdated = time.Now()
-	return s.db.WithContext(ctx).Model(&Course{}).Where("course_id = ?", id).Updates(updateData).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Course{}, "course_id", id, version); err != nil {
+			return err
+		}
+		columns := append([]string{"date_last_updated"}, CourseMutableColumns...)
+		if err := tx.Model(&Course{}).Where("course_id = ?", id).Select(columns).Updates(updateData).Error; err != nil {
+			return err
+		}
+		updateData.Version = version + 1
+		var err error
+		if updateData.CatalogCourseID, err = linkCatalog(tx, id, true); err != nil {
+			return err
+		}
+		return recordCourseUpdated(tx, id)
+	})
 }
 
-// PatchCourse performs a partial update on a course
-func (s *CourseStore) PatchCourse(ctx context.Context, id uint, updateData map[string]interface{}) error {
+// PatchCourse performs a partial update on a course if it is still at version
+func (s *CourseStore) PatchCourse(ctx context.Context, id uint, version int64, updateData map[string]interface{}) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateCourse, id))
+	delete(updateData, "version")
 	updateData["date_last_updated"] = time.Now()
-	return s.db.WithContext(ctx).Model(&Course{}).Where("course_id = ?", id).Updates(updateData).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Course{}, "course_id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Model(&Course{}).Where("course_id = ?", id).Updates(updateData).Error; err != nil {
+			return err
+		}
+		updateData["version"] = version + 1
+		catalogCourseID, err := linkCatalog(tx, id, true)
+		if err != nil {
+			return err
+		}
+		updateData["catalog_course_id"] = catalogCourseID
+		return recordCourseUpdated(tx, id)
+	})
 }
 
-// DeleteCourse removes a course from the database
-func (s *CourseStore) DeleteCourse(ctx context.Context, id uint) error {
-	return s.db.WithContext(ctx).Delete(&Course{}, id).Error
+// DeleteCourse removes a course from the database if it is still at version
+func (s *CourseStore) DeleteCourse(ctx context.Context, id uint, version int64) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateCourse, id))
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Course{}, "course_id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Delete(&Course{}, id).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateCourse, id, EventDeleted, map[string]interface{}{"course_id": id})
+	})
+}
+
+// recordCourseUpdated reloads the course inside tx and records its new state
+func recordCourseUpdated(tx *gorm.DB, id uint) error {
+	var course Course
+	if err := tx.First(&course, id).Error; err != nil {
+		return err
+	}
+	return recordEvent(tx, AggregateCourse, id, EventUpdated, course)
 }
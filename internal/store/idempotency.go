@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Idempotency record states
+const (
+	IdempotencyInFlight  = "in_flight"
+	IdempotencyCompleted = "completed"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an Idempotency-Key.
+// Keys are scoped to the caller and the endpoint, so clients cannot see each other's responses.
+type IdempotencyRecord struct {
+	IdempotencyKey string `gorm:"primaryKey"`
+	Scope          string `gorm:"primaryKey"`
+	Fingerprint    string `gorm:"not null"`
+	State          string `gorm:"not null"`
+	StatusCode     int
+	Headers        map[string]string `gorm:"serializer:json;type:jsonb"`
+	Body           []byte
+	DateCreated    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ExpiresAt      time.Time `gorm:"index"`
+	// LockedUntil ends the in-flight lease; a key still in flight after it was abandoned
+	LockedUntil *time.Time
+}
+
+type IdempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyStore(db *gorm.DB) *IdempotencyStore {
+	return &IdempotencyStore{db: db}
+}
+
+// Reserve claims the record's key for a new in-flight request, leased until
+// record.LockedUntil. When the key is already taken it returns the existing
+// record instead; expired records and in-flight records whose lease ran out are
+// replaced. The insert is atomic, so of several concurrent requests with the
+// same key exactly one gets to run.
+func (s *IdempotencyStore) Reserve(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	db := conn(ctx, s.db)
+	now := time.Now()
+	if err := db.Where("idempotency_key = ? AND scope = ? AND (expires_at <= ? OR (state = ? AND locked_until <= ?))",
+		record.IdempotencyKey, record.Scope, now, IdempotencyInFlight, now).
+		Delete(&IdempotencyRecord{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 🔹 The lease end identifies this reservation, so Postgres must store it exactly
+	if record.LockedUntil != nil {
+		lockedUntil := record.LockedUntil.Truncate(time.Microsecond)
+		record.LockedUntil = &lockedUntil
+	}
+	record.State = IdempotencyInFlight
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing IdempotencyRecord
+	if err := db.Where("idempotency_key = ? AND scope = ?", record.IdempotencyKey, record.Scope).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of a reserved request for later replays. A
+// reservation whose lease was reclaimed by another request is left alone.
+func (s *IdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	record.State = IdempotencyCompleted
+	return reservation(conn(ctx, s.db), record).
+		Select("state", "status_code", "headers", "body").
+		Updates(record).Error
+}
+
+// Release frees a reserved key whose request failed, so a retry can run it again
+func (s *IdempotencyStore) Release(ctx context.Context, record *IdempotencyRecord) error {
+	return reservation(conn(ctx, s.db), record).Delete(&IdempotencyRecord{}).Error
+}
+
+// reservation selects the in-flight record made by this reservation
+func reservation(db *gorm.DB, record *IdempotencyRecord) *gorm.DB {
+	db = db.Model(&IdempotencyRecord{}).
+		Where("idempotency_key = ? AND scope = ? AND state = ?", record.IdempotencyKey, record.Scope, IdempotencyInFlight)
+	if record.LockedUntil == nil {
+		return db.Where("locked_until IS NULL")
+	}
+	return db.Where("locked_until = ?", *record.LockedUntil)
+}
+
+// PurgeExpired deletes records past their expiry and returns how many were removed
+func (s *IdempotencyStore) PurgeExpired(ctx context.Context) (int64, error) {
+	result := conn(ctx, s.db).Where("expires_at <= ?", time.Now()).Delete(&IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -3,7 +3,10 @@ package store
 import (
 	"context"
 	"fmt"
+	"github.com/csye7125/team01/internal/cache"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
 	"time"
 )
 
@@ -12,10 +15,45 @@ type Instructor struct {
 	UserID       uint      `json:"user_id"`
 	Name         string    `json:"name"`
 	DateCreated  time.Time `json:"date_created" gorm:"default:CURRENT_TIMESTAMP"`
+	DateUpdated  time.Time `json:"date_updated" gorm:"default:CURRENT_TIMESTAMP;autoUpdateTime"`
+	Version      int64     `json:"version" gorm:"not null;default:1"`
+	// Courses is only loaded on request
+	Courses []Course `json:"courses,omitempty" gorm:"foreignKey:InstructorID;references:InstructorID"`
+	// Relevance is the name similarity to the search query, only set by searches
+	Relevance *float64 `json:"relevance,omitempty" gorm:"->;-:migration"`
+}
+
+// InstructorMutableColumns are the instructor columns callers may change; the
+// rest are managed by the service
+var InstructorMutableColumns = []string{"name"}
+
+const (
+	InstructorSortRelevance   = "relevance"
+	InstructorSortName        = "name"
+	InstructorSortDateCreated = "date_created"
+)
+
+// InstructorListFilter selects one page of the instructor directory
+type InstructorListFilter struct {
+	// Name matches names containing it or similar to it, ignoring case
+	Name           string
+	IncludeCourses bool
+	Sort           string
+	Desc           bool
+	After          *Cursor
+	Limit          int
+}
+
+// InstructorList is one page of instructors; Next is nil on the last page
+type InstructorList struct {
+	Instructors []Instructor
+	Total       int64
+	Next        *Cursor
 }
 
 type InstructorStore struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache cache.Cache
 }
 
 func NewInstructorStore(db *gorm.DB) *InstructorStore {
@@ -25,36 +63,229 @@ func NewInstructorStore(db *gorm.DB) *InstructorStore {
 func (s *InstructorStore) CreateInstructor(ctx context.Context, username string, instructor *Instructor) error {
 	// 🔹 Step 1: Get User ID using username
 	var user User
-	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	if err := conn(ctx, s.db).Where("username = ?", username).First(&user).Error; err != nil {
 		return fmt.Errorf("user with username '%s' does not exist", username)
 	}
 
 	// 🔹 Step 2: Assign the retrieved user ID
 	instructor.UserID = user.ID
 
-	// 🔹 Step 3: Insert the instructor into the database
-	return s.db.WithContext(ctx).Create(&instructor).Error
+	// 🔹 Step 3: Insert the instructor and record the event in one transaction
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&instructor).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateInstructor, instructor.InstructorID, EventCreated, instructor)
+	})
 }
 
 func (s *InstructorStore) GetInstructorByID(ctx context.Context, id string) (*Instructor, error) {
 	var instructor Instructor
-	if err := s.db.WithContext(ctx).First(&instructor, "instructor_id = ?", id).Error; err != nil {
+	if err := conn(ctx, s.db).First(&instructor, "instructor_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &instructor, nil
+}
+
+// GetInstructorByIDCached is GetInstructorByID through the read cache; age is
+// how long ago the instructor was read from the database
+func (s *InstructorStore) GetInstructorByIDCached(ctx context.Context, id string) (*Instructor, time.Duration, error) {
+	return cachedRead(ctx, s.cache, cacheKey(AggregateInstructor, id), func() (*Instructor, error) {
+		return s.GetInstructorByID(ctx, id)
+	})
+}
+
+// GetInstructorsByIDs fetches several instructors in one query; missing IDs are simply absent
+func (s *InstructorStore) GetInstructorsByIDs(ctx context.Context, ids []uint) ([]Instructor, error) {
+	var instructors []Instructor
+	if err := conn(ctx, s.db).Where("instructor_id IN ?", ids).Find(&instructors).Error; err != nil {
+		return nil, err
+	}
+	return instructors, nil
+}
+
+// GetInstructorWithCourses fetches an instructor together with the courses they teach
+func (s *InstructorStore) GetInstructorWithCourses(ctx context.Context, id string) (*Instructor, error) {
+	var instructor Instructor
+	if err := conn(ctx, s.db).Preload("Courses", orderCourses).First(&instructor, "instructor_id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &instructor, nil
 }
 
-func (s *InstructorStore) UpdateInstructor(ctx context.Context, id string, updateData *Instructor) error {
-	return s.db.WithContext(ctx).Model(&Instructor{}).Where("instructor_id = ?", id).Updates(updateData).Error
+// ListInstructors pages through the directory. Courses of the whole page are
+// loaded with a single extra query.
+func (s *InstructorStore) ListInstructors(ctx context.Context, filter InstructorListFilter) (*InstructorList, error) {
+	query := s.instructorQuery(ctx, filter)
+
+	var list InstructorList
+	if err := query.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var after interface{}
+	if filter.After != nil {
+		value, err := parseInstructorSortValue(filter.Sort, filter.After.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after = value
+	}
+
+	query = keysetPage(query, filter.Sort, "instructor_id", filter.Desc, filter.After, after, filter.Limit)
+	if filter.IncludeCourses {
+		query = query.Preload("Courses", orderCourses)
+	}
+	if err := query.Find(&list.Instructors).Error; err != nil {
+		return nil, err
+	}
+	if len(list.Instructors) > filter.Limit {
+		list.Instructors = list.Instructors[:filter.Limit]
+		last := list.Instructors[len(list.Instructors)-1]
+		list.Next = &Cursor{Sort: filter.Sort, Desc: filter.Desc, Value: instructorSortValue(filter.Sort, last), ID: last.InstructorID}
+	}
+	return &list, nil
+}
+
+// StreamInstructors calls fn with every instructor matching the filter, in the
+// order ListInstructors pages through them; Limit, After and IncludeCourses do not apply
+func (s *InstructorStore) StreamInstructors(ctx context.Context, filter InstructorListFilter, fn func(*Instructor) error) error {
+	return streamRows(sortedBy(s.instructorQuery(ctx, filter), filter.Sort, "instructor_id", filter.Desc), fn)
+}
+
+// instructorQuery selects the instructors matching a filter
+func (s *InstructorStore) instructorQuery(ctx context.Context, filter InstructorListFilter) *gorm.DB {
+	db := conn(ctx, s.db)
+	if filter.Name == "" {
+		return db.Model(&Instructor{})
+	}
+	// 🔹 Substring matches and trigram-similar names, scored by similarity
+	return db.Table("(?) AS instructors", db.Model(&Instructor{}).
+		Select("instructors.*, similarity(name, ?)::float8 AS relevance", filter.Name).
+		Where("name ILIKE ? ESCAPE '\\' OR name % ?", "%"+escapeLike(filter.Name)+"%", filter.Name))
+}
+
+// orderCourses keeps embedded courses in a stable order
+func orderCourses(db *gorm.DB) *gorm.DB {
+	return db.Order("semester_year DESC").Order("code").Order("course_id")
+}
+
+// instructorSortValue formats an instructor's sort key for a cursor
+func instructorSortValue(sort string, instructor Instructor) string {
+	switch sort {
+	case InstructorSortRelevance:
+		if instructor.Relevance == nil {
+			return "0"
+		}
+		return strconv.FormatFloat(*instructor.Relevance, 'g', -1, 64)
+	case InstructorSortDateCreated:
+		return instructor.DateCreated.Format(time.RFC3339Nano)
+	default:
+		return instructor.Name
+	}
+}
+
+// parseInstructorSortValue reads a cursor's sort key back into the column's type
+func parseInstructorSortValue(sort, value string) (interface{}, error) {
+	switch sort {
+	case InstructorSortRelevance:
+		return strconv.ParseFloat(value, 64)
+	case InstructorSortDateCreated:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+
+// EnsureInstructorIndexes adds the version columns and creates the indexes behind
+// directory search and sorting; the instructors table itself is not migrated here
+func EnsureInstructorIndexes(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE instructors ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1`,
+		`ALTER TABLE instructors ADD COLUMN IF NOT EXISTS date_updated timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_instructors_name_trgm ON instructors USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_instructors_name ON instructors (name, instructor_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_instructors_date_created ON instructors (date_created, instructor_id)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateInstructor writes the mutable columns of updateData if the instructor is still at version, returning ErrVersionMismatch otherwise
+func (s *InstructorStore) UpdateInstructor(ctx context.Context, id string, version int64, updateData *Instructor) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateInstructor, id))
+	updateData.DateUpdated = time.Now()
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Instructor{}, "instructor_id", id, version); err != nil {
+			return err
+		}
+		columns := append([]string{"date_updated"}, InstructorMutableColumns...)
+		if err := tx.Model(&Instructor{}).Where("instructor_id = ?", id).Select(columns).Updates(updateData).Error; err != nil {
+			return err
+		}
+		var instructor Instructor
+		if err := tx.First(&instructor, "instructor_id = ?", id).Error; err != nil {
+			return err
+		}
+		updateData.Version, updateData.DateUpdated = instructor.Version, instructor.DateUpdated
+		return recordEvent(tx, AggregateInstructor, id, EventUpdated, instructor)
+	})
 }
 
-func (s *InstructorStore) DeleteInstructor(ctx context.Context, id string) error {
-	return s.db.WithContext(ctx).Delete(&Instructor{}, "instructor_id = ?", id).Error
+// PatchInstructor is UpdateInstructor for a partial set of columns
+func (s *InstructorStore) PatchInstructor(ctx context.Context, id string, version int64, updateData map[string]interface{}) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateInstructor, id))
+	updateData["date_updated"] = time.Now()
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Instructor{}, "instructor_id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Model(&Instructor{}).Where("instructor_id = ?", id).Updates(updateData).Error; err != nil {
+			return err
+		}
+		var instructor Instructor
+		if err := tx.First(&instructor, "instructor_id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateInstructor, id, EventUpdated, instructor)
+	})
+}
+
+// DeleteInstructor removes the instructor if it is still at version
+func (s *InstructorStore) DeleteInstructor(ctx context.Context, id string, version int64) error {
+	defer invalidate(ctx, s.cache, cacheKey(AggregateInstructor, id))
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &Instructor{}, "instructor_id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Delete(&Instructor{}, "instructor_id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateInstructor, id, EventDeleted, map[string]interface{}{"instructor_id": id})
+	})
+}
+
+// ExistingInstructorIDs returns which of the given instructor IDs exist, in one query
+func (s *InstructorStore) ExistingInstructorIDs(ctx context.Context, ids []uint) (map[uint]bool, error) {
+	var found []uint
+	if err := conn(ctx, s.db).Model(&Instructor{}).Where("instructor_id IN ?", ids).Pluck("instructor_id", &found).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[uint]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
 }
 
 func (s *InstructorStore) CheckInstructorExists(ctx context.Context, instructorID uint) error {
 	var instructor Instructor
-	if err := s.db.WithContext(ctx).First(&instructor, "instructor_id = ?", instructorID).Error; err != nil {
+	if err := conn(ctx, s.db).First(&instructor, "instructor_id = ?", instructorID).Error; err != nil {
 		return fmt.Errorf("instructor not found")
 	}
 	return nil
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Aggregate types recorded on outbox events
+const (
+	AggregateCourse        = "course"
+	AggregateInstructor    = "instructor"
+	AggregateTrace         = "trace"
+	AggregateUser          = "user"
+	AggregateCatalogCourse = "catalog_course"
+)
+
+// Event types recorded on outbox events
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes
+type OutboxEvent struct {
+	ID            uint64          `json:"id" gorm:"primaryKey;autoIncrement"`
+	AggregateType string          `json:"aggregate_type" gorm:"index:idx_outbox_aggregate,priority:1"`
+	AggregateID   string          `json:"aggregate_id" gorm:"index:idx_outbox_aggregate,priority:2"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb"`
+	DateCreated   time.Time       `json:"date_created" gorm:"default:CURRENT_TIMESTAMP"`
+	PublishedAt   *time.Time      `json:"published_at" gorm:"index"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error"`
+	DeadAt        *time.Time      `json:"dead_at"` // set once the event failed too often to retry
+	ClaimToken    string          `json:"-"`
+	ClaimedUntil  *time.Time      `json:"-"`
+}
+
+// OrderingKey groups events of one aggregate so brokers keep them in order
+func (e *OutboxEvent) OrderingKey() string {
+	return e.AggregateType + ":" + e.AggregateID
+}
+
+// Subject is the routing name of the event, e.g. "course.updated"
+func (e *OutboxEvent) Subject() string {
+	return e.AggregateType + "." + e.EventType
+}
+
+// recordEvent writes an event to the outbox using the caller's transaction and
+// invalidates the aggregate's cached reads when it commits
+func recordEvent(tx *gorm.DB, aggregateType string, aggregateID interface{}, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s.%s event: %w", aggregateType, eventType, err)
+	}
+
+	event := OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   fmt.Sprint(aggregateID),
+		EventType:     eventType,
+		Payload:       data,
+		DateCreated:   time.Now(),
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return err
+	}
+	return notifyInvalidation(tx, aggregateType, aggregateID)
+}
+
+type OutboxStore struct {
+	db *gorm.DB
+}
+
+func NewOutboxStore(db *gorm.DB) *OutboxStore {
+	return &OutboxStore{db: db}
+}
+
+// outboxRelayLockKey is the advisory lock that serializes relays claiming events.
+// It is only held while claiming, never while publishing.
+const outboxRelayLockKey = 7125026
+
+// ProcessPending leases up to limit unpublished events in id order and hands them
+// to fn once the claim has committed, so no transaction or lock is held while fn
+// publishes. Only one relay holds a lease at a time, which keeps per-aggregate
+// ordering intact when several replicas run the relay; a relay that dies mid-batch
+// loses its lease after lease and the events are claimed again.
+//
+// fn reports a result per event it attempted; successes are marked published and
+// failures are retried on a later call until maxAttempts, when the event is dead
+// lettered and stops holding back the rest of its aggregate. Events fn leaves out
+// are released untouched.
+func (s *OutboxStore) ProcessPending(ctx context.Context, limit int, lease time.Duration, maxAttempts int, fn func([]OutboxEvent) map[uint64]error) (int, error) {
+	token, events, err := s.claimPending(ctx, limit, lease)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	return s.finishClaim(ctx, token, events, fn(events), maxAttempts)
+}
+
+// claimPending leases the next pending events to a new claim token, or claims
+// nothing while another relay's lease is live
+func (s *OutboxStore) claimPending(ctx context.Context, limit int, lease time.Duration) (string, []OutboxEvent, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(random)
+	var events []OutboxEvent
+	err := conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var leased bool
+		if err := tx.Raw("SELECT EXISTS (SELECT 1 FROM outbox_events WHERE published_at IS NULL AND dead_at IS NULL AND claimed_until > now())").
+			Scan(&leased).Error; err != nil {
+			return err
+		}
+		if leased {
+			return nil
+		}
+
+		if err := tx.Where("published_at IS NULL AND dead_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"claim_token":   token,
+			"claimed_until": gorm.Expr("now() + make_interval(secs => ?)", lease.Seconds()),
+		}).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, events, nil
+}
+
+// finishClaim records the publish results of a claim and releases its lease.
+// Rows whose lease was taken over by another claim are left to it.
+func (s *OutboxStore) finishClaim(ctx context.Context, token string, events []OutboxEvent, results map[uint64]error, maxAttempts int) (int, error) {
+	published := 0
+	err := conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, event := range events {
+			update := map[string]interface{}{"claim_token": "", "claimed_until": nil}
+			publishErr, attempted := results[event.ID]
+			switch {
+			case !attempted:
+			case publishErr == nil:
+				update["published_at"] = now
+				update["attempts"] = gorm.Expr("attempts + 1")
+				update["last_error"] = ""
+			default:
+				update["attempts"] = gorm.Expr("attempts + 1")
+				update["last_error"] = publishErr.Error()
+				if maxAttempts > 0 && event.Attempts+1 >= maxAttempts {
+					update["dead_at"] = now
+				}
+			}
+
+			result := tx.Model(&OutboxEvent{}).Where("id = ? AND claim_token = ?", event.ID, token).Updates(update)
+			if result.Error != nil {
+				return result.Error
+			}
+			if attempted && publishErr == nil && result.RowsAffected > 0 {
+				published++
+			}
+		}
+		return nil
+	})
+	return published, err
+}
+
+// DeletePublishedBefore removes published events older than the cutoff
+func (s *OutboxStore) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := conn(ctx, s.db).Where("published_at IS NOT NULL AND published_at < ?", cutoff).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
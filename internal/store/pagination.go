@@ -0,0 +1,83 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued for the current query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page in keyset pagination. It records the sort it
+// was issued for, the sort key of that row and its ID as a tiebreaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// keysetPage orders a query by column and then idColumn, resumes it after the
+// cursor row whose sort key is afterValue and fetches one extra row so callers
+// can tell whether another page follows
+func keysetPage(query *gorm.DB, column, idColumn string, desc bool, after *Cursor, afterValue interface{}, limit int) *gorm.DB {
+	comparison := ">"
+	if desc {
+		comparison = "<"
+	}
+	if after != nil {
+		query = query.Where("("+column+", "+idColumn+") "+comparison+" (?, ?)", afterValue, after.ID)
+	}
+	return sortedBy(query, column, idColumn, desc).Limit(limit + 1)
+}
+
+// sortedBy orders a query by column and then idColumn, the order pages are issued in
+func sortedBy(query *gorm.DB, column, idColumn string, desc bool) *gorm.DB {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return query.Order(column + " " + direction).Order(idColumn + " " + direction)
+}
+
+// streamRows reads the rows of a query one at a time from the open result set,
+// so that exports of any size never hold more than one row in memory
+func streamRows[T any](query *gorm.DB, fn func(*T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
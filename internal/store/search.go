@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/csye7125/team01/internal/env"
+	"gorm.io/gorm"
+)
+
+// Kinds of search hits
+const (
+	SearchKindCourse     = "course"
+	SearchKindInstructor = "instructor"
+	SearchKindComment    = "comment"
+)
+
+var validSearchLanguage = regexp.MustCompile(`^[a-z_]+$`)
+
+// Snippets are highlighted between these control characters, which are
+// stripped from the text first, so that the text can be HTML-escaped before
+// they become <mark> tags
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// highlightSnippet turns a ts_headline result into HTML-safe text with <mark>
+// around the matches
+func highlightSnippet(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+// SearchLanguage is the Postgres text search configuration, set with SEARCH_LANGUAGE
+func SearchLanguage() string {
+	return env.GetString("SEARCH_LANGUAGE", "english")
+}
+
+// SearchFilter narrows a full-text search
+type SearchFilter struct {
+	Query        string
+	Kinds        []string
+	SemesterTerm string
+	SemesterYear int
+	InstructorID uint
+	Limit        int
+	Offset       int
+}
+
+type SearchHit struct {
+	Kind         string  `json:"kind"`
+	ID           uint    `json:"id"`
+	CourseID     *uint   `json:"course_id"`
+	TraceID      *uint   `json:"trace_id"`
+	Title        string  `json:"title"`
+	Snippet      string  `json:"snippet"`
+	Rank         float64 `json:"rank"`
+	SemesterTerm *string `json:"semester_term"`
+	SemesterYear *int    `json:"semester_year"`
+	InstructorID *uint   `json:"instructor_id"`
+}
+
+type SemesterFacet struct {
+	SemesterTerm string `json:"semester_term"`
+	SemesterYear int    `json:"semester_year"`
+	Count        int    `json:"count"`
+}
+
+type InstructorFacet struct {
+	InstructorID uint   `json:"instructor_id"`
+	Name         string `json:"name"`
+	Count        int    `json:"count"`
+}
+
+type KindFacet struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+type SearchFacets struct {
+	Kinds       []KindFacet       `json:"kinds"`
+	Semesters   []SemesterFacet   `json:"semesters"`
+	Instructors []InstructorFacet `json:"instructors"`
+}
+
+type SearchResult struct {
+	Hits   []SearchHit  `json:"results"`
+	Total  int64        `json:"total"`
+	Facets SearchFacets `json:"facets"`
+}
+
+type SearchStore struct {
+	db       *gorm.DB
+	language string
+}
+
+func NewSearchStore(db *gorm.DB, language string) *SearchStore {
+	return &SearchStore{db: db, language: language}
+}
+
+// EnsureSearchIndexes adds the generated tsvector columns and their GIN indexes.
+// The text search configuration is fixed into the columns, so changing the language
+// means dropping the search_vector columns and starting again.
+func EnsureSearchIndexes(db *gorm.DB, language string) error {
+	if !validSearchLanguage.MatchString(language) {
+		return fmt.Errorf("invalid search language %q", language)
+	}
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE courses ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('%[1]s', coalesce(code, '')), 'A') ||
+			setweight(to_tsvector('%[1]s', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('%[1]s', coalesce(description, '')), 'B')) STORED`, language),
+		`CREATE INDEX IF NOT EXISTS idx_courses_search_vector ON courses USING GIN (search_vector)`,
+		fmt.Sprintf(`ALTER TABLE instructors ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			to_tsvector('%s', coalesce(name, ''))) STORED`, language),
+		`CREATE INDEX IF NOT EXISTS idx_instructors_search_vector ON instructors USING GIN (search_vector)`,
+		fmt.Sprintf(`ALTER TABLE trace_comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			to_tsvector('%s', coalesce(body, ''))) STORED`, language),
+		`CREATE INDEX IF NOT EXISTS idx_trace_comments_search_vector ON trace_comments USING GIN (search_vector)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchHitsCTE unions matching courses, instructors and comments.
+// Its parameters are the search language and the query text.
+const searchHitsCTE = `WITH q AS (SELECT websearch_to_tsquery(CAST(@language AS regconfig), @query) AS query),
+hits AS (
+	SELECT 'course' AS kind, c.course_id AS id, c.course_id AS course_id, NULL::bigint AS trace_id,
+		concat_ws(' ', c.code, c.name) AS title,
+		concat_ws(' ', c.code, c.name, c.description) AS document,
+		ts_rank_cd(c.search_vector, q.query) AS rank,
+		c.semester_term, c.semester_year, c.instructor_id
+	FROM courses c, q WHERE c.search_vector @@ q.query
+	UNION ALL
+	SELECT 'instructor', i.instructor_id, NULL, NULL, i.name, i.name,
+		ts_rank_cd(i.search_vector, q.query), NULL, NULL, i.instructor_id
+	FROM instructors i, q WHERE i.search_vector @@ q.query
+	UNION ALL
+	SELECT 'comment', tc.trace_comment_id, tc.course_id, tc.trace_id,
+		concat_ws(' - ', c.code, nullif(tc.question, '')), tc.body,
+		ts_rank_cd(tc.search_vector, q.query), c.semester_term, c.semester_year, c.instructor_id
+	FROM trace_comments tc JOIN courses c ON c.course_id = tc.course_id, q WHERE tc.search_vector @@ q.query
+),
+filtered AS (SELECT * FROM hits WHERE %s)
+`
+
+// searchFacetsSelect counts the filtered hits once per grouping set: the
+// total, by kind, by semester and by instructor. Semesters are ordered newest
+// first, the other facets by count.
+const searchFacetsSelect = `SELECT
+		CASE
+			WHEN GROUPING(f.kind) = 0 THEN 'kind'
+			WHEN GROUPING(f.semester_year) = 0 THEN 'semester'
+			WHEN GROUPING(f.instructor_id) = 0 THEN 'instructor'
+			ELSE 'total'
+		END AS facet,
+		f.kind, coalesce(f.semester_term, '') AS semester_term, f.semester_year,
+		f.instructor_id, coalesce(i.name, '') AS name, count(*) AS count
+	FROM filtered f
+	LEFT JOIN instructors i ON i.instructor_id = f.instructor_id
+	GROUP BY GROUPING SETS ((), (f.kind), (f.semester_term, f.semester_year), (f.instructor_id, i.name))
+	ORDER BY CASE WHEN GROUPING(f.semester_year) = 0 THEN 0 ELSE count(*) END DESC,
+		f.semester_year DESC, f.semester_term, f.kind, f.instructor_id`
+
+// searchFacetRow is one row of searchFacetsSelect; the columns outside its
+// grouping set are null
+type searchFacetRow struct {
+	Facet        string
+	Kind         *string
+	SemesterTerm string
+	SemesterYear *int
+	InstructorID *uint
+	Name         string
+	Count        int64
+}
+
+// Search runs a ranked full-text search with highlighted snippets and facet counts
+func (s *SearchStore) Search(ctx context.Context, filter SearchFilter) (*SearchResult, error) {
+	args := map[string]interface{}{
+		"language": s.language,
+		"query":    filter.Query,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+	}
+	where := "TRUE"
+	if len(filter.Kinds) > 0 {
+		where += " AND kind IN @kinds"
+		args["kinds"] = filter.Kinds
+	}
+	if filter.SemesterTerm != "" {
+		where += " AND lower(semester_term) = lower(@semester_term)"
+		args["semester_term"] = filter.SemesterTerm
+	}
+	if filter.SemesterYear != 0 {
+		where += " AND semester_year = @semester_year"
+		args["semester_year"] = filter.SemesterYear
+	}
+	if filter.InstructorID != 0 {
+		where += " AND instructor_id = @instructor_id"
+		args["instructor_id"] = filter.InstructorID
+	}
+	cte := fmt.Sprintf(searchHitsCTE, where)
+	db := conn(ctx, s.db)
+
+	result := &SearchResult{Hits: []SearchHit{}}
+
+	// 🔹 Ranked page, highlighting only the rows returned
+	if err := db.Raw(cte+`SELECT kind, id, course_id, trace_id, title,
+			ts_headline(CAST(@language AS regconfig), translate(document, chr(2) || chr(3), ''), q.query,
+				'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=30, MinWords=10') AS snippet,
+			rank, semester_term, semester_year, instructor_id
+		FROM filtered, q
+		ORDER BY rank DESC, kind, id
+		LIMIT @limit OFFSET @offset`, args).Scan(&result.Hits).Error; err != nil {
+		return nil, err
+	}
+	for i := range result.Hits {
+		result.Hits[i].Snippet = highlightSnippet(result.Hits[i].Snippet)
+	}
+
+	// 🔹 Total and facets over the whole filtered match set in one pass
+	var rows []searchFacetRow
+	if err := db.Raw(cte+searchFacetsSelect, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result.Facets = SearchFacets{Kinds: []KindFacet{}, Semesters: []SemesterFacet{}, Instructors: []InstructorFacet{}}
+	for _, row := range rows {
+		switch row.Facet {
+		case "total":
+			result.Total = row.Count
+		case "kind":
+			result.Facets.Kinds = append(result.Facets.Kinds, KindFacet{Kind: *row.Kind, Count: int(row.Count)})
+		case "semester":
+			// Instructor hits have no semester
+			if row.SemesterYear != nil {
+				result.Facets.Semesters = append(result.Facets.Semesters, SemesterFacet{
+					SemesterTerm: row.SemesterTerm,
+					SemesterYear: *row.SemesterYear,
+					Count:        int(row.Count),
+				})
+			}
+		case "instructor":
+			if row.InstructorID != nil {
+				result.Facets.Instructors = append(result.Facets.Instructors, InstructorFacet{
+					InstructorID: *row.InstructorID,
+					Name:         row.Name,
+					Count:        int(row.Count),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/csye7125/team01/internal/dbtest"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet, want string
+	}{
+		{"the \x02workload\x03 is heavy", "the <mark>workload</mark> is heavy"},
+		{"<script>alert(1)</script> \x02grading\x03", "&lt;script&gt;alert(1)&lt;/script&gt; <mark>grading</mark>"},
+		{"\x02\"clear\"\x03 & fair", "<mark>&#34;clear&#34;</mark> &amp; fair"},
+		{"<mark>typed by a student</mark>", "&lt;mark&gt;typed by a student&lt;/mark&gt;"},
+	}
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestSearchCountsFacetsInOneQuery(t *testing.T) {
+	db, scripted := dbtest.Open(t,
+		dbtest.Result{
+			Match:   "GROUPING SETS",
+			Columns: []string{"facet", "kind", "semester_term", "semester_year", "instructor_id", "name", "count"},
+			Rows: [][]driver.Value{
+				{"total", nil, "", nil, nil, "", int64(5)},
+				{"kind", "comment", "", nil, nil, "", int64(3)},
+				{"instructor", nil, "", nil, int64(4), "Ada Lovelace", int64(4)},
+				{"kind", "course", "", nil, nil, "", int64(1)},
+				{"kind", "instructor", "", nil, nil, "", int64(1)},
+				{"instructor", nil, "", nil, nil, "", int64(1)},
+				{"semester", nil, "Fall", int64(2024), nil, "", int64(4)},
+				{"semester", nil, "", nil, nil, "", int64(1)},
+			},
+		},
+		dbtest.Result{
+			Match:   "ts_headline",
+			Columns: []string{"kind", "id", "title", "snippet", "rank"},
+			Rows:    [][]driver.Value{{"comment", int64(9), "CSYE 7125", "\x02clear\x03 labs", 0.5}},
+		},
+	)
+
+	result, err := NewSearchStore(db, "english").Search(context.Background(), SearchFilter{Query: "clear", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statements := scripted.Statements(); len(statements) != 2 {
+		t.Fatalf("ran %d statements, want the page and one for the facets: %q", len(statements), statements)
+	}
+	if len(result.Hits) != 1 || result.Hits[0].Snippet != "<mark>clear</mark> labs" {
+		t.Errorf("hits = %+v, want the highlighted comment", result.Hits)
+	}
+	if result.Total != 5 {
+		t.Errorf("total = %d, want 5", result.Total)
+	}
+
+	// 🔹 Hits without a semester or instructor are not facets
+	want := SearchFacets{
+		Kinds:       []KindFacet{{"comment", 3}, {"course", 1}, {"instructor", 1}},
+		Semesters:   []SemesterFacet{{"Fall", 2024, 4}},
+		Instructors: []InstructorFacet{{4, "Ada Lovelace", 4}},
+	}
+	if !reflect.DeepEqual(result.Facets, want) {
+		t.Errorf("facets = %+v, want %+v", result.Facets, want)
+	}
+}
+
+func TestSearchWithoutMatches(t *testing.T) {
+	db, _ := dbtest.Open(t, dbtest.Result{
+		Match:   "GROUPING SETS",
+		Columns: []string{"facet", "kind", "semester_term", "semester_year", "instructor_id", "name", "count"},
+		Rows:    [][]driver.Value{{"total", nil, "", nil, nil, "", int64(0)}},
+	})
+
+	result, err := NewSearchStore(db, "english").Search(context.Background(), SearchFilter{Query: "parking", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 || result.Hits == nil || result.Facets.Kinds == nil || result.Facets.Semesters == nil || result.Facets.Instructors == nil {
+		t.Errorf("result = %+v, want empty lists rather than null", result)
+	}
+}
+
+func TestSearchFiltersBothQueries(t *testing.T) {
+	db, scripted := dbtest.Open(t)
+	filter := SearchFilter{Query: "labs", Kinds: []string{SearchKindComment}, SemesterTerm: "fall", SemesterYear: 2024, InstructorID: 4, Limit: 10}
+	if _, err := NewSearchStore(db, "english").Search(context.Background(), filter); err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range scripted.Statements() {
+		for _, condition := range []string{"kind IN", "lower(semester_term) = lower(", "semester_year = ", "instructor_id = "} {
+			if !strings.Contains(statement, condition) {
+				t.Errorf("statement %q lacks %q", statement, condition)
+			}
+		}
+	}
+}
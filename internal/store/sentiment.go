@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sentiment labels stored on comments
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// CommentTheme records that a comment talks about a theme, with the sentiment it expressed
+type CommentTheme struct {
+	CommentThemeID uint    `json:"comment_theme_id" gorm:"primaryKey;autoIncrement"`
+	TraceCommentID uint    `json:"trace_comment_id" gorm:"index"`
+	TraceID        uint    `json:"trace_id" gorm:"index"`
+	CourseID       uint    `json:"course_id" gorm:"index"`
+	Theme          string  `json:"theme"`
+	Score          float64 `json:"score"`
+	Mentions       int     `json:"mentions"`
+}
+
+// TraceSentiment is the sentiment rollup of all comments in a trace
+type TraceSentiment struct {
+	TraceID     uint      `json:"trace_id" gorm:"primaryKey;autoIncrement:false"`
+	CourseID    uint      `json:"course_id" gorm:"index"`
+	Comments    int       `json:"comments"`
+	Positive    int       `json:"positive"`
+	Neutral     int       `json:"neutral"`
+	Negative    int       `json:"negative"`
+	MeanScore   float64   `json:"mean_score"`
+	DateUpdated time.Time `json:"date_updated" gorm:"autoUpdateTime"`
+}
+
+// CommentScore is the analysis of one comment
+type CommentScore struct {
+	TraceCommentID uint
+	Score          float64
+	Label          string
+	Themes         []CommentTheme
+}
+
+// SentimentSummary aggregates trace rollups over a course or an instructor
+type SentimentSummary struct {
+	Traces    int     `json:"traces"`
+	Comments  int     `json:"comments"`
+	Positive  int     `json:"positive"`
+	Neutral   int     `json:"neutral"`
+	Negative  int     `json:"negative"`
+	MeanScore float64 `json:"mean_score"`
+}
+
+type ThemeSummary struct {
+	Theme     string  `json:"theme"`
+	Comments  int     `json:"comments"`
+	Mentions  int     `json:"mentions"`
+	MeanScore float64 `json:"mean_score"`
+}
+
+// DepartmentOf extracts the department prefix from a course code, e.g. "CSYE" from "CSYE 7125"
+func DepartmentOf(code string) string {
+	end := strings.IndexFunc(code, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(code)
+	}
+	return strings.ToUpper(code[:end])
+}
+
+type SentimentStore struct {
+	db *gorm.DB
+}
+
+func NewSentimentStore(db *gorm.DB) *SentimentStore {
+	return &SentimentStore{db: db}
+}
+
+// SaveTraceAnalysis stores per-comment scores and themes and rebuilds the trace rollup
+func (s *SentimentStore) SaveTraceAnalysis(ctx context.Context, traceID, courseID uint, scores []CommentScore) error {
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&CommentTheme{}).Error; err != nil {
+			return err
+		}
+
+		rollup := TraceSentiment{TraceID: traceID, CourseID: courseID}
+		var themes []CommentTheme
+		total := 0.0
+		for _, score := range scores {
+			if err := tx.Model(&TraceComment{}).Where("trace_comment_id = ?", score.TraceCommentID).Updates(map[string]interface{}{
+				"sentiment_score": score.Score,
+				"sentiment_label": score.Label,
+			}).Error; err != nil {
+				return err
+			}
+			for _, theme := range score.Themes {
+				theme.TraceCommentID = score.TraceCommentID
+				theme.TraceID = traceID
+				theme.CourseID = courseID
+				themes = append(themes, theme)
+			}
+
+			rollup.Comments++
+			total += score.Score
+			switch score.Label {
+			case SentimentPositive:
+				rollup.Positive++
+			case SentimentNegative:
+				rollup.Negative++
+			default:
+				rollup.Neutral++
+			}
+		}
+		if rollup.Comments > 0 {
+			rollup.MeanScore = total / float64(rollup.Comments)
+		}
+
+		if len(themes) > 0 {
+			if err := tx.Create(&themes).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rollup).Error
+	})
+}
+
+// GetTraceSentimentsByCourseID returns the rollup of every analysed trace of a course
+func (s *SentimentStore) GetTraceSentimentsByCourseID(ctx context.Context, courseID uint) ([]TraceSentiment, error) {
+	var rollups []TraceSentiment
+	err := conn(ctx, s.db).Where("course_id = ?", courseID).Order("trace_id").Find(&rollups).Error
+	return rollups, err
+}
+
+// GetCourseSummary combines the trace rollups of a course
+func (s *SentimentStore) GetCourseSummary(ctx context.Context, courseID uint) (*SentimentSummary, error) {
+	return s.summary(conn(ctx, s.db).Where("trace_sentiments.course_id = ?", courseID))
+}
+
+// GetInstructorSummary combines the trace rollups of every course an instructor taught
+func (s *SentimentStore) GetInstructorSummary(ctx context.Context, instructorID uint) (*SentimentSummary, error) {
+	return s.summary(conn(ctx, s.db).
+		Joins("JOIN courses ON courses.course_id = trace_sentiments.course_id").
+		Where("courses.instructor_id = ?", instructorID))
+}
+
+func (s *SentimentStore) summary(scope *gorm.DB) (*SentimentSummary, error) {
+	var summary SentimentSummary
+	err := summaryQuery(scope, "").Scan(&summary).Error
+	return &summary, err
+}
+
+// summaryQuery combines the trace rollups in scope into SentimentSummary
+// columns, one row per value of key, or one row in all when key is empty
+func summaryQuery(scope *gorm.DB, key string) *gorm.DB {
+	columns := `COUNT(*) AS traces,
+		COALESCE(SUM(trace_sentiments.comments), 0) AS comments,
+		COALESCE(SUM(trace_sentiments.positive), 0) AS positive,
+		COALESCE(SUM(trace_sentiments.neutral), 0) AS neutral,
+		COALESCE(SUM(trace_sentiments.negative), 0) AS negative,
+		COALESCE(SUM(trace_sentiments.mean_score * trace_sentiments.comments) / NULLIF(SUM(trace_sentiments.comments), 0), 0) AS mean_score`
+	query := scope.Table("trace_sentiments")
+	if key == "" {
+		return query.Select(columns)
+	}
+	return query.Select(key + " AS key, " + columns).Group(key)
+}
+
+// GetCourseThemes summarises theme mentions across a course's comments
+func (s *SentimentStore) GetCourseThemes(ctx context.Context, courseID uint) ([]ThemeSummary, error) {
+	return s.themes(conn(ctx, s.db).Where("comment_themes.course_id = ?", courseID))
+}
+
+// GetInstructorThemes summarises theme mentions across an instructor's comments
+func (s *SentimentStore) GetInstructorThemes(ctx context.Context, instructorID uint) ([]ThemeSummary, error) {
+	return s.themes(conn(ctx, s.db).
+		Joins("JOIN courses ON courses.course_id = comment_themes.course_id").
+		Where("courses.instructor_id = ?", instructorID))
+}
+
+func (s *SentimentStore) themes(scope *gorm.DB) ([]ThemeSummary, error) {
+	themes := []ThemeSummary{}
+	err := scope.Table("comment_themes").
+		Select(`comment_themes.theme,
+			COUNT(DISTINCT comment_themes.trace_comment_id) AS comments,
+			SUM(comment_themes.mentions) AS mentions,
+			AVG(comment_themes.score) AS mean_score`).
+		Group("comment_themes.theme").
+		Order("comments DESC, comment_themes.theme").
+		Scan(&themes).Error
+	return themes, err
+}
+
+// Sentiment is the sentiment of a course or an instructor as embedded in their reads
+type Sentiment struct {
+	Summary SentimentSummary `json:"summary"`
+	Themes  []ThemeSummary   `json:"themes"`
+}
+
+// GetCourseSentiments combines the rollups and themes of several courses in
+// two queries. Courses without analysed traces get an empty sentiment.
+func (s *SentimentStore) GetCourseSentiments(ctx context.Context, courseIDs []uint) (map[uint]*Sentiment, error) {
+	db := conn(ctx, s.db)
+	return s.sentiments(courseIDs, "trace_sentiments.course_id",
+		db.Where("trace_sentiments.course_id IN ?", courseIDs),
+		"comment_themes.course_id",
+		db.Where("comment_themes.course_id IN ?", courseIDs))
+}
+
+// GetInstructorSentiments is GetCourseSentiments over every course each instructor taught
+func (s *SentimentStore) GetInstructorSentiments(ctx context.Context, instructorIDs []uint) (map[uint]*Sentiment, error) {
+	db := conn(ctx, s.db)
+	return s.sentiments(instructorIDs, "courses.instructor_id",
+		db.Joins("JOIN courses ON courses.course_id = trace_sentiments.course_id").Where("courses.instructor_id IN ?", instructorIDs),
+		"courses.instructor_id",
+		db.Joins("JOIN courses ON courses.course_id = comment_themes.course_id").Where("courses.instructor_id IN ?", instructorIDs))
+}
+
+func (s *SentimentStore) sentiments(ids []uint, summaryKey string, summaryScope *gorm.DB, themeKey string, themeScope *gorm.DB) (map[uint]*Sentiment, error) {
+	sentiments := make(map[uint]*Sentiment, len(ids))
+	for _, id := range ids {
+		sentiments[id] = &Sentiment{Themes: []ThemeSummary{}}
+	}
+	if len(ids) == 0 {
+		return sentiments, nil
+	}
+
+	var summaries []struct {
+		Key uint
+		SentimentSummary
+	}
+	if err := summaryQuery(summaryScope, summaryKey).Scan(&summaries).Error; err != nil {
+		return nil, err
+	}
+	for _, summary := range summaries {
+		if sentiment := sentiments[summary.Key]; sentiment != nil {
+			sentiment.Summary = summary.SentimentSummary
+		}
+	}
+
+	var themes []struct {
+		Key uint
+		ThemeSummary
+	}
+	if err := themeScope.Table("comment_themes").
+		Select(themeKey + ` AS key,
+			comment_themes.theme,
+			COUNT(DISTINCT comment_themes.trace_comment_id) AS comments,
+			SUM(comment_themes.mentions) AS mentions,
+			AVG(comment_themes.score) AS mean_score`).
+		Group(themeKey + ", comment_themes.theme").
+		Order("key, comments DESC, comment_themes.theme").
+		Scan(&themes).Error; err != nil {
+		return nil, err
+	}
+	for _, theme := range themes {
+		if sentiment := sentiments[theme.Key]; sentiment != nil {
+			sentiment.Themes = append(sentiment.Themes, theme.ThemeSummary)
+		}
+	}
+	return sentiments, nil
+}
@@ -10,7 +10,15 @@ type Storage struct {
 	Users       *UserStore
 	Traces      *TraceStore
 	Courses     *CourseStore
+	Catalog     *CatalogStore
 	Instructors *InstructorStore
+	Outbox      *OutboxStore
+	Results     *TraceResultStore
+	Comments    *TraceCommentStore
+	Search      *SearchStore
+	Sentiments  *SentimentStore
+	Texts       *TraceTextStore
+	Idempotency *IdempotencyStore
 }
 
 // NewStorage initializes Storage with a database connection
@@ -20,6 +28,14 @@ func NewStorage(db *gorm.DB) *Storage {
 		Users:       NewUserStore(db),
 		Traces:      NewTraceStore(db),
 		Courses:     NewCourseStore(db),
+		Catalog:     NewCatalogStore(db),
 		Instructors: NewInstructorStore(db),
+		Outbox:      NewOutboxStore(db),
+		Results:     NewTraceResultStore(db),
+		Comments:    NewTraceCommentStore(db),
+		Search:      NewSearchStore(db, SearchLanguage()),
+		Sentiments:  NewSentimentStore(db),
+		Texts:       NewTraceTextStore(db),
+		Idempotency: NewIdempotencyStore(db),
 	}
 }
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,77 @@ type Trace struct {
 	FileName    string    `json:"file_name"`
 	DateCreated time.Time `json:"date_created"`
 	BucketPath  string    `json:"bucket_path"`
+	// Parser that produced the stored results, comments and text
+	ParserName    string     `json:"parser_name"`
+	ParserVersion int        `json:"parser_version"`
+	ParseStatus   string     `json:"parse_status"`
+	ParseError    string     `json:"parse_error,omitempty"`
+	DateParsed    *time.Time `json:"date_parsed"`
+	// Column profile the upload was mapped through, reused when reparsing
+	ParseProfile string `json:"parse_profile"`
+}
+
+const (
+	ParseStatusParsed      = "parsed"
+	ParseStatusFailed      = "failed"
+	ParseStatusUnsupported = "unsupported"
+)
+
+const (
+	TraceSortDateCreated = "date_created"
+	TraceSortFileName    = "file_name"
+)
+
+// TraceListFilter selects one page of a course's traces
+type TraceListFilter struct {
+	CourseID       uint
+	UserID         uint
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	FileNamePrefix string
+	Sort           string
+	Desc           bool
+	After          *Cursor
+	Limit          int
+}
+
+// TraceList is one page of traces; Next is nil on the last page
+type TraceList struct {
+	Traces []Trace
+	Total  int64
+	Next   *Cursor
+}
+
+// ReparseFilter selects traces whose stored content came from an older parser
+type ReparseFilter struct {
+	ParserName    string
+	ParserVersion int
+	CourseID      uint
+	// IncludeUnparsed also selects traces no parser has handled yet
+	IncludeUnparsed bool
+	Limit           int
+}
+
+// EnsureTraceColumns adds the parser columns and creates the indexes behind
+// trace listing; the traces table itself is not migrated here
+func EnsureTraceColumns(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS parser_name text NOT NULL DEFAULT ''`,
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS parser_version bigint NOT NULL DEFAULT 0`,
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS parse_status text NOT NULL DEFAULT ''`,
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS parse_error text NOT NULL DEFAULT ''`,
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS date_parsed timestamptz`,
+		`ALTER TABLE traces ADD COLUMN IF NOT EXISTS parse_profile text NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_traces_course_date ON traces (course_id, date_created)`,
+		`CREATE INDEX IF NOT EXISTS idx_traces_course_file ON traces (course_id, file_name)`,
+		`CREATE INDEX IF NOT EXISTS idx_traces_course_user ON traces (course_id, user_id)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type TraceStore struct {
@@ -25,13 +97,18 @@ func NewTraceStore(db *gorm.DB) *TraceStore {
 
 // Create Trace
 func (s *TraceStore) CreateTrace(ctx context.Context, trace *Trace) error {
-	return s.db.WithContext(ctx).Create(&trace).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&trace).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateTrace, trace.TraceID, EventCreated, trace)
+	})
 }
 
 // Get Trace by ID
 func (s *TraceStore) GetTraceByID(ctx context.Context, courseID, traceID string) (*Trace, error) {
 	var trace Trace
-	err := s.db.WithContext(ctx).Where("course_id = ? AND trace_id = ?", courseID, traceID).First(&trace).Error
+	err := conn(ctx, s.db).Where("course_id = ? AND trace_id = ?", courseID, traceID).First(&trace).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,14 +118,209 @@ func (s *TraceStore) GetTraceByID(ctx context.Context, courseID, traceID string)
 // Get All Traces by Course ID
 func (s *TraceStore) GetTracesByCourseID(ctx context.Context, courseID string) ([]Trace, error) {
 	var traces []Trace
-	err := s.db.WithContext(ctx).Where("course_id = ?", courseID).Find(&traces).Error
+	err := conn(ctx, s.db).Where("course_id = ?", courseID).Find(&traces).Error
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// List a course's traces one page at a time, ordered by the filter's sort key and trace ID
+func (s *TraceStore) ListTraces(ctx context.Context, filter TraceListFilter) (*TraceList, error) {
+	query := s.traceQuery(ctx, filter)
+
+	var list TraceList
+	if err := query.Session(&gorm.Session{}).Count(&list.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var after interface{}
+	if filter.After != nil {
+		after = filter.After.Value
+		if filter.Sort == TraceSortDateCreated {
+			value, err := time.Parse(time.RFC3339Nano, filter.After.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			after = value
+		}
+	}
+
+	err := keysetPage(query, filter.Sort, "trace_id", filter.Desc, filter.After, after, filter.Limit).Find(&list.Traces).Error
 	if err != nil {
 		return nil, err
 	}
+	if len(list.Traces) > filter.Limit {
+		list.Traces = list.Traces[:filter.Limit]
+		last := list.Traces[len(list.Traces)-1]
+		value := last.FileName
+		if filter.Sort == TraceSortDateCreated {
+			value = last.DateCreated.Format(time.RFC3339Nano)
+		}
+		list.Next = &Cursor{Sort: filter.Sort, Desc: filter.Desc, Value: value, ID: last.TraceID}
+	}
+	return &list, nil
+}
+
+// StreamTraces calls fn with every trace matching the filter, in the order
+// ListTraces pages through them; Limit and After do not apply
+func (s *TraceStore) StreamTraces(ctx context.Context, filter TraceListFilter, fn func(*Trace) error) error {
+	return streamRows(sortedBy(s.traceQuery(ctx, filter), filter.Sort, "trace_id", filter.Desc), fn)
+}
+
+// traceQuery selects the traces of a course matching a filter
+func (s *TraceStore) traceQuery(ctx context.Context, filter TraceListFilter) *gorm.DB {
+	query := conn(ctx, s.db).Model(&Trace{}).Where("course_id = ?", filter.CourseID)
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("date_created >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("date_created < ?", *filter.CreatedTo)
+	}
+	if filter.FileNamePrefix != "" {
+		query = query.Where("file_name LIKE ? ESCAPE '\\'", escapeLike(filter.FileNamePrefix)+"%")
+	}
+	return query
+}
+
+// GetTracesByIDs fetches several traces in one query; missing IDs are simply absent
+func (s *TraceStore) GetTracesByIDs(ctx context.Context, ids []uint) ([]Trace, error) {
+	var traces []Trace
+	if err := conn(ctx, s.db).Where("trace_id IN ?", ids).Find(&traces).Error; err != nil {
+		return nil, err
+	}
 	return traces, nil
 }
 
+// FirstTracePages loads the first page of each course's traces, newest first as
+// ListTraces returns them by default, with two queries in total
+func (s *TraceStore) FirstTracePages(ctx context.Context, courseIDs []uint, limit int) (map[uint]*TraceList, error) {
+	db := conn(ctx, s.db)
+	pages := make(map[uint]*TraceList, len(courseIDs))
+	for _, id := range courseIDs {
+		pages[id] = &TraceList{Traces: []Trace{}}
+	}
+
+	var totals []struct {
+		CourseID uint
+		Total    int64
+	}
+	if err := db.Model(&Trace{}).Select("course_id, COUNT(*) AS total").
+		Where("course_id IN ?", courseIDs).Group("course_id").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		pages[total.CourseID].Total = total.Total
+	}
+
+	ranked := db.Model(&Trace{}).
+		Select("traces.*, ROW_NUMBER() OVER (PARTITION BY course_id ORDER BY date_created DESC, trace_id DESC) AS page_rank").
+		Where("course_id IN ?", courseIDs)
+	var traces []Trace
+	if err := db.Table("(?) AS traces", ranked).Where("page_rank <= ?", limit+1).
+		Order("date_created DESC").Order("trace_id DESC").Find(&traces).Error; err != nil {
+		return nil, err
+	}
+	for _, trace := range traces {
+		page := pages[trace.CourseID]
+		if len(page.Traces) == limit {
+			last := page.Traces[limit-1]
+			page.Next = &Cursor{Sort: TraceSortDateCreated, Desc: true, Value: last.DateCreated.Format(time.RFC3339Nano), ID: last.TraceID}
+			continue
+		}
+		page.Traces = append(page.Traces, trace)
+	}
+	return pages, nil
+}
+
+// escapeLike escapes LIKE wildcards so the value matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
 // Delete Trace
 func (s *TraceStore) DeleteTrace(ctx context.Context, courseID, traceID string) error {
-	return s.db.WithContext(ctx).Where("course_id = ? AND trace_id = ?", courseID, traceID).Delete(&Trace{}).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trace_id = ?", traceID).Delete(&CommentTheme{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceSentiment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceComment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TracePage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("course_id = ? AND trace_id = ?", courseID, traceID).Delete(&Trace{}).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateTrace, traceID, EventDeleted, map[string]interface{}{
+			"course_id": courseID,
+			"trace_id":  traceID,
+		})
+	})
+}
+
+// Record which parser handled a trace and how it went
+func (s *TraceStore) RecordParse(ctx context.Context, trace *Trace, parserName string, parserVersion int, status, parseError string) error {
+	now := time.Now()
+	err := conn(ctx, s.db).Model(&Trace{}).Where("trace_id = ?", trace.TraceID).Updates(map[string]interface{}{
+		"parser_name":    parserName,
+		"parser_version": parserVersion,
+		"parse_status":   status,
+		"parse_error":    parseError,
+		"date_parsed":    now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	trace.ParserName, trace.ParserVersion = parserName, parserVersion
+	trace.ParseStatus, trace.ParseError, trace.DateParsed = status, parseError, &now
+	return nil
+}
+
+// traceParseLockClass sets the parse locks of traces apart from other advisory
+// locks; each trace's lock key is the class in the high 32 bits and its ID below
+const traceParseLockClass = 7125033
+
+// TryLockParse takes the parse lock of a trace until the transaction of ctx
+// ends, or returns false when another transaction holds it. ctx must carry a
+// transaction from WithTransaction, or the lock is released at once.
+func (s *TraceStore) TryLockParse(ctx context.Context, traceID uint) (bool, error) {
+	var locked bool
+	key := int64(traceParseLockClass)<<32 | int64(traceID)
+	err := conn(ctx, s.db).Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&locked).Error
+	return locked, err
+}
+
+// Get traces parsed by an older version of a parser
+func (s *TraceStore) GetTracesForReparse(ctx context.Context, filter ReparseFilter) ([]Trace, error) {
+	query := conn(ctx, s.db).Model(&Trace{})
+	if filter.IncludeUnparsed {
+		query = query.Where("(parser_name = ? AND parser_version < ?) OR parser_name = '' OR parser_name IS NULL",
+			filter.ParserName, filter.ParserVersion)
+	} else {
+		query = query.Where("parser_name = ? AND parser_version < ?", filter.ParserName, filter.ParserVersion)
+	}
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+
+	var traces []Trace
+	err := query.Order("trace_id").Limit(filter.Limit).Find(&traces).Error
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
 }
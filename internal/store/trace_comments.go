@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TraceComment is one free-text student comment extracted from a trace
+type TraceComment struct {
+	TraceCommentID uint      `json:"trace_comment_id" gorm:"primaryKey;autoIncrement"`
+	TraceID        uint      `json:"trace_id" gorm:"index"`
+	CourseID       uint      `json:"course_id" gorm:"index"`
+	Question       string    `json:"question"`
+	Body           string    `json:"body"`
+	Page           int       `json:"page"`
+	SentimentScore *float64  `json:"sentiment_score"`
+	SentimentLabel string    `json:"sentiment_label"`
+	DateCreated    time.Time `json:"date_created" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+type TraceCommentStore struct {
+	db *gorm.DB
+}
+
+func NewTraceCommentStore(db *gorm.DB) *TraceCommentStore {
+	return &TraceCommentStore{db: db}
+}
+
+// ReplaceComments swaps the extracted comments of a trace for a new set
+func (s *TraceCommentStore) ReplaceComments(ctx context.Context, traceID uint, comments []TraceComment) error {
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceComment{}).Error; err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			return nil
+		}
+		for i := range comments {
+			comments[i].TraceID = traceID
+		}
+		return tx.Create(&comments).Error
+	})
+}
+
+// GetCommentsByTraceID returns the comments of a trace in extraction order
+func (s *TraceCommentStore) GetCommentsByTraceID(ctx context.Context, traceID uint) ([]TraceComment, error) {
+	var comments []TraceComment
+	err := conn(ctx, s.db).Where("trace_id = ?", traceID).Order("trace_comment_id").Find(&comments).Error
+	return comments, err
+}
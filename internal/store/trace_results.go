@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TraceResult is the parsed score summary of one survey question in a trace
+type TraceResult struct {
+	TraceResultID uint      `json:"trace_result_id" gorm:"primaryKey;autoIncrement"`
+	TraceID       uint      `json:"trace_id" gorm:"index"`
+	CourseID      uint      `json:"course_id" gorm:"index"`
+	Question      string    `json:"question"`
+	ResponseCount int       `json:"response_count"`
+	InvitedCount  int       `json:"invited_count"`
+	Mean          float64   `json:"mean"`
+	Median        float64   `json:"median"`
+	StdDev        float64   `json:"std_dev"`
+	DateCreated   time.Time `json:"date_created" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// InstructorSemesterScore is an instructor's weighted mean for one question in one semester
+type InstructorSemesterScore struct {
+	SemesterTerm string  `json:"semester_term"`
+	SemesterYear int     `json:"semester_year"`
+	Question     string  `json:"question"`
+	WeightedMean float64 `json:"weighted_mean"`
+	Respondents  int     `json:"respondents"`
+	Courses      int     `json:"courses"`
+}
+
+// InstructorQuestionMean is an instructor's weighted mean for one question across all semesters
+type InstructorQuestionMean struct {
+	InstructorID uint    `json:"instructor_id"`
+	Question     string  `json:"question"`
+	WeightedMean float64 `json:"weighted_mean"`
+	Respondents  int     `json:"respondents"`
+}
+
+// departmentExpr extracts the department prefix from a course code, e.g. "CSYE" from "CSYE 7125"
+const departmentExpr = "upper(substring(courses.code from '^[A-Za-z]+'))"
+
+type TraceResultStore struct {
+	db *gorm.DB
+}
+
+func NewTraceResultStore(db *gorm.DB) *TraceResultStore {
+	return &TraceResultStore{db: db}
+}
+
+// ReplaceResults swaps the parsed results of a trace for a new set
+func (s *TraceResultStore) ReplaceResults(ctx context.Context, traceID uint, results []TraceResult) error {
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceResult{}).Error; err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		for i := range results {
+			results[i].TraceID = traceID
+		}
+		return tx.Create(&results).Error
+	})
+}
+
+// GetResultsByTraceID returns the parsed results of a trace in question order
+func (s *TraceResultStore) GetResultsByTraceID(ctx context.Context, traceID uint) ([]TraceResult, error) {
+	var results []TraceResult
+	err := conn(ctx, s.db).Where("trace_id = ?", traceID).Order("trace_result_id").Find(&results).Error
+	return results, err
+}
+
+// GetResultsByCourseIDs returns the parsed results of every trace of the given courses
+func (s *TraceResultStore) GetResultsByCourseIDs(ctx context.Context, courseIDs []uint) ([]TraceResult, error) {
+	var results []TraceResult
+	err := conn(ctx, s.db).Where("course_id IN ?", courseIDs).Order("trace_result_id").Find(&results).Error
+	return results, err
+}
+
+// GetInstructorSemesterScores groups an instructor's results by semester and question,
+// weighting each course's mean by its respondent count
+func (s *TraceResultStore) GetInstructorSemesterScores(ctx context.Context, instructorID uint) ([]InstructorSemesterScore, error) {
+	var scores []InstructorSemesterScore
+	err := conn(ctx, s.db).
+		Table("trace_results").
+		Select(`courses.semester_term, courses.semester_year, trace_results.question,
+			SUM(trace_results.mean * trace_results.response_count) / NULLIF(SUM(trace_results.response_count), 0) AS weighted_mean,
+			SUM(trace_results.response_count) AS respondents,
+			COUNT(DISTINCT courses.course_id) AS courses`).
+		Joins("JOIN courses ON courses.course_id = trace_results.course_id").
+		Where("courses.instructor_id = ? AND trace_results.response_count > 0", instructorID).
+		Group("courses.semester_term, courses.semester_year, trace_results.question").
+		Scan(&scores).Error
+	return scores, err
+}
+
+// GetInstructorDepartments lists the departments an instructor has taught in
+func (s *TraceResultStore) GetInstructorDepartments(ctx context.Context, instructorID uint) ([]string, error) {
+	var departments []string
+	err := conn(ctx, s.db).
+		Raw("SELECT DISTINCT "+departmentExpr+" FROM courses WHERE instructor_id = ? AND code ~ '^[A-Za-z]'", instructorID).
+		Scan(&departments).Error
+	return departments, err
+}
+
+// GetDepartmentQuestionMeans returns every instructor's overall weighted mean per question
+// for courses in the given departments
+func (s *TraceResultStore) GetDepartmentQuestionMeans(ctx context.Context, departments []string) ([]InstructorQuestionMean, error) {
+	var means []InstructorQuestionMean
+	if len(departments) == 0 {
+		return means, nil
+	}
+	err := conn(ctx, s.db).
+		Table("trace_results").
+		Select(`courses.instructor_id, trace_results.question,
+			SUM(trace_results.mean * trace_results.response_count) / NULLIF(SUM(trace_results.response_count), 0) AS weighted_mean,
+			SUM(trace_results.response_count) AS respondents`).
+		Joins("JOIN courses ON courses.course_id = trace_results.course_id").
+		Where(departmentExpr+" IN ? AND trace_results.response_count > 0", departments).
+		Group("courses.instructor_id, trace_results.question").
+		Scan(&means).Error
+	return means, err
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TracePage is the extracted text of one page of a trace document
+type TracePage struct {
+	TracePageID uint   `json:"trace_page_id" gorm:"primaryKey;autoIncrement"`
+	TraceID     uint   `json:"trace_id" gorm:"uniqueIndex:idx_trace_pages_trace_page,priority:1"`
+	CourseID    uint   `json:"course_id" gorm:"index"`
+	Page        int    `json:"page" gorm:"uniqueIndex:idx_trace_pages_trace_page,priority:2"`
+	Text        string `json:"text"`
+}
+
+// TraceChunk is a retrievable passage of trace text with its term statistics
+type TraceChunk struct {
+	TraceChunkID uint           `json:"trace_chunk_id" gorm:"primaryKey;autoIncrement"`
+	TraceID      uint           `json:"trace_id" gorm:"index"`
+	CourseID     uint           `json:"course_id" gorm:"index"`
+	Page         int            `json:"page"`
+	Position     int            `json:"position"`
+	Text         string         `json:"text"`
+	Terms        map[string]int `json:"-" gorm:"type:jsonb;serializer:json"`
+	Length       int            `json:"-"`
+	DateCreated  time.Time      `json:"date_created" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ChunkIndexVersion identifies the state of a course's chunks; it changes whenever
+// chunks are added or removed
+type ChunkIndexVersion struct {
+	Count int64
+	MaxID uint
+}
+
+type TraceTextStore struct {
+	db *gorm.DB
+}
+
+func NewTraceTextStore(db *gorm.DB) *TraceTextStore {
+	return &TraceTextStore{db: db}
+}
+
+// ReplacePages swaps the extracted page text of a trace for a new set
+func (s *TraceTextStore) ReplacePages(ctx context.Context, traceID uint, pages []TracePage) error {
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TracePage{}).Error; err != nil {
+			return err
+		}
+		if len(pages) == 0 {
+			return nil
+		}
+		for i := range pages {
+			pages[i].TraceID = traceID
+		}
+		return tx.Create(&pages).Error
+	})
+}
+
+// GetPagesByTraceID returns the extracted pages of a trace in page order
+func (s *TraceTextStore) GetPagesByTraceID(ctx context.Context, traceID uint) ([]TracePage, error) {
+	var pages []TracePage
+	err := conn(ctx, s.db).Where("trace_id = ?", traceID).Order("page").Find(&pages).Error
+	return pages, err
+}
+
+// ReplaceChunks swaps the indexed chunks of a trace for a new set
+func (s *TraceTextStore) ReplaceChunks(ctx context.Context, traceID uint, chunks []TraceChunk) error {
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trace_id = ?", traceID).Delete(&TraceChunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		for i := range chunks {
+			chunks[i].TraceID = traceID
+		}
+		return tx.CreateInBatches(&chunks, 200).Error
+	})
+}
+
+// GetChunksByCourseID loads every chunk of a course
+func (s *TraceTextStore) GetChunksByCourseID(ctx context.Context, courseID uint) ([]TraceChunk, error) {
+	var chunks []TraceChunk
+	err := conn(ctx, s.db).Where("course_id = ?", courseID).Order("trace_chunk_id").Find(&chunks).Error
+	return chunks, err
+}
+
+// GetChunkIndexVersion is a cheap check of whether a course's chunks changed
+func (s *TraceTextStore) GetChunkIndexVersion(ctx context.Context, courseID uint) (ChunkIndexVersion, error) {
+	var version ChunkIndexVersion
+	err := conn(ctx, s.db).Model(&TraceChunk{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(trace_chunk_id), 0) AS max_id").
+		Where("course_id = ?", courseID).
+		Scan(&version).Error
+	return version, err
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// afterCommitKey holds the effects waiting for the transaction of WithTransaction to commit
+type afterCommitKey struct{}
+
+// WithTransaction runs fn with a context in which every store call joins one
+// transaction. It commits when fn returns nil and rolls back otherwise;
+// transactions the stores open themselves become savepoints inside it, and so
+// does WithTransaction itself when ctx already carries a transaction.
+func (s *Storage) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	var effects []func()
+	err := conn(ctx, s.DB).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, &effects))
+	})
+	if err != nil {
+		return err
+	}
+	// 🔹 A nested transaction hands its effects on to the one around it
+	for _, effect := range effects {
+		AfterCommit(ctx, effect)
+	}
+	return nil
+}
+
+// AfterCommit runs effect once the transaction ctx carries has committed, or
+// right away outside WithTransaction. Effects outside the database, such as
+// cache deletes and bucket objects, use it so a rollback leaves them undone.
+func AfterCommit(ctx context.Context, effect func()) {
+	if effects, ok := ctx.Value(afterCommitKey{}).(*[]func()); ok {
+		*effects = append(*effects, effect)
+		return
+	}
+	effect()
+}
+
+// conn is db bound to ctx, or the transaction of WithTransaction when ctx carries one
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
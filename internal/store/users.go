@@ -13,10 +13,11 @@ type User struct {
 	ID             uint      `json:"id" gorm:"primaryKey"`
 	FirstName      string    `json:"first_name"`
 	LastName       string    `json:"last_name"`
-	Password       string    `json:"password"`
+	Password       string    `json:"password,omitempty"`
 	Username       string    `json:"username" gorm:"unique"`
 	AccountCreated time.Time `json:"account_created" gorm:"autoCreateTime"`
 	AccountUpdated time.Time `json:"account_updated" gorm:"autoUpdateTime"`
+	Version        int64     `json:"version" gorm:"not null;default:1"`
 }
 
 // ✅ UserStore Struct
@@ -39,7 +40,7 @@ func HashPassword(password string) (string, error) {
 func (s *UserStore) CreateUser(ctx context.Context, user *User) error {
 	// Check if the user already exists
 	var existingUser User
-	if err := s.db.WithContext(ctx).Where("username = ?", user.Username).First(&existingUser).Error; err == nil {
+	if err := conn(ctx, s.db).Where("username = ?", user.Username).First(&existingUser).Error; err == nil {
 		return gorm.ErrDuplicatedKey // Return a duplicate key error
 	}
 
@@ -50,8 +51,13 @@ func (s *UserStore) CreateUser(ctx context.Context, user *User) error {
 	}
 	user.Password = hashedPassword
 
-	// Insert user into DB
-	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+	// Insert user into DB and record the event in the same transaction
+	if err := conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateUser, user.ID, EventCreated, userEventPayload(*user))
+	}); err != nil {
 		return err
 	}
 
@@ -63,7 +69,7 @@ func (s *UserStore) CreateUser(ctx context.Context, user *User) error {
 // ✅ GetUserByID - Retrieve User by ID
 func (s *UserStore) GetUserByID(ctx context.Context, id uint) (*User, error) {
 	var user User
-	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+	if err := conn(ctx, s.db).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	// Exclude password from response
@@ -71,8 +77,17 @@ func (s *UserStore) GetUserByID(ctx context.Context, id uint) (*User, error) {
 	return &user, nil
 }
 
-// ✅ UpdateUser - Modify User Info (Handles Password Hashing)
-func (s *UserStore) UpdateUser(ctx context.Context, id uint, updateData *User) error {
+// ✅ GetUsersByIDs - Retrieve Several Users in One Query, Without Passwords
+func (s *UserStore) GetUsersByIDs(ctx context.Context, ids []uint) ([]User, error) {
+	var users []User
+	if err := conn(ctx, s.db).Omit("password").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ✅ UpdateUser - Modify User Info (Handles Password Hashing) if the user is still at version
+func (s *UserStore) UpdateUser(ctx context.Context, id uint, version int64, updateData *User) error {
 	// Hash password if it's being updated
 	if updateData.Password != "" {
 		hashedPassword, err := HashPassword(updateData.Password)
@@ -82,25 +97,85 @@ func (s *UserStore) UpdateUser(ctx context.Context, id uint, updateData *User) e
 		updateData.Password = hashedPassword
 	}
 
-	// Update user in DB
-	return s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(updateData).Error
+	// Only the names, and the password when one is given, are written
+	columns := []string{"account_updated", "first_name", "last_name"}
+	if updateData.Password != "" {
+		columns = append(columns, "password")
+	}
+	updateData.AccountUpdated = time.Now()
+
+	// Update user in DB and record the event in the same transaction
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &User{}, "id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", id).Select(columns).Updates(updateData).Error; err != nil {
+			return err
+		}
+		var user User
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		updateData.Version = user.Version
+		return recordEvent(tx, AggregateUser, id, EventUpdated, userEventPayload(user))
+	})
 }
 
-// ✅ DeleteUser - Remove User From DB (Ensures User Exists First)
-func (s *UserStore) DeleteUser(ctx context.Context, id uint) error {
+// ✅ PatchUser - UpdateUser for a partial set of columns
+func (s *UserStore) PatchUser(ctx context.Context, id uint, version int64, updateData map[string]interface{}) error {
+	if password, ok := updateData["password"].(string); ok {
+		hashedPassword, err := HashPassword(password)
+		if err != nil {
+			return err
+		}
+		updateData["password"] = hashedPassword
+	}
+	updateData["account_updated"] = time.Now()
+
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &User{}, "id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", id).Updates(updateData).Error; err != nil {
+			return err
+		}
+		var user User
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateUser, id, EventUpdated, userEventPayload(user))
+	})
+}
+
+// ✅ DeleteUser - Remove User From DB (Ensures User Exists First) if it is still at version
+func (s *UserStore) DeleteUser(ctx context.Context, id uint, version int64) error {
 	// Check if the user exists before deletion
 	var user User
-	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+	if err := conn(ctx, s.db).First(&user, id).Error; err != nil {
 		return err // User not found
 	}
 
 	// Proceed with deletion
-	return s.db.WithContext(ctx).Delete(&User{}, id).Error
+	return conn(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := bumpVersion(tx, &User{}, "id", id, version); err != nil {
+			return err
+		}
+		if err := tx.Delete(&User{}, id).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, AggregateUser, id, EventDeleted, map[string]interface{}{"id": id})
+	})
+}
+
+// userEventPayload strips the password hash before a user is published
+func userEventPayload(user User) User {
+	user.Password = ""
+	return user
 }
 
 func (s *UserStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
 	var user User
-	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	if err := conn(ctx, s.db).Where("username = ?", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -108,7 +183,7 @@ func (s *UserStore) GetUserByUsername(ctx context.Context, username string) (*Us
 
 func (s *UserStore) GetUserByCredentials(ctx context.Context, username, password string) (*User, error) {
 	var user User
-	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	if err := conn(ctx, s.db).Where("username = ?", username).First(&user).Error; err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionMismatch is returned when a row changed since the caller read it
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// bumpVersion moves a row to its next version if it is still at the expected
+// one. Run inside the write's transaction, the row stays locked until commit,
+// so concurrent writers holding the same version cannot both succeed.
+func bumpVersion(tx *gorm.DB, model interface{}, idColumn string, id interface{}, version int64) error {
+	result := tx.Model(model).
+		Where(idColumn+" = ? AND version = ?", id, version).
+		UpdateColumn("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(model).Where(idColumn+" = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrVersionMismatch
+	}
+	return nil
+}